@@ -1,29 +1,65 @@
 package cache
 
 import (
-	"runtime"
-	"sync"
 	"time"
 )
 
+const (
+	// NoExpiration 永不过期
+	NoExpiration time.Duration = -1
+	// DefaultExpiration 使用缓存的默认过期时间
+	DefaultExpiration time.Duration = 0
+)
+
+// EvictionReason 数据离开缓存的原因
+type EvictionReason int
+
+const (
+	// ReasonExpired 过期
+	ReasonExpired EvictionReason = iota
+	// ReasonEvicted 超过容量被淘汰
+	ReasonEvicted
+	// ReasonRemoved 主动删除
+	ReasonRemoved
+	// ReasonReplaced 被新的值替换
+	ReasonReplaced
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// Options 缓存的配置
+type Options = Config[string, any]
+
 type Cache *cache
+
+// cache 兼容原来的接口，基于 TypedCache[string, any]；hash 以及 list 通过 Compute 在分片锁内修改
 type cache struct {
-	defaultExpiration time.Duration
-	items             map[string]Item
-	mu                sync.RWMutex
-	onEvicted         func(string, any)
-	j                 *janitor
+	*TypedCache[string, any]
+	lists *listWaiters
 }
 
 type Item struct {
 	//data
 	Data any
-	//ttl time.UnixNano.Expiration of Item,if it is -1,it will be not Expired
+	//ttl time.UnixNano.Expiration of Item,if it is -1 or 0,it will be not Expired
 	Ttl int64
 }
 
 func (item *Item) Expired() bool {
-	if item.Ttl == -1 {
+	if item.Ttl <= 0 {
 		//用户
 		return false
 	}
@@ -42,72 +78,10 @@ func NewWithCleanupInterval(cleanupInterval time.Duration) *cache {
 	return NewWithExpirationAndCleanupInterval(0, cleanupInterval)
 }
 func NewWithExpirationAndCleanupInterval(defaultExpiration, cleanupInterval time.Duration) *cache {
-	if defaultExpiration == 0 {
-		defaultExpiration = -1
-	}
-	ch := make(chan bool)
-	c := &cache{
-		defaultExpiration: defaultExpiration,
-		items:             make(map[string]Item),
-		j: &janitor{
-			Interval: 1 * time.Second,
-			stop:     ch,
-		},
-	}
-	//启动清理协程
-	go func() {
-		c.runCleanup(cleanupInterval)
-		runtime.SetFinalizer(c, stopJanitor)
-	}()
-	return c
+	return NewWithOptions(Options{DefaultExpiration: defaultExpiration, CleanupInterval: cleanupInterval})
 }
 
-type janitor struct {
-	Interval time.Duration
-	stop     chan bool
-}
-
-func (c *cache) runCleanup(cleanupInterval time.Duration) {
-	if cleanupInterval == 0 {
-		cleanupInterval = 500 * time.Millisecond
-	}
-	ticker := time.NewTicker(cleanupInterval)
-	for {
-		select {
-		case <-ticker.C:
-			c.DeleteExpired()
-		case <-c.j.stop:
-			ticker.Stop()
-			return
-		}
-	}
-}
-
-func stopJanitor(c *cache) {
-	c.j.stop <- true
-}
-
-func (c *cache) DeleteExpired() {
-	l := len(c.items)
-	if l < 1 {
-		return
-	}
-	cloneMap := map[string]Item{}
-	c.mu.Lock()
-	for k, v := range c.items {
-		cloneMap[k] = v
-	}
-	c.mu.Unlock()
-
-	ch := make(chan int8, len(c.items))
-	for key, item := range cloneMap {
-		go func(k string, i Item) {
-			if i.Expired() {
-				c.mu.Lock()
-				delete(c.items, k)
-				c.mu.Unlock()
-			}
-			ch <- int8(1)
-		}(key, item)
-	}
+// NewWithOptions 按照配置创建缓存，可以限制条数以及内存，并选择淘汰策略
+func NewWithOptions(options Options) *cache {
+	return &cache{TypedCache: NewTyped[string, any](options), lists: &listWaiters{}}
 }
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+type evictionRecord struct {
+	key    string
+	reason EvictionReason
+}
+
+func recordEvictions(c *cache) (func() []evictionRecord, *sync.Mutex) {
+	var lock sync.Mutex
+	var records []evictionRecord
+	c.OnEvicted(func(key string, value any, reason EvictionReason) {
+		lock.Lock()
+		defer lock.Unlock()
+		records = append(records, evictionRecord{key: key, reason: reason})
+	})
+	return func() []evictionRecord {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]evictionRecord{}, records...)
+	}, &lock
+}
+
+func TestSetWithTTL(t *testing.T) {
+	c := NewWithExpiration(time.Hour)
+	records, _ := recordEvictions(c)
+	_ = c.SetWithTTL("short", 1, 20*time.Millisecond)
+	_ = c.SetWithTTL("forever", 2, NoExpiration)
+	_ = c.Set("default", 3)
+
+	time.Sleep(30 * time.Millisecond)
+	_, found := c.Get("short")
+	assert.Equal(t, found, false)
+	v, found := c.Get("forever")
+	assert.Equal(t, v, 2)
+	assert.Equal(t, found, true)
+	_, found = c.Get("default")
+	assert.Equal(t, found, true)
+	assert.Equal(t, records(), []evictionRecord{{"short", ReasonExpired}})
+
+	// 没有过期时间的数据不会被清理
+	c2 := New()
+	_ = c2.Set("key", "value")
+	c2.DeleteExpired()
+	_, found = c2.Get("key")
+	assert.Equal(t, found, true)
+}
+
+func TestEvictionReason(t *testing.T) {
+	c := New()
+	records, _ := recordEvictions(c)
+	_ = c.Set("a", 1)
+	_ = c.Set("a", 2)
+	c.Remove("a")
+	_ = c.SetHash("h", "f", 1)
+	_ = c.RemoveHash("h", "f")
+	assert.Equal(t, records(), []evictionRecord{{"a", ReasonReplaced}, {"a", ReasonRemoved}, {"h", ReasonRemoved}})
+}
+
+func TestLRU(t *testing.T) {
+	c := NewWithOptions(Options{MaxEntries: 3, Policy: LRU})
+	records, _ := recordEvictions(c)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(key, key)
+	}
+	_, _ = c.Get("a")
+	_ = c.Set("d", "d")
+	assert.Equal(t, records(), []evictionRecord{{"b", ReasonEvicted}})
+	assert.Equal(t, c.Cap(), 3)
+}
+
+func TestLFU(t *testing.T) {
+	c := NewWithOptions(Options{MaxEntries: 3, Policy: LFU})
+	records, _ := recordEvictions(c)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(key, key)
+	}
+	_, _ = c.Get("a")
+	_, _ = c.Get("a")
+	_, _ = c.Get("b")
+	_, _ = c.Get("c")
+	_, _ = c.Get("c")
+	_ = c.Set("d", "d")
+	assert.Equal(t, records(), []evictionRecord{{"b", ReasonEvicted}})
+
+	// 新加入的次数最少
+	_ = c.Set("e", "e")
+	assert.Equal(t, records()[1], evictionRecord{"d", ReasonEvicted})
+}
+
+func TestTinyLFU(t *testing.T) {
+	c := NewWithOptions(Options{MaxEntries: 100, Policy: TinyLFU})
+	// 热点数据
+	for i := 0; i < 100; i++ {
+		_ = c.Set(fmt.Sprintf("hot%d", i), i)
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 100; i++ {
+			_, _ = c.Get(fmt.Sprintf("hot%d", i))
+		}
+	}
+	// 只访问一次的数据不会挤掉热点数据
+	for i := 0; i < 1000; i++ {
+		_ = c.Set(fmt.Sprintf("scan%d", i), i)
+	}
+	hits := 0
+	for i := 0; i < 100; i++ {
+		if _, found := c.Get(fmt.Sprintf("hot%d", i)); found {
+			hits++
+		}
+	}
+	assert.Equal(t, c.Cap(), 100)
+	assert.Equal(t, hits >= 90, true)
+}
+
+func TestMaxMemory(t *testing.T) {
+	c := NewWithOptions(Options{MaxMemory: 100, Sizer: func(key string, value any) int64 {
+		return int64(len(value.(string)))
+	}})
+	_ = c.Set("a", string(make([]byte, 40)))
+	_ = c.Set("b", string(make([]byte, 40)))
+	_ = c.Set("c", string(make([]byte, 40)))
+	_, found := c.Get("a")
+	assert.Equal(t, found, false)
+	assert.Equal(t, c.Stats().Memory, int64(80))
+}
+
+func TestStats(t *testing.T) {
+	c := NewWithOptions(Options{MaxEntries: 1})
+	_ = c.Set("a", 1)
+	_, _ = c.Get("a")
+	_, _ = c.Get("b")
+	_ = c.Set("b", 2)
+	_ = c.SetWithTTL("c", 3, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	c.DeleteExpired()
+
+	stats := c.Stats()
+	assert.Equal(t, stats.Hits, uint64(1))
+	assert.Equal(t, stats.Misses, uint64(1))
+	assert.Equal(t, stats.Evictions, uint64(2))
+	assert.Equal(t, stats.Expirations, uint64(1))
+	assert.Equal(t, stats.Entries, 0)
+	assert.Equal(t, stats.HitRate(), 0.5)
+}
@@ -0,0 +1,383 @@
+package cache
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+const (
+	// 没有配置分片数时，每个CPU对应的分片数
+	shardsPerCpu = 4
+	// 有容量限制时每个分片至少的条数以及内存，避免分片过多使得每个分片的容量太小
+	minShardEntries = 64
+	minShardMemory  = 1 << 20
+)
+
+// Config 泛型缓存的配置
+type Config[K comparable, V any] struct {
+	// 默认的过期时间，0或者 NoExpiration 表示永不过期
+	DefaultExpiration time.Duration
+	// 清理过期数据的间隔，默认：500毫秒
+	CleanupInterval time.Duration
+	// 最多的条数，0则不限制；平均分配到各个分片
+	MaxEntries int
+	// 最多占用的内存（估算值，单位：字节），0则不限制；平均分配到各个分片
+	MaxMemory int64
+	// 估算数据占用的内存，默认按照数据的类型递归估算
+	Sizer func(key K, value V) int64
+	// 超过容量时的淘汰策略，默认：LRU
+	Policy EvictionPolicy
+	// 数据离开缓存时的回调，在锁外调用
+	OnEvicted func(key K, value V, reason EvictionReason)
+	// 分片数，向上取整为2的幂；默认为CPU数的4倍，有容量限制时会减少分片保证每个分片的容量
+	Shards int
+	// 快照文件，创建时加载其中没有过期的数据，Close 时保存
+	SnapshotFile string
+	// 定时保存快照的间隔，0则只在 Close 时保存
+	SnapshotInterval time.Duration
+}
+
+// ComputeOp Compute 回调返回的操作
+type ComputeOp int
+
+const (
+	// ComputeKeep 不修改
+	ComputeKeep ComputeOp = iota
+	// ComputeSet 保存返回的值，已有的数据保持原来的过期时间
+	ComputeSet
+	// ComputeDelete 删除
+	ComputeDelete
+)
+
+// TypedCache 泛型的本地缓存：按照key分片加锁，过期数据通过每个分片的最小堆清理，不会为每条数据启动协程
+type TypedCache[K comparable, V any] struct {
+	*shardedCache[K, V]
+}
+
+// shardedCache 清理协程只引用这里，Cache 不再被引用时可以通过finalizer停止清理协程
+type shardedCache[K comparable, V any] struct {
+	shards            []*shard[K, V]
+	mask              uint64
+	defaultExpiration time.Duration
+	sizer             func(key K, value V) int64
+
+	callbackLock sync.RWMutex
+	onEvicted    func(K, V, EvictionReason)
+
+	snapshotFile string
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTyped 按照配置创建泛型缓存
+func NewTyped[K comparable, V any](config Config[K, V]) *TypedCache[K, V] {
+	n := shardCount(config.Shards, config.MaxEntries, config.MaxMemory)
+	sc := &shardedCache[K, V]{
+		shards:            make([]*shard[K, V], n),
+		mask:              uint64(n - 1),
+		defaultExpiration: config.DefaultExpiration,
+		onEvicted:         config.OnEvicted,
+		snapshotFile:      config.SnapshotFile,
+		stop:              make(chan struct{}),
+	}
+	if config.MaxMemory > 0 {
+		sc.sizer = config.Sizer
+		if sc.sizer == nil {
+			sc.sizer = func(key K, value V) int64 {
+				return EstimateSize(key) + EstimateSize(value)
+			}
+		}
+	}
+	// 容量平均分配到各个分片
+	maxEntries, maxMemory := config.MaxEntries, config.MaxMemory
+	if maxEntries > 0 {
+		maxEntries = (maxEntries + n - 1) / n
+	}
+	if maxMemory > 0 {
+		maxMemory = (maxMemory + int64(n) - 1) / int64(n)
+	}
+	for i := range sc.shards {
+		sc.shards[i] = newShard[K, V](maxEntries, maxMemory, config.Policy)
+	}
+	if sc.snapshotFile != "" {
+		if _, err := sc.LoadFile(sc.snapshotFile); err != nil {
+			logger.Warn("加载缓存快照失败，文件：%s，错误：%v", sc.snapshotFile, err)
+		}
+	}
+	go sc.runCleanup(config.CleanupInterval, config.SnapshotInterval)
+
+	c := &TypedCache[K, V]{shardedCache: sc}
+	runtime.SetFinalizer(c, func(c *TypedCache[K, V]) {
+		c.Close()
+	})
+	return c
+}
+
+func shardCount(shards int, maxEntries int, maxMemory int64) int {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0) * shardsPerCpu
+		for shards > 1 && maxEntries > 0 && maxEntries/shards < minShardEntries {
+			shards /= 2
+		}
+		for shards > 1 && maxMemory > 0 && maxMemory/int64(shards) < minShardMemory {
+			shards /= 2
+		}
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	return n
+}
+
+func (c *shardedCache[K, V]) shardOf(key K) *shard[K, V] {
+	if c.mask == 0 {
+		return c.shards[0]
+	}
+	return c.shards[hashKey(key)&c.mask]
+}
+
+// expiration 过期的时间点，0表示永不过期
+func (c *shardedCache[K, V]) expiration(ttl time.Duration, now int64) int64 {
+	if ttl == DefaultExpiration {
+		ttl = c.defaultExpiration
+	}
+	if ttl <= 0 {
+		return 0
+	}
+	return now + int64(ttl)
+}
+
+func (c *shardedCache[K, V]) size(key K, value V) int64 {
+	if c.sizer == nil {
+		return 0
+	}
+	return c.sizer(key, value)
+}
+
+// notify 在锁外调用数据离开缓存的回调
+func (c *shardedCache[K, V]) notify(evicted []evictedEntry[K, V]) {
+	if len(evicted) == 0 {
+		return
+	}
+	c.callbackLock.RLock()
+	onEvicted := c.onEvicted
+	c.callbackLock.RUnlock()
+	if onEvicted == nil {
+		return
+	}
+	for _, e := range evicted {
+		onEvicted(e.key, e.value, e.reason)
+	}
+}
+
+func (c *shardedCache[K, V]) runCleanup(cleanupInterval time.Duration, snapshotInterval time.Duration) {
+	if cleanupInterval <= 0 {
+		cleanupInterval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	var snapshots <-chan time.Time
+	if c.snapshotFile != "" && snapshotInterval > 0 {
+		snapshotTicker := time.NewTicker(snapshotInterval)
+		defer snapshotTicker.Stop()
+		snapshots = snapshotTicker.C
+	}
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-snapshots:
+			c.snapshot()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Close 停止清理协程，之后过期的数据只会在访问时删除；配置了快照文件时保存快照
+func (c *shardedCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.snapshot()
+	})
+}
+
+// OnEvicted 设置数据离开缓存时的回调
+func (c *shardedCache[K, V]) OnEvicted(f func(key K, value V, reason EvictionReason)) {
+	c.callbackLock.Lock()
+	defer c.callbackLock.Unlock()
+	c.onEvicted = f
+}
+
+// Set 使用默认的过期时间保存数据
+func (c *shardedCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, DefaultExpiration)
+}
+
+// SetWithTTL 保存数据并指定过期时间，ttl为 DefaultExpiration 时使用缓存的默认过期时间，为 NoExpiration 时永不过期
+func (c *shardedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	size := c.size(key, value)
+	now := time.Now().UnixNano()
+	expireAt := c.expiration(ttl, now)
+	var evicted []evictedEntry[K, V]
+	s := c.shardOf(key)
+	s.mu.Lock()
+	s.set(key, value, expireAt, size, now, &evicted)
+	s.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Get 获取没有过期的数据；没有容量限制时只持有读锁
+func (c *shardedCache[K, V]) Get(key K) (V, bool) {
+	s := c.shardOf(key)
+	now := time.Now().UnixNano()
+	if s.policy == nil {
+		s.mu.RLock()
+		e, found := s.items[key]
+		if found && !e.expired(now) {
+			value := e.value
+			s.mu.RUnlock()
+			s.stats.record(true)
+			return value, true
+		}
+		s.mu.RUnlock()
+		if !found {
+			s.stats.record(false)
+			var zero V
+			return zero, false
+		}
+	}
+
+	var evicted []evictedEntry[K, V]
+	var value V
+	s.mu.Lock()
+	e, found := s.lookup(key, now, &evicted)
+	if found {
+		s.access(key)
+		value = e.value
+	}
+	s.mu.Unlock()
+	s.stats.record(found)
+	c.notify(evicted)
+	return value, found
+}
+
+// Remove 删除数据
+func (c *shardedCache[K, V]) Remove(key K) {
+	var evicted []evictedEntry[K, V]
+	s := c.shardOf(key)
+	s.mu.Lock()
+	if e, found := s.items[key]; found {
+		s.remove(e, ReasonRemoved, &evicted)
+	}
+	s.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Compute 持有分片的写锁读取并修改数据，fn 返回的操作决定保存、删除或者不修改，返回操作之后的数据以及是否存在；
+// ComputeSet 修改已有的数据时保持原来的过期时间并且不触发 ReasonReplaced，新的key使用默认的过期时间。
+// fn 在锁内调用，不能再访问同一个缓存
+func (c *shardedCache[K, V]) Compute(key K, fn func(value V, found bool) (V, ComputeOp)) (V, bool) {
+	var evicted []evictedEntry[K, V]
+	result, exist := c.compute(key, fn, &evicted)
+	c.notify(evicted)
+	return result, exist
+}
+
+// compute 在分片的写锁内执行 Compute，使用 defer 释放锁，fn panic 时分片不会一直被锁住
+func (c *shardedCache[K, V]) compute(key K, fn func(value V, found bool) (V, ComputeOp), evicted *[]evictedEntry[K, V]) (result V, exist bool) {
+	s := c.shardOf(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UnixNano()
+	e, found := s.lookup(key, now, evicted)
+	var old V
+	if found {
+		old = e.value
+	}
+	value, op := fn(old, found)
+	switch op {
+	case ComputeSet:
+		if found {
+			e.value = value
+			s.update(e, c.size(key, value), evicted)
+		} else {
+			s.set(key, value, c.expiration(DefaultExpiration, now), c.size(key, value), now, evicted)
+		}
+		_, exist = s.items[key]
+		if exist {
+			result = value
+		}
+	case ComputeDelete:
+		if found {
+			s.remove(e, ReasonRemoved, evicted)
+		}
+	default:
+		if found {
+			s.access(key)
+			result, exist = old, true
+		}
+	}
+	return result, exist
+}
+
+// Len 当前的条数，包含已经过期但是还没有清理的数据
+func (c *shardedCache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.items)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range 遍历没有过期的数据，fn返回false时停止；遍历时持有分片的读锁，fn中不能修改同一个缓存
+func (c *shardedCache[K, V]) Range(fn func(key K, value V) bool) {
+	for _, s := range c.shards {
+		if !rangeShard(s, fn) {
+			return
+		}
+	}
+}
+
+func rangeShard[K comparable, V any](s *shard[K, V], fn func(key K, value V) bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now().UnixNano()
+	for key, e := range s.items {
+		if !e.expired(now) && !fn(key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteExpired 删除已经过期的数据
+func (c *shardedCache[K, V]) DeleteExpired() {
+	for _, s := range c.shards {
+		var evicted []evictedEntry[K, V]
+		s.mu.Lock()
+		s.deleteExpired(time.Now().UnixNano(), &evicted)
+		s.mu.Unlock()
+		c.notify(evicted)
+	}
+}
+
+// Stats 命中、未命中、淘汰以及过期的统计
+func (c *shardedCache[K, V]) Stats() Stats {
+	var stats Stats
+	for _, s := range c.shards {
+		s.mu.RLock()
+		stats.Entries += len(s.items)
+		stats.Memory += s.memory
+		s.mu.RUnlock()
+		stats.add(&s.stats)
+	}
+	return stats
+}
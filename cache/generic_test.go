@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+type user struct {
+	Name string
+	Age  int
+}
+
+func TestCacheGeneric(t *testing.T) {
+	c := NewTyped[int, user](Config[int, user]{})
+	defer c.Close()
+	c.Set(1, user{"库陈胜", 29})
+	u, found := c.Get(1)
+	assert.Equal(t, found, true)
+	assert.Equal(t, u.Name, "库陈胜")
+
+	_, found = c.Get(2)
+	assert.Equal(t, found, false)
+	c.Remove(1)
+	assert.Equal(t, c.Len(), 0)
+}
+
+func TestCacheExpiration(t *testing.T) {
+	var lock sync.Mutex
+	var expired []string
+	c := NewTyped[string, int](Config[string, int]{
+		DefaultExpiration: 20 * time.Millisecond,
+		CleanupInterval:   10 * time.Millisecond,
+		OnEvicted: func(key string, value int, reason EvictionReason) {
+			lock.Lock()
+			defer lock.Unlock()
+			if reason == ReasonExpired {
+				expired = append(expired, key)
+			}
+		},
+	})
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.SetWithTTL("forever", 1, NoExpiration)
+	c.SetWithTTL("later", 1, time.Hour)
+
+	// 清理协程只弹出过期堆的堆顶，不需要扫描全部数据
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, c.Len(), 2)
+	lock.Lock()
+	assert.Equal(t, len(expired), 100)
+	lock.Unlock()
+	assert.Equal(t, c.Stats().Expirations, uint64(100))
+
+	// 重新设置过期时间
+	c.SetWithTTL("later", 2, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	_, found := c.Get("later")
+	assert.Equal(t, found, false)
+	_, found = c.Get("forever")
+	assert.Equal(t, found, true)
+}
+
+func TestCacheCompute(t *testing.T) {
+	c := NewTyped[string, int](Config[string, int]{Shards: 8})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Compute("counter", func(value int, found bool) (int, ComputeOp) {
+				return value + 1, ComputeSet
+			})
+		}()
+	}
+	wg.Wait()
+	v, _ := c.Get("counter")
+	assert.Equal(t, v, 100)
+
+	v, found := c.Compute("counter", func(value int, found bool) (int, ComputeOp) {
+		return value, ComputeKeep
+	})
+	assert.Equal(t, v, 100)
+	assert.Equal(t, found, true)
+
+	_, found = c.Compute("counter", func(value int, found bool) (int, ComputeOp) {
+		return 0, ComputeDelete
+	})
+	assert.Equal(t, found, false)
+	assert.Equal(t, c.Len(), 0)
+}
+
+func TestCacheComputePanic(t *testing.T) {
+	c := NewTyped[string, int](Config[string, int]{Shards: 1})
+	defer c.Close()
+
+	func() {
+		defer func() {
+			assert.Equal(t, recover(), "compute panic")
+		}()
+		c.Compute("key", func(value int, found bool) (int, ComputeOp) {
+			panic("compute panic")
+		})
+	}()
+
+	// fn panic 之后分片的锁已经释放
+	c.Set("key", 1)
+	v, found := c.Get("key")
+	assert.Equal(t, v, 1)
+	assert.Equal(t, found, true)
+}
+
+func TestCacheShards(t *testing.T) {
+	assert.Equal(t, shardCount(3, 0, 0), 4)
+	assert.Equal(t, shardCount(0, 3, 0), 1)
+	assert.Equal(t, shardCount(0, 0, 100), 1)
+
+	c := NewTyped[int, int](Config[int, int]{Shards: 16, MaxEntries: 1600})
+	defer c.Close()
+	for i := 0; i < 10000; i++ {
+		c.Set(i, i)
+	}
+	// 每个分片最多100条
+	assert.Equal(t, c.Len(), 1600)
+	assert.Equal(t, c.Stats().Evictions, uint64(10000-c.Len()))
+}
+
+func benchmarkGet(b *testing.B, shards int, writePercent int) {
+	c := NewTyped[string, int](Config[string, int]{Shards: shards})
+	defer c.Close()
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		c.Set(keys[i], i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i&1023]
+			if i%100 < writePercent {
+				c.Set(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkCacheGet 只读，对比单个锁以及分片锁
+func BenchmarkCacheGet(b *testing.B) {
+	b.Run("shards=1", func(b *testing.B) { benchmarkGet(b, 1, 0) })
+	b.Run("shards=default", func(b *testing.B) { benchmarkGet(b, 0, 0) })
+}
+
+// BenchmarkCacheReadHeavy 90%读10%写
+func BenchmarkCacheReadHeavy(b *testing.B) {
+	b.Run("shards=1", func(b *testing.B) { benchmarkGet(b, 1, 10) })
+	b.Run("shards=default", func(b *testing.B) { benchmarkGet(b, 0, 10) })
+}
+
+func BenchmarkCacheLRU(b *testing.B) {
+	c := NewTyped[int, int](Config[int, int]{MaxEntries: 10000})
+	defer c.Close()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, found := c.Get(i % 20000); !found {
+				c.Set(i%20000, i)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+var (
+	// ErrNotFound Loader 返回它表示数据不存在，配置了 NegativeTTL 时会缓存不存在的结果
+	ErrNotFound = errors.New("cache: 数据不存在")
+	// ErrNoLoader 没有传入也没有配置加载数据的函数
+	ErrNoLoader = errors.New("cache: 没有配置加载数据的函数")
+)
+
+// Loader 加载一个key的数据，数据不存在时返回 ErrNotFound
+type Loader[K comparable, V any] func(key K) (V, error)
+
+// BulkLoader 批量加载数据，返回的map中没有的key视为不存在
+type BulkLoader[K comparable, V any] func(keys []K) (map[K]V, error)
+
+// LoadingConfig 自动加载缓存的配置
+type LoadingConfig[K comparable, V any] struct {
+	Config[K, V]
+	// 默认的加载函数
+	Loader Loader[K, V]
+	// 批量加载函数，GetAll 时使用；没有配置 Loader 时也用于加载单个key
+	BulkLoader BulkLoader[K, V]
+	// 写入之后超过这个时间再访问时在后台重新加载，加载完成前返回旧的数据；0则不刷新
+	RefreshAfterWrite time.Duration
+	// 数据不存在的结果缓存的时间，0则不缓存
+	NegativeTTL time.Duration
+}
+
+type loadedValue[V any] struct {
+	value    V
+	notFound bool
+	// 加载的时间（UnixNano）
+	loadedAt int64
+}
+
+// loadedValueData 快照中保存的加载结果，gob只编码导出的字段
+type loadedValueData[V any] struct {
+	Value    V
+	NotFound bool
+	LoadedAt int64
+}
+
+func (v loadedValue[V]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(loadedValueData[V]{Value: v.value, NotFound: v.notFound, LoadedAt: v.loadedAt})
+	return buf.Bytes(), err
+}
+
+func (v *loadedValue[V]) GobDecode(data []byte) error {
+	var value loadedValueData[V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return err
+	}
+	v.value, v.notFound, v.loadedAt = value.Value, value.NotFound, value.LoadedAt
+	return nil
+}
+
+// LoadingCache 没有命中时自动加载的缓存：同一个key并发的加载只执行一次，支持后台刷新以及缓存不存在的结果
+type LoadingCache[K comparable, V any] struct {
+	cache        *TypedCache[K, loadedValue[V]]
+	group        flightGroup[K, V]
+	loader       Loader[K, V]
+	bulkLoader   BulkLoader[K, V]
+	refreshAfter time.Duration
+	negativeTTL  time.Duration
+
+	// 正在加载的key的代数：Set、Invalidate 时增加，加载完成时代数已经变化则丢弃加载的结果
+	stampLock sync.Mutex
+	stamps    map[K]*loadStamp
+}
+
+type loadStamp struct {
+	generation uint64
+	loads      int
+}
+
+// NewLoadingCache 按照配置创建自动加载的缓存
+func NewLoadingCache[K comparable, V any](config LoadingConfig[K, V]) *LoadingCache[K, V] {
+	inner := Config[K, loadedValue[V]]{
+		DefaultExpiration: config.DefaultExpiration,
+		CleanupInterval:   config.CleanupInterval,
+		MaxEntries:        config.MaxEntries,
+		MaxMemory:         config.MaxMemory,
+		Policy:            config.Policy,
+		Shards:            config.Shards,
+		SnapshotFile:      config.SnapshotFile,
+		SnapshotInterval:  config.SnapshotInterval,
+	}
+	if sizer := config.Sizer; sizer != nil {
+		inner.Sizer = func(key K, value loadedValue[V]) int64 {
+			return sizer(key, value.value)
+		}
+	}
+	if onEvicted := config.OnEvicted; onEvicted != nil {
+		// 不存在的结果不通知
+		inner.OnEvicted = func(key K, value loadedValue[V], reason EvictionReason) {
+			if !value.notFound {
+				onEvicted(key, value.value, reason)
+			}
+		}
+	}
+	return &LoadingCache[K, V]{
+		cache:        NewTyped[K, loadedValue[V]](inner),
+		loader:       config.Loader,
+		bulkLoader:   config.BulkLoader,
+		refreshAfter: config.RefreshAfterWrite,
+		negativeTTL:  config.NegativeTTL,
+		stamps:       map[K]*loadStamp{},
+	}
+}
+
+// Get 使用配置的加载函数获取数据
+func (c *LoadingCache[K, V]) Get(key K) (V, error) {
+	return c.GetOrLoad(key, nil)
+}
+
+// GetOrLoad 获取数据，没有命中时调用loader加载并缓存；loader为nil时使用配置的加载函数。
+// 数据不存在时返回 ErrNotFound，loader返回的其他错误不会缓存
+func (c *LoadingCache[K, V]) GetOrLoad(key K, loader Loader[K, V]) (V, error) {
+	if loader == nil {
+		loader = c.defaultLoader()
+	}
+	if item, found := c.cache.Get(key); found {
+		c.refreshIfStale(key, item, loader)
+		if item.notFound {
+			var zero V
+			return zero, ErrNotFound
+		}
+		return item.value, nil
+	}
+	if loader == nil {
+		var zero V
+		return zero, ErrNoLoader
+	}
+	value, err, _ := c.group.do(key, func() (V, error) {
+		return c.load(key, loader)
+	})
+	return value, err
+}
+
+// GetAll 批量获取数据，没有命中的key配置了 BulkLoader 时一次加载，否则逐个加载；不存在的key不在返回的map中
+func (c *LoadingCache[K, V]) GetAll(keys []K) (map[K]V, error) {
+	result := make(map[K]V, len(keys))
+	var missing []K
+	seen := make(map[K]struct{}, len(keys))
+	loader := c.defaultLoader()
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		item, found := c.cache.Get(key)
+		if !found {
+			missing = append(missing, key)
+			continue
+		}
+		c.refreshIfStale(key, item, loader)
+		if !item.notFound {
+			result[key] = item.value
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	if c.bulkLoader == nil {
+		if loader == nil {
+			return result, ErrNoLoader
+		}
+		for _, key := range missing {
+			value, err := c.GetOrLoad(key, loader)
+			if err == nil {
+				result[key] = value
+			} else if !errors.Is(err, ErrNotFound) {
+				return result, err
+			}
+		}
+		return result, nil
+	}
+
+	generations := make([]uint64, len(missing))
+	for i, key := range missing {
+		generations[i] = c.beginLoad(key)
+	}
+	completed := false
+	defer func() {
+		// bulkLoader panic时结束加载，不保存结果
+		if !completed {
+			for i, key := range missing {
+				c.endLoad(key, generations[i], nil)
+			}
+		}
+	}()
+	values, err := c.bulkLoader(missing)
+	completed = true
+	for i, key := range missing {
+		value, ok := values[key]
+		switch {
+		case err != nil:
+			c.endLoad(key, generations[i], nil)
+		case ok:
+			c.endLoad(key, generations[i], func() {
+				c.store(key, value, nil)
+			})
+			result[key] = value
+		default:
+			c.endLoad(key, generations[i], func() {
+				c.store(key, value, ErrNotFound)
+			})
+		}
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Set 直接写入数据，正在进行的加载的结果不会覆盖
+func (c *LoadingCache[K, V]) Set(key K, value V) {
+	c.stampLock.Lock()
+	defer c.stampLock.Unlock()
+	c.advance(key)
+	c.store(key, value, nil)
+}
+
+// Invalidate 删除数据，下一次访问时重新加载；正在进行的加载的结果会被丢弃，不会写入旧的数据
+func (c *LoadingCache[K, V]) Invalidate(key K) {
+	c.stampLock.Lock()
+	defer c.stampLock.Unlock()
+	c.advance(key)
+	c.cache.Remove(key)
+}
+
+// advance 增加正在加载的key的代数，需要持有 stampLock
+func (c *LoadingCache[K, V]) advance(key K) {
+	if stamp, ok := c.stamps[key]; ok {
+		stamp.generation++
+	}
+}
+
+// beginLoad 开始加载，返回加载开始时的代数
+func (c *LoadingCache[K, V]) beginLoad(key K) uint64 {
+	c.stampLock.Lock()
+	defer c.stampLock.Unlock()
+	stamp, ok := c.stamps[key]
+	if !ok {
+		stamp = &loadStamp{}
+		c.stamps[key] = stamp
+	}
+	stamp.loads++
+	return stamp.generation
+}
+
+// endLoad 加载结束，加载期间没有 Set、Invalidate 时执行apply保存结果
+func (c *LoadingCache[K, V]) endLoad(key K, generation uint64, apply func()) {
+	c.stampLock.Lock()
+	defer c.stampLock.Unlock()
+	stamp := c.stamps[key]
+	if stamp.loads--; stamp.loads == 0 {
+		delete(c.stamps, key)
+	}
+	if apply != nil && stamp.generation == generation {
+		apply()
+	}
+}
+
+// Refresh 在后台使用配置的加载函数重新加载，返回是否启动了加载
+func (c *LoadingCache[K, V]) Refresh(key K) bool {
+	loader := c.defaultLoader()
+	if loader == nil {
+		return false
+	}
+	return c.group.doAsync(key, func() (V, error) {
+		return c.reload(key, loader)
+	})
+}
+
+// Len 当前的条数，包含缓存的不存在的结果
+func (c *LoadingCache[K, V]) Len() int {
+	return c.cache.Len()
+}
+
+// Stats 命中、未命中、淘汰以及过期的统计
+func (c *LoadingCache[K, V]) Stats() Stats {
+	return c.cache.Stats()
+}
+
+// Close 停止清理协程
+func (c *LoadingCache[K, V]) Close() {
+	c.cache.Close()
+}
+
+// defaultLoader 配置的加载函数，只配置了 BulkLoader 时按照单个key批量加载
+func (c *LoadingCache[K, V]) defaultLoader() Loader[K, V] {
+	if c.loader != nil {
+		return c.loader
+	}
+	if c.bulkLoader == nil {
+		return nil
+	}
+	return func(key K) (V, error) {
+		values, err := c.bulkLoader([]K{key})
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		value, ok := values[key]
+		if !ok {
+			return value, ErrNotFound
+		}
+		return value, nil
+	}
+}
+
+func (c *LoadingCache[K, V]) load(key K, loader Loader[K, V]) (V, error) {
+	value, err := c.loadStamped(key, loader, func(value V, err error) {
+		c.store(key, value, err)
+	})
+	return value, err
+}
+
+// loadStamped 执行loader，加载期间没有 Set、Invalidate 时执行apply保存结果；loader panic时不保存
+func (c *LoadingCache[K, V]) loadStamped(key K, loader Loader[K, V], apply func(value V, err error)) (value V, err error) {
+	generation := c.beginLoad(key)
+	completed := false
+	defer func() {
+		if !completed {
+			c.endLoad(key, generation, nil)
+			return
+		}
+		c.endLoad(key, generation, func() {
+			apply(value, err)
+		})
+	}()
+	value, err = loader(key)
+	completed = true
+	return value, err
+}
+
+// store 保存加载的结果：成功的使用默认过期时间，不存在的使用 NegativeTTL，其他错误不保存
+func (c *LoadingCache[K, V]) store(key K, value V, err error) {
+	now := time.Now().UnixNano()
+	switch {
+	case err == nil:
+		c.cache.Set(key, loadedValue[V]{value: value, loadedAt: now})
+	case errors.Is(err, ErrNotFound) && c.negativeTTL > 0:
+		c.cache.SetWithTTL(key, loadedValue[V]{notFound: true, loadedAt: now}, c.negativeTTL)
+	}
+}
+
+// refreshIfStale 超过 RefreshAfterWrite 的数据在后台重新加载
+func (c *LoadingCache[K, V]) refreshIfStale(key K, item loadedValue[V], loader Loader[K, V]) {
+	if c.refreshAfter <= 0 || item.notFound || loader == nil {
+		return
+	}
+	if time.Now().UnixNano()-item.loadedAt < int64(c.refreshAfter) {
+		return
+	}
+	c.group.doAsync(key, func() (V, error) {
+		return c.reload(key, loader)
+	})
+}
+
+// reload 重新加载，失败时保留旧的数据，数据已经不存在时删除
+func (c *LoadingCache[K, V]) reload(key K, loader Loader[K, V]) (V, error) {
+	value, err := c.loadStamped(key, loader, func(value V, err error) {
+		switch {
+		case err == nil:
+			c.store(key, value, nil)
+		case errors.Is(err, ErrNotFound):
+			if c.negativeTTL > 0 {
+				c.store(key, value, err)
+			} else {
+				c.cache.Remove(key)
+			}
+		}
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		logger.Warn("刷新缓存失败，key：%v，错误：%v", key, err)
+	}
+	return value, err
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	var loads int32
+	c := NewLoadingCache[string, string](LoadingConfig[string, string]{})
+	defer c.Close()
+	loader := func(key string) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "value-" + key, nil
+	}
+
+	// 并发加载同一个key只执行一次
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad("a", loader)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, v, "value-a")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1))
+
+	_, _ = c.GetOrLoad("a", loader)
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1))
+
+	_, err := c.Get("b")
+	assert.Equal(t, err, ErrNoLoader)
+
+	// 其他错误不缓存
+	failed := errors.New("db down")
+	_, err = c.GetOrLoad("c", func(key string) (string, error) {
+		return "", failed
+	})
+	assert.Equal(t, err, failed)
+	assert.Equal(t, c.Len(), 1)
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	c := NewLoadingCache[string, string](LoadingConfig[string, string]{})
+	defer c.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad("a", func(key string) (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-started
+	c.Invalidate("a")
+	close(release)
+	<-done
+
+	// 加载期间被删除，加载的结果不保存
+	assert.Equal(t, c.Len(), 0)
+	assert.Equal(t, len(c.stamps), 0)
+
+	// 加载期间写入的数据不被加载的结果覆盖
+	started, release, done = make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad("a", func(key string) (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-started
+	c.Set("a", "new")
+	close(release)
+	<-done
+	v, _ := c.Get("a")
+	assert.Equal(t, v, "new")
+}
+
+func TestNegativeCache(t *testing.T) {
+	var loads int32
+	c := NewLoadingCache[int, string](LoadingConfig[int, string]{
+		NegativeTTL: 30 * time.Millisecond,
+		Loader: func(key int) (string, error) {
+			atomic.AddInt32(&loads, 1)
+			return "", ErrNotFound
+		},
+	})
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(1)
+		assert.Equal(t, err, ErrNotFound)
+	}
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1))
+
+	time.Sleep(40 * time.Millisecond)
+	_, _ = c.Get(1)
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(2))
+}
+
+func TestRefreshAfterWrite(t *testing.T) {
+	var version int32
+	c := NewLoadingCache[string, int32](LoadingConfig[string, int32]{
+		RefreshAfterWrite: 20 * time.Millisecond,
+		Loader: func(key string) (int32, error) {
+			time.Sleep(10 * time.Millisecond)
+			return atomic.AddInt32(&version, 1), nil
+		},
+	})
+	defer c.Close()
+
+	v, _ := c.Get("k")
+	assert.Equal(t, v, int32(1))
+	time.Sleep(30 * time.Millisecond)
+
+	// 过了刷新时间返回旧的数据，后台重新加载
+	v, _ = c.Get("k")
+	assert.Equal(t, v, int32(1))
+	v, _ = c.Get("k")
+	assert.Equal(t, v, int32(1))
+	time.Sleep(30 * time.Millisecond)
+	v, _ = c.Get("k")
+	assert.Equal(t, v, int32(2))
+}
+
+func TestLoadingSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "loading.snapshot")
+	var loads int32
+	config := LoadingConfig[int, string]{
+		Config:      Config[int, string]{SnapshotFile: file},
+		NegativeTTL: time.Minute,
+		Loader: func(key int) (string, error) {
+			atomic.AddInt32(&loads, 1)
+			if key == 2 {
+				return "", ErrNotFound
+			}
+			return "v1", nil
+		},
+	}
+	c := NewLoadingCache[int, string](config)
+	_, _ = c.Get(1)
+	_, _ = c.Get(2)
+	c.Close()
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(2))
+
+	// 重新创建时从快照加载，包括不存在的结果
+	restored := NewLoadingCache[int, string](config)
+	defer restored.Close()
+	v, err := restored.Get(1)
+	assert.Equal(t, v, "v1")
+	assert.Equal(t, err, nil)
+	_, err = restored.Get(2)
+	assert.Equal(t, err, ErrNotFound)
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(2))
+}
+
+func TestGetAll(t *testing.T) {
+	var batches [][]int
+	var lock sync.Mutex
+	c := NewLoadingCache[int, int](LoadingConfig[int, int]{
+		NegativeTTL: time.Minute,
+		BulkLoader: func(keys []int) (map[int]int, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			sorted := append([]int{}, keys...)
+			sort.Ints(sorted)
+			batches = append(batches, sorted)
+			values := map[int]int{}
+			for _, key := range keys {
+				if key%2 == 0 {
+					values[key] = key * 10
+				}
+			}
+			return values, nil
+		},
+	})
+	defer c.Close()
+
+	c.Set(1, 100)
+	values, err := c.GetAll([]int{1, 2, 3, 4, 4})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, values, map[int]int{1: 100, 2: 20, 4: 40})
+
+	// 不存在的结果已经缓存
+	values, _ = c.GetAll([]int{2, 3, 5})
+	assert.Equal(t, values, map[int]int{2: 20})
+	assert.Equal(t, batches, [][]int{{2, 3, 4}, {5}})
+
+	// 只配置了 BulkLoader 时也可以加载单个key
+	v, err := c.Get(6)
+	assert.Equal(t, v, 60)
+	assert.Equal(t, err, nil)
+}
@@ -0,0 +1,374 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+// MemoStore 记忆化结果的存储，本地缓存使用 NewLocalMemoStore，redis使用 rediscache.NewMemoStore
+type MemoStore[V any] interface {
+	Get(ctx context.Context, key string) (V, bool, error)
+	Set(ctx context.Context, key string, value V, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePattern 删除匹配的key，pattern和redis一样支持*、?、[...]以及\转义，返回删除的条数
+	DeletePattern(ctx context.Context, pattern string) (int, error)
+}
+
+// MemoOptions 记忆化的配置
+type MemoOptions[K any, V any] struct {
+	// key的前缀，默认为函数名
+	Prefix string
+	// 结果缓存的时间，0则永不过期
+	TTL time.Duration
+	// 从参数生成key，默认为 Key(arg)
+	Key func(arg K) string
+	// 存储，默认为不限制容量的本地缓存
+	Store MemoStore[V]
+	// 是否缓存结果，默认只缓存没有错误的结果
+	CacheIf func(value V, err error) bool
+	// 不缓存空的结果：零值、nil以及长度为0的slice、map、string
+	SkipEmpty bool
+}
+
+// MemoStats 记忆化的统计
+type MemoStats struct {
+	Hits   uint64
+	Misses uint64
+	// 调用函数的次数以及其中返回错误的次数
+	Loads      uint64
+	LoadErrors uint64
+	// 按照 CacheIf 或者 SkipEmpty 没有缓存的次数
+	Skipped uint64
+	// Evict 以及 EvictPattern 删除的次数
+	Evictions uint64
+	// 调用函数的总耗时
+	LoadTime time.Duration
+}
+
+// Memo 记忆化的函数
+type Memo[K any, V any] struct {
+	fn      func(ctx context.Context, arg K) (V, error)
+	prefix  string
+	ttl     time.Duration
+	key     func(arg K) string
+	store   MemoStore[V]
+	cacheIf func(value V, err error) bool
+	group   flightGroup[string, V]
+
+	hits, misses, loads, loadErrors, skipped, evictions uint64
+	loadTime                                            int64
+}
+
+// Memoize 缓存函数的结果：先读取缓存，没有时调用函数并按照配置保存结果，同一个key并发的调用只执行一次
+func Memoize[K any, V any](fn func(ctx context.Context, arg K) (V, error), options MemoOptions[K, V]) *Memo[K, V] {
+	m := &Memo[K, V]{
+		fn:      fn,
+		prefix:  options.Prefix,
+		ttl:     options.TTL,
+		key:     options.Key,
+		store:   options.Store,
+		cacheIf: options.CacheIf,
+	}
+	if m.prefix == "" {
+		m.prefix = funcName(fn)
+	}
+	if m.key == nil {
+		m.key = func(arg K) string {
+			return Key(arg)
+		}
+	}
+	if m.store == nil {
+		m.store = NewLocalMemoStore[V](Config[string, V]{})
+	}
+	if m.cacheIf == nil {
+		skipEmpty := options.SkipEmpty
+		m.cacheIf = func(value V, err error) bool {
+			return err == nil && !(skipEmpty && isEmpty(value))
+		}
+	}
+	return m
+}
+
+// keyEscaper 参数中的:以及\转义，避免 Key("a:b") 与 Key("a", "b") 相同
+var keyEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`)
+
+// Key 使用:连接参数生成key，参数使用fmt.Sprint格式化，其中的:以及\使用\转义
+func Key(args ...any) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = keyEscaper.Replace(fmt.Sprint(arg))
+	}
+	return strings.Join(parts, ":")
+}
+
+// funcName 函数的完整名称，作为默认的key前缀
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "memo"
+}
+
+func isEmpty(value any) bool {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array, reflect.Chan:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Func:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// Key 参数对应的完整key
+func (m *Memo[K, V]) Key(arg K) string {
+	return m.prefix + ":" + m.key(arg)
+}
+
+// Call 调用记忆化的函数；读取缓存失败时直接调用函数
+func (m *Memo[K, V]) Call(ctx context.Context, arg K) (V, error) {
+	key := m.Key(arg)
+	value, found, err := m.store.Get(ctx, key)
+	if err != nil {
+		logger.Warn("读取缓存失败，key：%s，错误：%v", key, err)
+	} else if found {
+		atomic.AddUint64(&m.hits, 1)
+		return value, nil
+	}
+	atomic.AddUint64(&m.misses, 1)
+
+	// 其他调用共享这次加载的结果，不能因为第一个调用方的ctx取消或者超时而一起失败
+	loadCtx := DetachContext(ctx)
+	value, err, _ = m.group.do(key, func() (V, error) {
+		return m.load(loadCtx, key, arg)
+	})
+	return value, err
+}
+
+// DetachContext 返回保留ctx中的值（traceId等）但不继承取消和超时的context，
+// 用于多个调用方共享的加载，避免第一个调用方取消或者超时导致其他调用方一起失败
+func DetachContext(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+func (m *Memo[K, V]) load(ctx context.Context, key string, arg K) (V, error) {
+	start := time.Now()
+	value, err := m.fn(ctx, arg)
+	atomic.AddInt64(&m.loadTime, int64(time.Since(start)))
+	atomic.AddUint64(&m.loads, 1)
+	if err != nil {
+		atomic.AddUint64(&m.loadErrors, 1)
+	}
+	if !m.cacheIf(value, err) {
+		atomic.AddUint64(&m.skipped, 1)
+		return value, err
+	}
+	if setErr := m.store.Set(ctx, key, value, m.ttl); setErr != nil {
+		logger.Warn("保存缓存失败，key：%s，错误：%v", key, setErr)
+	}
+	return value, err
+}
+
+// Evict 删除参数对应的结果
+func (m *Memo[K, V]) Evict(ctx context.Context, args ...K) error {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = m.Key(arg)
+	}
+	return m.EvictKey(ctx, keys...)
+}
+
+// EvictKey 按照完整的key删除结果
+func (m *Memo[K, V]) EvictKey(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := m.store.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	atomic.AddUint64(&m.evictions, uint64(len(keys)))
+	return nil
+}
+
+// EvictPattern 删除前缀之后匹配pattern的结果，pattern为*时删除这个函数所有的结果
+func (m *Memo[K, V]) EvictPattern(ctx context.Context, pattern string) (int, error) {
+	n, err := m.store.DeletePattern(ctx, escapePattern(m.prefix)+":"+pattern)
+	atomic.AddUint64(&m.evictions, uint64(n))
+	return n, err
+}
+
+// Stats 命中、未命中、调用以及删除的统计
+func (m *Memo[K, V]) Stats() MemoStats {
+	return MemoStats{
+		Hits:       atomic.LoadUint64(&m.hits),
+		Misses:     atomic.LoadUint64(&m.misses),
+		Loads:      atomic.LoadUint64(&m.loads),
+		LoadErrors: atomic.LoadUint64(&m.loadErrors),
+		Skipped:    atomic.LoadUint64(&m.skipped),
+		Evictions:  atomic.LoadUint64(&m.evictions),
+		LoadTime:   time.Duration(atomic.LoadInt64(&m.loadTime)),
+	}
+}
+
+// localMemoStore 基于本地缓存的存储
+type localMemoStore[V any] struct {
+	cache *TypedCache[string, V]
+}
+
+// NewLocalMemoStore 使用本地缓存保存记忆化的结果
+func NewLocalMemoStore[V any](config Config[string, V]) MemoStore[V] {
+	return &localMemoStore[V]{cache: NewTyped[string, V](config)}
+}
+
+func (s *localMemoStore[V]) Get(_ context.Context, key string) (V, bool, error) {
+	value, found := s.cache.Get(key)
+	return value, found, nil
+}
+
+func (s *localMemoStore[V]) Set(_ context.Context, key string, value V, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = NoExpiration
+	}
+	s.cache.SetWithTTL(key, value, ttl)
+	return nil
+}
+
+func (s *localMemoStore[V]) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		s.cache.Remove(key)
+	}
+	return nil
+}
+
+func (s *localMemoStore[V]) DeletePattern(_ context.Context, pattern string) (int, error) {
+	var keys []string
+	s.cache.Range(func(key string, _ V) bool {
+		if MatchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	for _, key := range keys {
+		s.cache.Remove(key)
+	}
+	return len(keys), nil
+}
+
+// escapePattern 转义前缀中匹配使用的特殊字符，默认的前缀是函数名，可能包含*以及[]
+func escapePattern(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// MatchPattern 和redis的KEYS、SCAN一样的匹配：*匹配任意字符串，?匹配一个字节，\转义，
+// [abc]匹配其中的一个字节，[^abc]匹配不在其中的字节，[a-z]匹配范围内的字节
+func MatchPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if MatchPattern(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var matched bool
+			if matched, pattern = matchClass(pattern[1:], key[0]); !matched {
+				return false
+			}
+			key = key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass 匹配[]中的字符集，返回是否匹配以及]之后的pattern；和redis一样，没有]时到pattern的结尾
+func matchClass(pattern string, c byte) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-':
+			low, high := pattern[0], pattern[2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (c >= low && c <= high)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return matched != not, pattern
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+type query struct {
+	Id   int
+	Name string
+}
+
+func TestMemoize(t *testing.T) {
+	var calls int32
+	memo := Memoize(func(ctx context.Context, q query) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return q.Name, nil
+	}, MemoOptions[query, string]{
+		Prefix: "user",
+		TTL:    time.Minute,
+		Key: func(q query) string {
+			return Key(q.Id, q.Name)
+		},
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = memo.Call(ctx, query{1, "a"})
+		}()
+	}
+	wg.Wait()
+	v, _ := memo.Call(ctx, query{1, "a"})
+	assert.Equal(t, v, "a")
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1))
+	assert.Equal(t, memo.Key(query{1, "a"}), "user:1:a")
+
+	_, _ = memo.Call(ctx, query{2, "b"})
+	_, _ = memo.Call(ctx, query{12, "c"})
+	assert.Equal(t, memo.Evict(ctx, query{1, "a"}), nil)
+	n, _ := memo.EvictPattern(ctx, "?:*")
+	assert.Equal(t, n, 1)
+	_, _ = memo.Call(ctx, query{2, "b"})
+	_, _ = memo.Call(ctx, query{12, "c"})
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(4))
+
+	stats := memo.Stats()
+	assert.Equal(t, stats.Loads, uint64(4))
+	assert.Equal(t, stats.Evictions, uint64(2))
+	assert.Equal(t, stats.Hits+stats.Misses, uint64(15))
+}
+
+func TestMemoizeConditional(t *testing.T) {
+	var calls int32
+	failed := errors.New("failed")
+	memo := Memoize(func(ctx context.Context, id int) ([]int, error) {
+		atomic.AddInt32(&calls, 1)
+		switch id {
+		case 0:
+			return nil, failed
+		case 1:
+			return []int{}, nil
+		default:
+			return []int{id}, nil
+		}
+	}, MemoOptions[int, []int]{SkipEmpty: true})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		for id := 0; id < 3; id++ {
+			_, _ = memo.Call(ctx, id)
+		}
+	}
+	// 错误以及空的结果不缓存
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(5))
+	assert.Equal(t, memo.Stats().Skipped, uint64(4))
+	assert.Equal(t, memo.Stats().LoadErrors, uint64(2))
+}
+
+func TestMatchPattern(t *testing.T) {
+	assert.Equal(t, MatchPattern("user:*", "user:1"), true)
+	assert.Equal(t, MatchPattern("user:?", "user:12"), false)
+	assert.Equal(t, MatchPattern("*:1", "user:1"), true)
+	assert.Equal(t, MatchPattern(`a\*b`, "a*b"), true)
+	assert.Equal(t, MatchPattern(`a\*b`, "axb"), false)
+	assert.Equal(t, MatchPattern(escapePattern("pkg.(*T).Get[...]")+":*", "pkg.(*T).Get[...]:1"), true)
+	// 和redis一样支持字符集
+	assert.Equal(t, MatchPattern("user:[12]", "user:2"), true)
+	assert.Equal(t, MatchPattern("user:[12]", "user:3"), false)
+	assert.Equal(t, MatchPattern("user:[^12]", "user:3"), true)
+	assert.Equal(t, MatchPattern("user:[a-c]*", "user:bob"), true)
+	assert.Equal(t, MatchPattern("user:[a-c]*", "user:dan"), false)
+	assert.Equal(t, MatchPattern(`user:[\]]`, "user:]"), true)
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key(1, "a"), "1:a")
+	// 参数中的:转义，不同的参数不会生成相同的key
+	assert.Equal(t, Key("a:b"), `a\:b`)
+	assert.Equal(t, Key("a", "b"), "a:b")
+	assert.Equal(t, Key(`a\`, "b") == Key(`a\:b`), false)
+}
+
+func TestMemoizeDetachedContext(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	memo := Memoize(func(ctx context.Context, id int) (int, error) {
+		close(started)
+		<-release
+		return id, ctx.Err()
+	}, MemoOptions[int, int]{Prefix: "detached"})
+
+	// 第一个调用方取消之后，共享加载结果的其他调用方不受影响
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := memo.Call(ctx, 1)
+		first <- err
+	}()
+	<-started
+	second := make(chan int)
+	go func() {
+		v, _ := memo.Call(context.Background(), 1)
+		second <- v
+	}()
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, <-first, nil)
+	assert.Equal(t, <-second, 1)
+	assert.Equal(t, memo.Stats().Loads, uint64(1))
+}
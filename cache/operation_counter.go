@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+var (
+	errNotInteger = errors.New("key的值不是整数")
+	errOverflow   = errors.New("整数溢出")
+)
+
+//Incr increment the integer value of a key by one, a missing key is set to 0 before the operation
+func (c *cache) Incr(key string) (int64, error) {
+	return c.IncrBy(key, 1)
+}
+
+//Decr decrement the integer value of a key by one
+func (c *cache) Decr(key string) (int64, error) {
+	return c.IncrBy(key, -1)
+}
+
+//DecrBy decrement the integer value of a key by delta
+func (c *cache) DecrBy(key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, errOverflow
+	}
+	return c.IncrBy(key, -delta)
+}
+
+//IncrBy increment the integer value of a key by delta, the ttl of an existing key is kept.
+//The value is stored as int64, integers and numeric strings saved by Set are accepted
+func (c *cache) IncrBy(key string, delta int64) (int64, error) {
+	var result int64
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		var current int64
+		if found {
+			var ok bool
+			if current, ok = toInt64(data); !ok {
+				err = errNotInteger
+				return data, ComputeKeep
+			}
+		}
+		if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+			err = errOverflow
+			return data, ComputeKeep
+		}
+		result = current + delta
+		return result, ComputeSet
+	})
+	return result, err
+}
+
+func toInt64(data any) (int64, bool) {
+	switch v := data.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -2,64 +2,210 @@ package cache
 
 import (
 	"errors"
-	"reflect"
+	"time"
 )
 
+var errNotHash = errors.New("key的值不是hash")
+
 //SetHash Add an item to the cache,replacing any existing item.
 //note key and subKey is primary key
 func (c *cache) SetHash(key, subKey string, value any) error {
-	e := c.getUnixNano()
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if vv, b := c.items[key]; b {
-		vv.Data.(map[string]any)[subKey] = value
-	} else {
-		subKeyValue := make(map[string]any)
-		subKeyValue[subKey] = value
-		v := Item{
-			Data: subKeyValue,
-			Ttl:  e,
-		}
-		c.items[key] = v
-	}
-	return nil
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return map[string]any{subKey: value}, ComputeSet
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		hash[subKey] = value
+		return hash, ComputeSet
+	})
+	return err
 }
 
 func (c *cache) RemoveHash(key, subKey string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if item, found := c.items[key]; found {
-		data := item.Data
-		if reflect.TypeOf(data).Kind() != reflect.Map {
-			return errors.New("key的值不是hash")
-		}
-		subValue := data.(map[string]any)
-		delete(subValue, subKey)
-		if len(subValue) == 0 {
-			delete(c.items, key)
-		}
-		return nil
-	}
-	return nil
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		delete(hash, subKey)
+		if len(hash) == 0 {
+			return nil, ComputeDelete
+		}
+		return hash, ComputeSet
+	})
+	return err
 }
 
 //GetHash get a hash value from the cache.Returns the hashes or nil, and a bool indicating
 // whether the key was found
 func (c *cache) GetHash(key, subKey string) (any, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if item, found := c.items[key]; !found {
-		return nil, found
-	} else {
-		data := item.Data
-		if reflect.TypeOf(data).Kind() != reflect.Map {
-			return nil, false
-		}
-		if value, found := item.Data.(map[string]any)[subKey]; !found {
-			return nil, found
-		} else {
-			return value, true
+	var value any
+	var found bool
+	c.Compute(key, func(data any, exist bool) (any, ComputeOp) {
+		hash, ok := data.(map[string]any)
+		if !ok {
+			return data, ComputeKeep
+		}
+		if purgeHash(hash) {
+			return updatedHash(hash)
+		}
+		if value, found = hash[subKey]; found {
+			value = unwrapField(value)
+		}
+		return data, ComputeKeep
+	})
+	c.shardOf(key).stats.record(found)
+	return value, found
+}
+
+//HGetAll return a copy of all fields of a hash, fields expired by HExpire are excluded
+func (c *cache) HGetAll(key string) (map[string]any, error) {
+	var result map[string]any
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		purged := purgeHash(hash)
+		result = make(map[string]any, len(hash))
+		for field, value := range hash {
+			result[field] = unwrapField(value)
+		}
+		if purged {
+			return updatedHash(hash)
+		}
+		return data, ComputeKeep
+	})
+	return result, err
+}
+
+// expiringField 设置了过期时间的hash字段
+type expiringField struct {
+	Value    any
+	ExpireAt int64
+}
+
+func unwrapField(value any) any {
+	if field, ok := value.(expiringField); ok {
+		return field.Value
+	}
+	return value
+}
+
+// purgeHash 删除已经过期的字段，返回是否有删除
+func purgeHash(hash map[string]any) bool {
+	now := time.Now().UnixNano()
+	purged := false
+	for field, value := range hash {
+		if f, ok := value.(expiringField); ok && now > f.ExpireAt {
+			delete(hash, field)
+			purged = true
 		}
 	}
+	return purged
+}
+
+// updatedHash 字段修改之后，hash为空时删除key
+func updatedHash(hash map[string]any) (any, ComputeOp) {
+	if len(hash) == 0 {
+		return nil, ComputeDelete
+	}
+	return hash, ComputeSet
+}
+
+//HExpire set a ttl on a field of a hash like redis HEXPIRE, ttl <= 0 deletes the field.
+//SetHash on the field clears the ttl. Returns whether the field exists.
+//Expired fields are removed when the hash is read, use HGetAll instead of Get to read such hashes
+func (c *cache) HExpire(key, subKey string, ttl time.Duration) (bool, error) {
+	var exist bool
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		purgeHash(hash)
+		var value any
+		if value, exist = hash[subKey]; exist {
+			if ttl <= 0 {
+				delete(hash, subKey)
+			} else {
+				hash[subKey] = expiringField{Value: unwrapField(value), ExpireAt: time.Now().Add(ttl).UnixNano()}
+			}
+		}
+		return updatedHash(hash)
+	})
+	return exist, err
+}
+
+//HPersist remove the ttl of a field, returns whether the field had a ttl
+func (c *cache) HPersist(key, subKey string) (bool, error) {
+	var persisted bool
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		purgeHash(hash)
+		if field, ok := hash[subKey].(expiringField); ok {
+			hash[subKey] = field.Value
+			persisted = true
+		}
+		return updatedHash(hash)
+	})
+	return persisted, err
+}
+
+//HTTL return the remaining ttl of a field, NoExpiration if the field has no ttl, and whether the field exists
+func (c *cache) HTTL(key, subKey string) (time.Duration, bool, error) {
+	var ttl time.Duration
+	var exist bool
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		hash, ok := data.(map[string]any)
+		if !ok {
+			err = errNotHash
+			return data, ComputeKeep
+		}
+		purged := purgeHash(hash)
+		value, has := hash[subKey]
+		if exist = has; exist {
+			ttl = NoExpiration
+			if field, ok := value.(expiringField); ok {
+				ttl = time.Duration(field.ExpireAt - time.Now().UnixNano())
+			}
+		}
+		if purged {
+			return updatedHash(hash)
+		}
+		return data, ComputeKeep
+	})
+	return ttl, exist, err
 }
@@ -2,65 +2,237 @@ package cache
 
 import (
 	"errors"
-	"reflect"
+	"sync"
+	"time"
 )
 
+var errNotList = errors.New("key 对应的数据类型不是 slice")
+
+// ErrTimeout 阻塞读取超时
+var ErrTimeout = errors.New("cache: 等待超时")
+
+// listWaiters 阻塞读取list的等待者，list添加数据时通知
+type listWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+func (w *listWaiters) register(ch chan struct{}, keys []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.waiters == nil {
+		w.waiters = map[string]map[chan struct{}]struct{}{}
+	}
+	for _, key := range keys {
+		if w.waiters[key] == nil {
+			w.waiters[key] = map[chan struct{}]struct{}{}
+		}
+		w.waiters[key][ch] = struct{}{}
+	}
+}
+
+func (w *listWaiters) unregister(ch chan struct{}, keys []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		delete(w.waiters[key], ch)
+		if len(w.waiters[key]) == 0 {
+			delete(w.waiters, key)
+		}
+	}
+}
+
+func (w *listWaiters) notify(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func (c *cache) AddItem(key string, value ...any) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if item, found := c.items[key]; found {
-		data := item.Data
-		if reflect.TypeOf(data).Kind() != reflect.Slice {
-			return errors.New("key 对应的数据类型不是 slice")
-		}
-		item.Data = append(data.([]any), value...)
-		c.items[key] = item
-	} else {
-		e := c.getUnixNano()
-		data := Item{
-			Data: value,
-			Ttl:  e,
-		}
-		c.items[key] = data
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return value, ComputeSet
+		}
+		items, ok := data.([]any)
+		if !ok {
+			err = errNotList
+			return data, ComputeKeep
+		}
+		return append(items, value...), ComputeSet
+	})
+	if err == nil && len(value) > 0 {
+		c.lists.notify(key)
+	}
+	return err
+}
+
+//RPush append values to the tail of a list, same as AddItem
+func (c *cache) RPush(key string, value ...any) error {
+	return c.AddItem(key, value...)
+}
+
+//LPush insert values at the head of a list one after another like redis, so the last value is the first
+func (c *cache) LPush(key string, value ...any) error {
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		var items []any
+		if found {
+			var ok bool
+			if items, ok = data.([]any); !ok {
+				err = errNotList
+				return data, ComputeKeep
+			}
+		}
+		newItems := make([]any, 0, len(items)+len(value))
+		for i := len(value) - 1; i >= 0; i-- {
+			newItems = append(newItems, value[i])
+		}
+		return append(newItems, items...), ComputeSet
+	})
+	if err == nil && len(value) > 0 {
+		c.lists.notify(key)
+	}
+	return err
+}
+
+//LPop remove and return the first value of a list, the key is removed when the list is empty
+func (c *cache) LPop(key string) (any, bool, error) {
+	return c.pop(key, true)
+}
+
+//RPop remove and return the last value of a list, the key is removed when the list is empty
+func (c *cache) RPop(key string) (any, bool, error) {
+	return c.pop(key, false)
+}
+
+//LLen return the length of a list
+func (c *cache) LLen(key string) (int, error) {
+	var n int
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		items, ok := data.([]any)
+		if !ok {
+			err = errNotList
+			return data, ComputeKeep
+		}
+		n = len(items)
+		return data, ComputeKeep
+	})
+	return n, err
+}
+
+//BLPop pop the first value of the first non-empty list, blocks until a value is pushed or the timeout
+//elapses, timeout 0 blocks forever. Returns the key and the value, or ErrTimeout
+func (c *cache) BLPop(timeout time.Duration, keys ...string) (string, any, error) {
+	return c.blockingPop(timeout, true, keys)
+}
+
+//BRPop pop the last value of the first non-empty list, see BLPop
+func (c *cache) BRPop(timeout time.Duration, keys ...string) (string, any, error) {
+	return c.blockingPop(timeout, false, keys)
+}
+
+func (c *cache) pop(key string, left bool) (any, bool, error) {
+	var value any
+	var popped bool
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		items, ok := data.([]any)
+		if !ok {
+			err = errNotList
+			return data, ComputeKeep
+		}
+		if len(items) == 0 {
+			return data, ComputeKeep
+		}
+		popped = true
+		if left {
+			value, items = items[0], items[1:]
+		} else {
+			value, items = items[len(items)-1], items[:len(items)-1]
+		}
+		if len(items) == 0 {
+			return nil, ComputeDelete
+		}
+		return items, ComputeSet
+	})
+	return value, popped, err
+}
+
+func (c *cache) blockingPop(timeout time.Duration, left bool, keys []string) (string, any, error) {
+	// 先注册再读取，避免读取之后、注册之前添加的数据没有通知
+	ch := make(chan struct{}, 1)
+	c.lists.register(ch, keys)
+	defer c.lists.unregister(ch, keys)
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		for _, key := range keys {
+			value, popped, err := c.pop(key, left)
+			if err != nil {
+				return key, nil, err
+			}
+			if popped {
+				return key, value, nil
+			}
+		}
+		select {
+		case <-ch:
+		case <-deadline:
+			return "", nil, ErrTimeout
+		}
 	}
-	return nil
 }
 
 //SetItem set or replace a value of items by index
 func (c *cache) SetItem(key string, idx int, value any) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if item, found := c.items[key]; found {
-		data := item.Data
-		if reflect.TypeOf(data).Kind() != reflect.Slice {
-			return errors.New("key 对应的数据类型不是 slice")
-		}
-		items := data.([]any)
-		if len(items) <= idx {
-			return errors.New("数组下标越界")
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			err = errors.New("key不存在")
+			return data, ComputeKeep
+		}
+		items, ok := data.([]any)
+		if !ok {
+			err = errNotList
+			return data, ComputeKeep
+		}
+		if idx < 0 || len(items) <= idx {
+			err = errors.New("数组下标越界")
+			return data, ComputeKeep
 		}
-
 		items[idx] = value
-		item.Data = items
-		return nil
-	}
-	return errors.New("key不存在")
+		return items, ComputeSet
+	})
+	return err
 }
 
 //GetItem return an array of points or nil
 func (c *cache) GetItem(key string) []any {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if item, found := c.items[key]; !found {
-		return nil
-	} else {
-		data := item.Data
-		if reflect.TypeOf(data).Kind() != reflect.Slice {
-			return nil
-		}
-		return data.([]any)
-	}
+	var items []any
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		items, _ = data.([]any)
+		return data, ComputeKeep
+	})
+	c.shardOf(key).stats.record(items != nil)
+	return items
 }
 
 //GetItemByIndex return a value of Type is T or nil
@@ -83,15 +255,23 @@ func (c *cache) RemoveItem(key string, idx int) error {
 		c.Remove(key)
 		return nil
 	}
-	item := c.GetItem(key)
-	if item == nil {
-		return errors.New("key不存在")
-	}
-	if len(item) <= idx {
-		return nil
-	}
-	newItem := item[:idx]
-	newItem = append(newItem, item[idx+1:]...)
-	c.Set(key, newItem)
-	return nil
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			err = errors.New("key不存在")
+			return data, ComputeKeep
+		}
+		items, ok := data.([]any)
+		if !ok {
+			err = errNotList
+			return data, ComputeKeep
+		}
+		if len(items) <= idx {
+			return data, ComputeKeep
+		}
+		newItem := make([]any, 0, len(items)-1)
+		newItem = append(newItem, items[:idx]...)
+		return append(newItem, items[idx+1:]...), ComputeSet
+	})
+	return err
 }
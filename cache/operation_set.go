@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"errors"
+	"sort"
+)
+
+var errNotSet = errors.New("key的值不是set")
+
+// memberSet 集合，gob不支持空结构体，值使用bool
+type memberSet map[string]bool
+
+//SAdd add members to a set, returns the number of members that were added
+func (c *cache) SAdd(key string, members ...string) (int, error) {
+	var added int
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		set := memberSet{}
+		if found {
+			var ok bool
+			if set, ok = data.(memberSet); !ok {
+				err = errNotSet
+				return data, ComputeKeep
+			}
+		}
+		for _, member := range members {
+			if !set[member] {
+				set[member] = true
+				added++
+			}
+		}
+		if len(set) == 0 {
+			return data, ComputeKeep
+		}
+		return set, ComputeSet
+	})
+	return added, err
+}
+
+//SRem remove members from a set, the key is removed when the set is empty
+func (c *cache) SRem(key string, members ...string) (int, error) {
+	var removed int
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		set, ok := data.(memberSet)
+		if !ok {
+			err = errNotSet
+			return data, ComputeKeep
+		}
+		for _, member := range members {
+			if set[member] {
+				delete(set, member)
+				removed++
+			}
+		}
+		if len(set) == 0 {
+			return nil, ComputeDelete
+		}
+		return set, ComputeSet
+	})
+	return removed, err
+}
+
+//SMembers return all members of a set in ascending order
+func (c *cache) SMembers(key string) ([]string, error) {
+	var members []string
+	err := c.readSet(key, func(set memberSet) {
+		members = make([]string, 0, len(set))
+		for member := range set {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+	})
+	return members, err
+}
+
+//SIsMember return whether member is a member of the set
+func (c *cache) SIsMember(key, member string) (bool, error) {
+	var exist bool
+	err := c.readSet(key, func(set memberSet) {
+		exist = set[member]
+	})
+	return exist, err
+}
+
+//SCard return the number of members of a set
+func (c *cache) SCard(key string) (int, error) {
+	var n int
+	err := c.readSet(key, func(set memberSet) {
+		n = len(set)
+	})
+	return n, err
+}
+
+// readSet 在分片锁内读取集合，key不存在时不调用fn
+func (c *cache) readSet(key string, fn func(set memberSet)) error {
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		set, ok := data.(memberSet)
+		if !ok {
+			err = errNotSet
+			return data, ComputeKeep
+		}
+		fn(set)
+		return data, ComputeKeep
+	})
+	return err
+}
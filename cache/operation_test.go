@@ -41,7 +41,8 @@ func Test_cache_Get1(t *testing.T) {
 	} else {
 		t.Logf("获取到hash值 %v", v1)
 	}
-	time.Sleep(1 * time.Second)
+	// key的ttl为1秒，GetHash也会检查key是否过期，需要在过期之前读取
+	time.Sleep(500 * time.Millisecond)
 
 	if v2, b := c.GetHash("Key", "subKey"); !b {
 		t.Error("未获取到hash值 v2")
@@ -59,6 +60,28 @@ func Test_cache_Get1(t *testing.T) {
 	}
 }
 
+func Test_cache_GetHashExpired(t *testing.T) {
+	c := NewWithExpiration(200 * time.Millisecond)
+	defer c.Close()
+	_ = c.SetHash("Key", "subKey", "库陈胜")
+	_ = c.AddItem("List", "item")
+	time.Sleep(100 * time.Millisecond)
+	// 已有key上修改字段不会刷新ttl
+	_ = c.SetHash("Key", "other", "贼溜")
+	if _, b := c.GetHash("Key", "subKey"); !b {
+		t.Error("未获取到hash值")
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	// 过期之后即使还没有清理也不能再读取到
+	if _, b := c.GetHash("Key", "subKey"); b {
+		t.Error("Key - subKey 未过期")
+	}
+	if items := c.GetItem("List"); items != nil {
+		t.Error("List 未过期")
+	}
+}
+
 //性能测试
 //fixme 并发问题有待处理
 func Test_cache_Get2(t *testing.T) {
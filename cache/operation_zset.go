@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var errNotZSet = errors.New("key的值不是sorted set")
+var errZScoreNaN = errors.New("sorted set的分数不能是NaN")
+
+// ZMember 有序集合的成员以及分数
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// sortedSet 有序集合，Members 按照分数升序，分数相同时按照成员升序
+type sortedSet struct {
+	Scores  map[string]float64
+	Members []ZMember
+}
+
+func (z *sortedSet) search(member ZMember) int {
+	return sort.Search(len(z.Members), func(i int) bool {
+		m := z.Members[i]
+		return m.Score > member.Score || (m.Score == member.Score && m.Member >= member.Member)
+	})
+}
+
+// add 添加或者更新分数，返回是否是新的成员
+func (z *sortedSet) add(member ZMember) bool {
+	score, exist := z.Scores[member.Member]
+	if exist {
+		if score == member.Score {
+			return false
+		}
+		z.remove(member.Member)
+	}
+	i := z.search(member)
+	z.Members = append(z.Members, ZMember{})
+	copy(z.Members[i+1:], z.Members[i:])
+	z.Members[i] = member
+	z.Scores[member.Member] = member.Score
+	return !exist
+}
+
+func (z *sortedSet) remove(member string) bool {
+	score, exist := z.Scores[member]
+	if !exist {
+		return false
+	}
+	i := z.search(ZMember{Member: member, Score: score})
+	z.Members = append(z.Members[:i], z.Members[i+1:]...)
+	delete(z.Scores, member)
+	return true
+}
+
+//ZAdd add members to a sorted set or update their scores, returns the number of members that were added
+func (c *cache) ZAdd(key string, members ...ZMember) (int, error) {
+	for _, member := range members {
+		if math.IsNaN(member.Score) {
+			return 0, errZScoreNaN
+		}
+	}
+	var added int
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		zset := &sortedSet{Scores: map[string]float64{}}
+		if found {
+			var ok bool
+			if zset, ok = data.(*sortedSet); !ok {
+				err = errNotZSet
+				return data, ComputeKeep
+			}
+		}
+		for _, member := range members {
+			if zset.add(member) {
+				added++
+			}
+		}
+		if len(zset.Members) == 0 {
+			return data, ComputeKeep
+		}
+		return zset, ComputeSet
+	})
+	return added, err
+}
+
+//ZIncrBy increment the score of member by incr, a missing member is added with score incr
+func (c *cache) ZIncrBy(key string, incr float64, member string) (float64, error) {
+	if math.IsNaN(incr) {
+		return 0, errZScoreNaN
+	}
+	var score float64
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		zset := &sortedSet{Scores: map[string]float64{}}
+		if found {
+			var ok bool
+			if zset, ok = data.(*sortedSet); !ok {
+				err = errNotZSet
+				return data, ComputeKeep
+			}
+		}
+		score = zset.Scores[member] + incr
+		if math.IsNaN(score) {
+			// 类似 +inf 加 -inf 的情况，和redis一样拒绝
+			score = 0
+			err = errZScoreNaN
+			return data, ComputeKeep
+		}
+		zset.add(ZMember{Member: member, Score: score})
+		return zset, ComputeSet
+	})
+	return score, err
+}
+
+//ZRem remove members from a sorted set, the key is removed when the sorted set is empty
+func (c *cache) ZRem(key string, members ...string) (int, error) {
+	var removed int
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		zset, ok := data.(*sortedSet)
+		if !ok {
+			err = errNotZSet
+			return data, ComputeKeep
+		}
+		for _, member := range members {
+			if zset.remove(member) {
+				removed++
+			}
+		}
+		if len(zset.Members) == 0 {
+			return nil, ComputeDelete
+		}
+		return zset, ComputeSet
+	})
+	return removed, err
+}
+
+//ZScore return the score of member
+func (c *cache) ZScore(key, member string) (float64, bool, error) {
+	var score float64
+	var exist bool
+	err := c.readZSet(key, func(zset *sortedSet) {
+		score, exist = zset.Scores[member]
+	})
+	return score, exist, err
+}
+
+//ZCard return the number of members of a sorted set
+func (c *cache) ZCard(key string) (int, error) {
+	var n int
+	err := c.readZSet(key, func(zset *sortedSet) {
+		n = len(zset.Members)
+	})
+	return n, err
+}
+
+//ZRange return members by index in ascending order of score, start and stop are inclusive and
+//negative numbers count from the end like redis
+func (c *cache) ZRange(key string, start, stop int) ([]ZMember, error) {
+	var members []ZMember
+	err := c.readZSet(key, func(zset *sortedSet) {
+		n := len(zset.Members)
+		if start < 0 {
+			start += n
+		}
+		if stop < 0 {
+			stop += n
+		}
+		if start < 0 {
+			start = 0
+		}
+		if stop >= n {
+			stop = n - 1
+		}
+		if start > stop {
+			return
+		}
+		members = append([]ZMember{}, zset.Members[start:stop+1]...)
+	})
+	return members, err
+}
+
+//ZRangeByScore return members with a score between min and max (inclusive) in ascending order,
+//use math.Inf for unbounded ranges
+func (c *cache) ZRangeByScore(key string, min, max float64) ([]ZMember, error) {
+	var members []ZMember
+	err := c.readZSet(key, func(zset *sortedSet) {
+		i := sort.Search(len(zset.Members), func(i int) bool {
+			return zset.Members[i].Score >= min
+		})
+		for ; i < len(zset.Members) && zset.Members[i].Score <= max; i++ {
+			members = append(members, zset.Members[i])
+		}
+	})
+	return members, err
+}
+
+// readZSet 在分片锁内读取有序集合，key不存在时不调用fn
+func (c *cache) readZSet(key string, fn func(zset *sortedSet)) error {
+	var err error
+	c.Compute(key, func(data any, found bool) (any, ComputeOp) {
+		if !found {
+			return data, ComputeKeep
+		}
+		zset, ok := data.(*sortedSet)
+		if !ok {
+			err = errNotZSet
+			return data, ComputeKeep
+		}
+		fn(zset)
+		return data, ComputeKeep
+	})
+	return err
+}
@@ -0,0 +1,416 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// EvictionPolicy 超过容量时的淘汰策略
+type EvictionPolicy int
+
+const (
+	// LRU 淘汰最久没有访问的
+	LRU EvictionPolicy = iota
+	// LFU 淘汰访问次数最少的，次数相同时淘汰最久没有访问的
+	LFU
+	// TinyLFU W-TinyLFU：新数据先进入窗口，离开窗口时与主区域的淘汰候选比较访问频率，频率高的留下
+	TinyLFU
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	case TinyLFU:
+		return "tinylfu"
+	default:
+		return "unknown"
+	}
+}
+
+// evictionPolicy 记录key的访问情况，超过容量时选出淘汰的key
+type evictionPolicy[K comparable] interface {
+	add(key K)
+	access(key K)
+	remove(key K)
+	// victim 选出淘汰的key；W-TinyLFU可能淘汰刚加入的key
+	victim() (K, bool)
+}
+
+func newEvictionPolicy[K comparable](policy EvictionPolicy, capacity int) evictionPolicy[K] {
+	switch policy {
+	case LFU:
+		return newLfuPolicy[K]()
+	case TinyLFU:
+		return newTinyLfuPolicy[K](capacity)
+	default:
+		return newLruPolicy[K]()
+	}
+}
+
+type lruPolicy[K comparable] struct {
+	ll    *list.List
+	nodes map[K]*list.Element
+}
+
+func newLruPolicy[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{ll: list.New(), nodes: map[K]*list.Element{}}
+}
+
+func (p *lruPolicy[K]) add(key K) {
+	if e, ok := p.nodes[key]; ok {
+		p.ll.MoveToFront(e)
+		return
+	}
+	p.nodes[key] = p.ll.PushFront(key)
+}
+
+func (p *lruPolicy[K]) access(key K) {
+	if e, ok := p.nodes[key]; ok {
+		p.ll.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if e, ok := p.nodes[key]; ok {
+		p.ll.Remove(e)
+		delete(p.nodes, key)
+	}
+}
+
+func (p *lruPolicy[K]) victim() (K, bool) {
+	e := p.ll.Back()
+	if e == nil {
+		var zero K
+		return zero, false
+	}
+	return e.Value.(K), true
+}
+
+// lfuPolicy 按照访问次数分桶，每个桶内按照访问时间排序
+type lfuPolicy[K comparable] struct {
+	nodes   map[K]*list.Element
+	buckets map[int]*list.List
+	minFreq int
+}
+
+type lfuNode[K comparable] struct {
+	key  K
+	freq int
+}
+
+func newLfuPolicy[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{nodes: map[K]*list.Element{}, buckets: map[int]*list.List{}}
+}
+
+func (p *lfuPolicy[K]) bucket(freq int) *list.List {
+	b, ok := p.buckets[freq]
+	if !ok {
+		b = list.New()
+		p.buckets[freq] = b
+	}
+	return b
+}
+
+func (p *lfuPolicy[K]) add(key K) {
+	if _, ok := p.nodes[key]; ok {
+		p.access(key)
+		return
+	}
+	p.nodes[key] = p.bucket(1).PushFront(&lfuNode[K]{key: key, freq: 1})
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy[K]) access(key K) {
+	e, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+	node := e.Value.(*lfuNode[K])
+	if p.detach(e, node.freq) && p.minFreq == node.freq {
+		p.minFreq++
+	}
+	node.freq++
+	p.nodes[key] = p.bucket(node.freq).PushFront(node)
+}
+
+// detach 从桶中移除，返回桶是否已经为空
+func (p *lfuPolicy[K]) detach(e *list.Element, freq int) bool {
+	b := p.buckets[freq]
+	b.Remove(e)
+	if b.Len() == 0 {
+		delete(p.buckets, freq)
+		return true
+	}
+	return false
+}
+
+func (p *lfuPolicy[K]) remove(key K) {
+	e, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+	freq := e.Value.(*lfuNode[K]).freq
+	delete(p.nodes, key)
+	if p.detach(e, freq) && p.minFreq == freq {
+		p.minFreq = 0
+		for f := range p.buckets {
+			if p.minFreq == 0 || f < p.minFreq {
+				p.minFreq = f
+			}
+		}
+	}
+}
+
+func (p *lfuPolicy[K]) victim() (K, bool) {
+	if b, ok := p.buckets[p.minFreq]; ok {
+		return b.Back().Value.(*lfuNode[K]).key, true
+	}
+	var zero K
+	return zero, false
+}
+
+const (
+	tinyLfuWindow = iota
+	tinyLfuProbation
+	tinyLfuProtected
+)
+
+type tinyLfuNode[K comparable] struct {
+	key     K
+	segment int
+}
+
+// tinyLfuPolicy W-TinyLFU：窗口LRU（1%）+ 主区域SLRU（试用20%、保护80%），通过频率草图决定是否接纳
+type tinyLfuPolicy[K comparable] struct {
+	capacity  int
+	nodes     map[K]*list.Element
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	// 最近一个离开窗口进入试用区的，淘汰时与试用区最久的比较频率
+	candidate *tinyLfuNode[K]
+	sketch    *countMinSketch
+}
+
+func newTinyLfuPolicy[K comparable](capacity int) *tinyLfuPolicy[K] {
+	return &tinyLfuPolicy[K]{
+		capacity:  capacity,
+		nodes:     map[K]*list.Element{},
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		sketch:    newCountMinSketch(capacity),
+	}
+}
+
+// limits 窗口以及保护区的容量；没有配置条数上限时按照当前的条数计算
+func (p *tinyLfuPolicy[K]) limits() (window int, protected int) {
+	capacity := p.capacity
+	if capacity <= 0 {
+		capacity = len(p.nodes)
+	}
+	window = capacity / 100
+	if window < 1 {
+		window = 1
+	}
+	protected = (capacity - window) * 8 / 10
+	return
+}
+
+func (p *tinyLfuPolicy[K]) listOf(segment int) *list.List {
+	switch segment {
+	case tinyLfuWindow:
+		return p.window
+	case tinyLfuProbation:
+		return p.probation
+	default:
+		return p.protected
+	}
+}
+
+func (p *tinyLfuPolicy[K]) move(e *list.Element, segment int) {
+	node := e.Value.(*tinyLfuNode[K])
+	p.listOf(node.segment).Remove(e)
+	node.segment = segment
+	p.nodes[node.key] = p.listOf(segment).PushFront(node)
+}
+
+func (p *tinyLfuPolicy[K]) add(key K) {
+	if _, ok := p.nodes[key]; ok {
+		p.access(key)
+		return
+	}
+	p.sketch.increment(hashKey(key))
+	p.nodes[key] = p.window.PushFront(&tinyLfuNode[K]{key: key, segment: tinyLfuWindow})
+
+	// 超出窗口的进入试用区，成为下一次淘汰的候选
+	window, _ := p.limits()
+	for p.window.Len() > window {
+		back := p.window.Back()
+		p.move(back, tinyLfuProbation)
+		p.candidate = back.Value.(*tinyLfuNode[K])
+	}
+}
+
+func (p *tinyLfuPolicy[K]) access(key K) {
+	e, ok := p.nodes[key]
+	if !ok {
+		return
+	}
+	p.sketch.increment(hashKey(key))
+	switch e.Value.(*tinyLfuNode[K]).segment {
+	case tinyLfuProbation:
+		// 试用区再次访问后晋升到保护区，保护区满时将最久的降级到试用区
+		p.move(e, tinyLfuProtected)
+		_, protected := p.limits()
+		for p.protected.Len() > protected {
+			p.move(p.protected.Back(), tinyLfuProbation)
+		}
+	case tinyLfuWindow:
+		p.window.MoveToFront(e)
+	default:
+		p.protected.MoveToFront(e)
+	}
+}
+
+func (p *tinyLfuPolicy[K]) remove(key K) {
+	if e, ok := p.nodes[key]; ok {
+		node := e.Value.(*tinyLfuNode[K])
+		p.listOf(node.segment).Remove(e)
+		delete(p.nodes, key)
+		if p.candidate == node {
+			p.candidate = nil
+		}
+	}
+}
+
+func (p *tinyLfuPolicy[K]) victim() (K, bool) {
+	var victim *list.Element
+	if victim = p.probation.Back(); victim == nil {
+		if victim = p.protected.Back(); victim == nil {
+			if victim = p.window.Back(); victim == nil {
+				var zero K
+				return zero, false
+			}
+		}
+	}
+	victimKey := victim.Value.(*tinyLfuNode[K]).key
+
+	candidate := p.candidate
+	p.candidate = nil
+	if candidate == nil || candidate.segment != tinyLfuProbation || candidate.key == victimKey {
+		return victimKey, true
+	}
+	// 频率高的留下
+	if p.sketch.estimate(hashKey(candidate.key)) > p.sketch.estimate(hashKey(victimKey)) {
+		return victimKey, true
+	}
+	return candidate.key, true
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// hashKey 字符串使用FNV-1a，整数使用splitmix64混合，其他类型按照字符串处理
+func hashKey[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case int32:
+		return mix64(uint64(k))
+	case uint32:
+		return mix64(uint64(k))
+	default:
+		return hashString(fmt.Sprint(k))
+	}
+}
+
+func hashString(s string) uint64 {
+	h := uint64(fnvOffset)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return mix64(h)
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// countMinSketch 4行的计数草图，计数达到上限后整体减半，使频率随时间衰减
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	if capacity < 16 {
+		capacity = 16
+	}
+	// 宽度取容量的8倍，减少冲突导致的频率高估
+	width := 1
+	for width < capacity*8 {
+		width <<= 1
+	}
+	s := &countMinSketch{mask: uint64(width - 1), resetAt: capacity * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch) index(hash uint64, row int) uint64 {
+	hash = (hash ^ (hash >> 29)) * (0x9E3779B97F4A7C15 + uint64(row)*2)
+	return (hash >> (row * 8)) & s.mask
+}
+
+func (s *countMinSketch) increment(hash uint64) {
+	for i := range s.rows {
+		idx := s.index(hash, i)
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch) estimate(hash uint64) uint8 {
+	min := uint8(255)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(hash, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
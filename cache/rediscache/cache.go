@@ -0,0 +1,205 @@
+package rediscache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/cache"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/redis"
+)
+
+// Loader 两级缓存都没有命中时加载数据，数据不存在时返回 cache.ErrNotFound
+type Loader[V any] func(ctx context.Context, key string) (V, error)
+
+// Config 两级缓存的配置
+type Config[V any] struct {
+	// redis key的前缀
+	Prefix string
+	// 本地缓存（一级）的配置，过期时间建议小于 RedisTTL，作为丢失失效通知时的兜底
+	Local cache.Config[string, V]
+	// 本地缓存数据不存在的结果的时间，0则不缓存
+	NegativeTTL time.Duration
+	// redis（二级）中数据的过期时间，0则永不过期
+	RedisTTL time.Duration
+	// 写入redis的序列化方式，默认：JSON
+	Codec Codec
+	// 失效通知的频道，默认：Prefix + "invalidate"
+	Channel string
+	// 两级缓存都没有命中时的加载函数，加载后写入两级缓存
+	Loader Loader[V]
+}
+
+// Cache 两级缓存：本地缓存（一级）+ redis（二级）。读取时逐级查找并回填，写入时同时写两级，
+// 并通过redis的发布订阅通知其他实例删除本地缓存
+type Cache[V any] struct {
+	client  goredis.UniversalClient
+	local   *cache.LoadingCache[string, V]
+	codec   Codec
+	prefix  string
+	channel string
+	ttl     time.Duration
+	loader  Loader[V]
+	// 实例的标识，忽略自己发出的失效通知
+	id     string
+	pubsub *goredis.PubSub
+}
+
+// NewDefault 使用 redis.GetClient 创建两级缓存
+func NewDefault[V any](config Config[V]) (*Cache[V], error) {
+	client, err := redis.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	return New(client, config)
+}
+
+// New 创建两级缓存并订阅失效通知
+func New[V any](client goredis.UniversalClient, config Config[V]) (*Cache[V], error) {
+	id, err := instanceId()
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache[V]{
+		client:  client,
+		codec:   config.Codec,
+		prefix:  config.Prefix,
+		channel: config.Channel,
+		ttl:     config.RedisTTL,
+		loader:  config.Loader,
+		id:      id,
+	}
+	if c.codec == nil {
+		c.codec = JSON
+	}
+	if c.channel == "" {
+		c.channel = c.prefix + "invalidate"
+	}
+
+	// 等待订阅成功，避免错过创建之后的通知
+	c.pubsub = client.Subscribe(context.Background(), c.channel)
+	if _, err := c.pubsub.Receive(context.Background()); err != nil {
+		_ = c.pubsub.Close()
+		return nil, fmt.Errorf("订阅失效通知失败：%w", err)
+	}
+	c.local = cache.NewLoadingCache[string, V](cache.LoadingConfig[string, V]{
+		Config:      config.Local,
+		NegativeTTL: config.NegativeTTL,
+	})
+	go c.listen(c.pubsub.Channel())
+	return c, nil
+}
+
+func instanceId() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Get 依次读取本地缓存、redis以及配置的 Loader，数据不存在时返回 cache.ErrNotFound
+func (c *Cache[V]) Get(ctx context.Context, key string) (V, error) {
+	return c.GetOrLoad(ctx, key, c.loader)
+}
+
+// GetOrLoad 依次读取本地缓存、redis以及loader，同一个key并发的读取只执行一次；
+// 加载不继承ctx的取消和超时，第一个调用方取消时其他等待的调用方仍然可以拿到结果
+func (c *Cache[V]) GetOrLoad(ctx context.Context, key string, loader Loader[V]) (V, error) {
+	loadCtx := cache.DetachContext(ctx)
+	return c.local.GetOrLoad(key, func(key string) (V, error) {
+		return c.load(loadCtx, key, loader)
+	})
+}
+
+// load 读取redis，没有时调用loader并写入redis
+func (c *Cache[V]) load(ctx context.Context, key string, loader Loader[V]) (V, error) {
+	var value V
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err == nil {
+		if err = c.codec.Unmarshal(data, &value); err != nil {
+			return value, fmt.Errorf("反序列化redis中的数据失败，key：%s：%w", key, err)
+		}
+		return value, nil
+	}
+	if !errors.Is(err, goredis.Nil) {
+		return value, err
+	}
+	if loader == nil {
+		return value, cache.ErrNotFound
+	}
+	if value, err = loader(ctx, key); err != nil {
+		return value, err
+	}
+	// 加载期间其他实例可能已经写入了新的数据，只在不存在时写入
+	if err := c.write(ctx, key, value, true); err != nil {
+		logger.Warn("写入redis失败，key：%s，错误：%v", key, err)
+	}
+	return value, nil
+}
+
+func (c *Cache[V]) write(ctx context.Context, key string, value V, onlyAbsent bool) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if onlyAbsent {
+		return c.client.SetNX(ctx, c.prefix+key, data, c.ttl).Err()
+	}
+	return c.client.Set(ctx, c.prefix+key, data, c.ttl).Err()
+}
+
+// Set 写入两级缓存，并通知其他实例删除本地缓存
+func (c *Cache[V]) Set(ctx context.Context, key string, value V) error {
+	if err := c.write(ctx, key, value, false); err != nil {
+		return err
+	}
+	c.local.Set(key, value)
+	return c.publish(ctx, key)
+}
+
+// Delete 删除两级缓存，并通知其他实例删除本地缓存
+func (c *Cache[V]) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		return err
+	}
+	c.local.Invalidate(key)
+	return c.publish(ctx, key)
+}
+
+// InvalidateLocal 只删除当前实例的本地缓存
+func (c *Cache[V]) InvalidateLocal(key string) {
+	c.local.Invalidate(key)
+}
+
+// Stats 本地缓存的统计
+func (c *Cache[V]) Stats() cache.Stats {
+	return c.local.Stats()
+}
+
+// Close 取消订阅并停止本地缓存的清理协程
+func (c *Cache[V]) Close() error {
+	c.local.Close()
+	return c.pubsub.Close()
+}
+
+func (c *Cache[V]) publish(ctx context.Context, key string) error {
+	return c.client.Publish(ctx, c.channel, c.id+"\n"+key).Err()
+}
+
+// listen 收到其他实例的通知时删除本地缓存，正在进行的加载的结果也会被丢弃；断线期间的通知会丢失，由本地缓存的过期时间兜底
+func (c *Cache[V]) listen(messages <-chan *goredis.Message) {
+	for msg := range messages {
+		sender, key, ok := strings.Cut(msg.Payload, "\n")
+		if !ok || sender == c.id {
+			continue
+		}
+		c.local.Invalidate(key)
+	}
+}
@@ -0,0 +1,67 @@
+package rediscache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Codec 数据写入redis时的序列化方式
+type Codec interface {
+	Marshal(value any) ([]byte, error)
+	Unmarshal(data []byte, value any) error
+}
+
+var (
+	// JSON 默认的序列化方式
+	JSON Codec = jsonCodec{}
+	// Gob 使用 encoding/gob，支持接口以外的任意Go类型
+	Gob Codec = gobCodec{}
+	// MsgPack 体积更小的二进制格式
+	MsgPack Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, value any) error {
+	return json.Unmarshal(data, value)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, value any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+var msgpackHandle = &codec.MsgpackHandle{}
+
+func init() {
+	// 解码到any时字符串不会变成[]byte
+	msgpackHandle.RawToString = true
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(value any) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(value)
+	return data, err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, value any) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(value)
+}
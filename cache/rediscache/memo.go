@@ -0,0 +1,108 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/cache"
+)
+
+type memoStore[V any] struct {
+	client goredis.UniversalClient
+	codec  Codec
+}
+
+// NewMemoStore 使用redis保存 cache.Memoize 的结果，codec为nil时使用JSON
+func NewMemoStore[V any](client goredis.UniversalClient, codec Codec) cache.MemoStore[V] {
+	if codec == nil {
+		codec = JSON
+	}
+	return &memoStore[V]{client: client, codec: codec}
+}
+
+func (s *memoStore[V]) Get(ctx context.Context, key string) (V, bool, error) {
+	var value V
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	if err := s.codec.Unmarshal(data, &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+func (s *memoStore[V]) Set(ctx context.Context, key string, value V, ttl time.Duration) error {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.Set(ctx, key, data, ttl).Err()
+}
+
+func (s *memoStore[V]) Delete(ctx context.Context, keys ...string) error {
+	_, err := deleteKeys(ctx, s.client, keys)
+	return err
+}
+
+// DeletePattern 使用SCAN查找匹配的key，集群时遍历每个主节点
+func (s *memoStore[V]) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	if cluster, ok := s.client.(*goredis.ClusterClient); ok {
+		var total int64
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+			n, err := scanDelete(ctx, node, pattern)
+			atomic.AddInt64(&total, int64(n))
+			return err
+		})
+		return int(total), err
+	}
+	return scanDelete(ctx, s.client, pattern)
+}
+
+func scanDelete(ctx context.Context, client goredis.Cmdable, pattern string) (int, error) {
+	deleted := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return deleted, err
+		}
+		n, err := deleteKeys(ctx, client, keys)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if cursor = next; cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// deleteKeys 逐个删除，集群中不同slot的key不能在一个DEL中删除
+func deleteKeys(ctx context.Context, client goredis.Cmdable, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Del(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, cmd := range cmds {
+		deleted += int(cmd.Val())
+	}
+	return deleted, nil
+}
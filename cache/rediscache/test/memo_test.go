@@ -0,0 +1,54 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isyscore/isc-gobase/cache"
+	"github.com/isyscore/isc-gobase/cache/rediscache"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestRedisMemoize(t *testing.T) {
+	server, client := newClient(t)
+	var calls int32
+	load := func(ctx context.Context, id int) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{Name: "user", Age: id}, nil
+	}
+	memo := cache.Memoize(load, cache.MemoOptions[int, user]{
+		Prefix: "memo:user",
+		TTL:    time.Minute,
+		Store:  rediscache.NewMemoStore[user](client, rediscache.MsgPack),
+	})
+	ctx := context.Background()
+
+	u, err := memo.Call(ctx, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u, user{Name: "user", Age: 18})
+	assert.Equal(t, server.Exists("memo:user:18"), true)
+	assert.Equal(t, server.TTL("memo:user:18"), time.Minute)
+
+	// 其他实例共享redis中的结果
+	other := cache.Memoize(load, cache.MemoOptions[int, user]{
+		Prefix: "memo:user",
+		Store:  rediscache.NewMemoStore[user](client, rediscache.MsgPack),
+	})
+	u, _ = other.Call(ctx, 18)
+	assert.Equal(t, u.Age, 18)
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(1))
+
+	_, _ = memo.Call(ctx, 19)
+	_, _ = memo.Call(ctx, 20)
+	// 字符集的匹配与本地缓存一致
+	n, err := memo.EvictPattern(ctx, "[2-9]?")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, server.Exists("memo:user:20"), false)
+	n, err = memo.EvictPattern(ctx, "*")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 2)
+	assert.Equal(t, server.Exists("memo:user:18"), false)
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/cache"
+	"github.com/isyscore/isc-gobase/cache/rediscache"
+	"github.com/magiconair/properties/assert"
+)
+
+type user struct {
+	Name string
+	Age  int
+}
+
+func newClient(t *testing.T) (*miniredis.Miniredis, goredis.UniversalClient) {
+	server := miniredis.RunT(t)
+	return server, goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+}
+
+func newCache(t *testing.T, client goredis.UniversalClient, config rediscache.Config[user]) *rediscache.Cache[user] {
+	c, err := rediscache.New[user](client, config)
+	assert.Equal(t, err, nil)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func TestReadThrough(t *testing.T) {
+	server, client := newClient(t)
+	var loads int32
+	c := newCache(t, client, rediscache.Config[user]{
+		Prefix:   "user:",
+		RedisTTL: time.Minute,
+		Loader: func(ctx context.Context, key string) (user, error) {
+			atomic.AddInt32(&loads, 1)
+			if key == "missing" {
+				return user{}, cache.ErrNotFound
+			}
+			return user{Name: key, Age: 18}, nil
+		},
+	})
+	ctx := context.Background()
+
+	u, err := c.Get(ctx, "vector")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u, user{Name: "vector", Age: 18})
+	// 加载后写入redis
+	data, _ := server.Get("user:vector")
+	assert.Equal(t, data, `{"Name":"vector","Age":18}`)
+	assert.Equal(t, server.TTL("user:vector"), time.Minute)
+
+	// 本地缓存命中
+	_, _ = c.Get(ctx, "vector")
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1))
+
+	// 本地没有时读取redis
+	c.InvalidateLocal("vector")
+	_, _ = c.Get(ctx, "vector")
+	assert.Equal(t, atomic.LoadInt32(&loads), int32(1))
+
+	_, err = c.Get(ctx, "missing")
+	assert.Equal(t, err, cache.ErrNotFound)
+}
+
+func TestInvalidation(t *testing.T) {
+	_, client := newClient(t)
+	ctx := context.Background()
+	a := newCache(t, client, rediscache.Config[user]{Prefix: "user:"})
+	b := newCache(t, client, rediscache.Config[user]{Prefix: "user:"})
+
+	assert.Equal(t, a.Set(ctx, "1", user{Name: "v1"}), nil)
+	u, err := b.Get(ctx, "1")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u.Name, "v1")
+
+	// a写入后b的本地缓存被删除，重新从redis读取
+	assert.Equal(t, a.Set(ctx, "1", user{Name: "v2"}), nil)
+	waitFor(t, func() bool {
+		u, _ := b.Get(ctx, "1")
+		return u.Name == "v2"
+	})
+
+	assert.Equal(t, a.Delete(ctx, "1"), nil)
+	waitFor(t, func() bool {
+		_, err := b.Get(ctx, "1")
+		return err == cache.ErrNotFound
+	})
+}
+
+func TestInvalidationDuringLoad(t *testing.T) {
+	server, client := newClient(t)
+	ctx := context.Background()
+	a := newCache(t, client, rediscache.Config[user]{Prefix: "user:"})
+	b := newCache(t, client, rediscache.Config[user]{Prefix: "user:"})
+
+	// 通知按照顺序处理，marker更新后之前的通知已经处理
+	assert.Equal(t, a.Set(ctx, "marker", user{Name: "m1"}), nil)
+	_, _ = b.Get(ctx, "marker")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan user)
+	go func() {
+		u, _ := b.GetOrLoad(ctx, "1", func(ctx context.Context, key string) (user, error) {
+			close(started)
+			<-release
+			return user{Name: "stale"}, nil
+		})
+		done <- u
+	}()
+	<-started
+	assert.Equal(t, a.Set(ctx, "1", user{Name: "v2"}), nil)
+	assert.Equal(t, a.Set(ctx, "marker", user{Name: "m2"}), nil)
+	waitFor(t, func() bool {
+		u, _ := b.Get(ctx, "marker")
+		return u.Name == "m2"
+	})
+	close(release)
+	assert.Equal(t, (<-done).Name, "stale")
+
+	// 加载期间收到了失效通知，旧的数据不写入本地缓存，也不覆盖redis中新的数据
+	u, err := b.Get(ctx, "1")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, u.Name, "v2")
+	data, _ := server.Get("user:1")
+	assert.Equal(t, data, `{"Name":"v2","Age":0}`)
+}
+
+func TestLoadDetachedFromCaller(t *testing.T) {
+	_, client := newClient(t)
+	c := newCache(t, client, rediscache.Config[user]{Prefix: "user:"})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func(ctx context.Context, key string) (user, error) {
+		close(started)
+		<-release
+		if ctx.Err() != nil {
+			return user{}, ctx.Err()
+		}
+		return user{Name: key}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "1", loader)
+		first <- err
+	}()
+	<-started
+	// 第一个调用方取消之后，等待同一次加载的调用方仍然可以拿到结果
+	cancel()
+	second := make(chan user, 1)
+	go func() {
+		u, _ := c.GetOrLoad(context.Background(), "1", loader)
+		second <- u
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	assert.Equal(t, (<-second).Name, "1")
+	assert.Equal(t, <-first, nil)
+}
+
+func TestCodec(t *testing.T) {
+	for _, codec := range []rediscache.Codec{rediscache.JSON, rediscache.Gob, rediscache.MsgPack} {
+		_, client := newClient(t)
+		ctx := context.Background()
+		a := newCache(t, client, rediscache.Config[user]{Codec: codec})
+		b := newCache(t, client, rediscache.Config[user]{Codec: codec})
+
+		assert.Equal(t, a.Set(ctx, "1", user{Name: "库陈胜", Age: 29}), nil)
+		u, err := b.Get(ctx, "1")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, u, user{Name: "库陈胜", Age: 29})
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
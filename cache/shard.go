@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	// 过期的时间点（UnixNano），0表示永不过期
+	expireAt int64
+	size     int64
+	// 在过期堆中的下标，-1表示不在堆中
+	index int
+}
+
+func (e *entry[K, V]) expired(now int64) bool {
+	return e.expireAt > 0 && now > e.expireAt
+}
+
+// expiryHeap 按照过期时间排序的最小堆，清理时只需要弹出已经过期的数据
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].expireAt < h[j].expireAt }
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+type evictedEntry[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// shard 一个分片，拥有独立的锁、过期堆以及淘汰策略
+type shard[K comparable, V any] struct {
+	stats statsCounter
+
+	mu     sync.RWMutex
+	items  map[K]*entry[K, V]
+	expiry expiryHeap[K, V]
+	// 没有容量限制时为nil
+	policy     evictionPolicy[K]
+	maxEntries int
+	maxMemory  int64
+	memory     int64
+}
+
+func newShard[K comparable, V any](maxEntries int, maxMemory int64, policy EvictionPolicy) *shard[K, V] {
+	s := &shard[K, V]{
+		items:      map[K]*entry[K, V]{},
+		maxEntries: maxEntries,
+		maxMemory:  maxMemory,
+	}
+	if maxEntries > 0 || maxMemory > 0 {
+		s.policy = newEvictionPolicy[K](policy, maxEntries)
+	}
+	return s
+}
+
+// lookup 获取没有过期的数据，过期的数据直接删除；需要持有写锁
+func (s *shard[K, V]) lookup(key K, now int64, evicted *[]evictedEntry[K, V]) (*entry[K, V], bool) {
+	e, found := s.items[key]
+	if !found {
+		return nil, false
+	}
+	if e.expired(now) {
+		s.remove(e, ReasonExpired, evicted)
+		return nil, false
+	}
+	return e, true
+}
+
+// set 保存数据，替换已有的数据；需要持有写锁
+func (s *shard[K, V]) set(key K, value V, expireAt int64, size int64, now int64, evicted *[]evictedEntry[K, V]) {
+	if e, found := s.items[key]; found {
+		reason := ReasonReplaced
+		if e.expired(now) {
+			reason = ReasonExpired
+			s.stats.expired()
+		}
+		*evicted = append(*evicted, evictedEntry[K, V]{key: key, value: e.value, reason: reason})
+		e.value = value
+		s.setExpiry(e, expireAt)
+		s.update(e, size, evicted)
+		return
+	}
+	e := &entry[K, V]{key: key, value: value, index: -1}
+	s.items[key] = e
+	s.setExpiry(e, expireAt)
+	s.memory += size
+	e.size = size
+	// 新的key在检查容量之后加入淘汰策略，避免刚加入就被淘汰
+	s.enforceCapacity(evicted)
+	if s.policy != nil && s.items[key] == e {
+		s.policy.add(key)
+	}
+}
+
+// update 数据修改后记录访问并重新检查容量；需要持有写锁
+func (s *shard[K, V]) update(e *entry[K, V], size int64, evicted *[]evictedEntry[K, V]) {
+	s.memory += size - e.size
+	e.size = size
+	s.access(e.key)
+	s.enforceCapacity(evicted)
+}
+
+func (s *shard[K, V]) setExpiry(e *entry[K, V], expireAt int64) {
+	e.expireAt = expireAt
+	switch {
+	case expireAt <= 0 && e.index >= 0:
+		heap.Remove(&s.expiry, e.index)
+	case expireAt > 0 && e.index >= 0:
+		heap.Fix(&s.expiry, e.index)
+	case expireAt > 0:
+		heap.Push(&s.expiry, e)
+	}
+}
+
+// access 记录访问；需要持有写锁
+func (s *shard[K, V]) access(key K) {
+	if s.policy != nil {
+		s.policy.access(key)
+	}
+}
+
+func (s *shard[K, V]) remove(e *entry[K, V], reason EvictionReason, evicted *[]evictedEntry[K, V]) {
+	delete(s.items, e.key)
+	if e.index >= 0 {
+		heap.Remove(&s.expiry, e.index)
+	}
+	if s.policy != nil {
+		s.policy.remove(e.key)
+	}
+	s.memory -= e.size
+	switch reason {
+	case ReasonExpired:
+		s.stats.expired()
+	case ReasonEvicted:
+		s.stats.evicted()
+	}
+	*evicted = append(*evicted, evictedEntry[K, V]{key: e.key, value: e.value, reason: reason})
+}
+
+// deleteExpired 从堆顶弹出已经过期的数据；需要持有写锁
+func (s *shard[K, V]) deleteExpired(now int64, evicted *[]evictedEntry[K, V]) {
+	for len(s.expiry) > 0 && s.expiry[0].expired(now) {
+		s.remove(s.expiry[0], ReasonExpired, evicted)
+	}
+}
+
+func (s *shard[K, V]) overCapacity() bool {
+	return (s.maxEntries > 0 && len(s.items) > s.maxEntries) || (s.maxMemory > 0 && s.memory > s.maxMemory)
+}
+
+func (s *shard[K, V]) enforceCapacity(evicted *[]evictedEntry[K, V]) {
+	if s.policy == nil {
+		return
+	}
+	for s.overCapacity() {
+		key, ok := s.policy.victim()
+		if !ok {
+			return
+		}
+		e, found := s.items[key]
+		if !found {
+			s.policy.remove(key)
+			continue
+		}
+		// 已经过期的按照过期统计
+		reason := ReasonEvicted
+		if e.expired(time.Now().UnixNano()) {
+			reason = ReasonExpired
+		}
+		s.remove(e, reason, evicted)
+	}
+}
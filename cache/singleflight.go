@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+type flightCall[V any] struct {
+	wg    sync.WaitGroup
+	value V
+	err   error
+}
+
+// flightGroup 同一个key同时只执行一次加载，其他调用等待并共享结果
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// do 执行或者等待正在执行的fn，shared表示结果是否来自其他调用
+func (g *flightGroup[K, V]) do(key K, fn func() (V, error)) (value V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*flightCall[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err, true
+	}
+	c := &flightCall[V]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.run(key, c, fn)
+	return c.value, c.err, false
+}
+
+// doAsync 没有正在执行的加载时在新的协程中执行fn，返回是否启动
+func (g *flightGroup[K, V]) doAsync(key K, fn func() (V, error)) bool {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*flightCall[V]{}
+	}
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return false
+	}
+	c := &flightCall[V]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			// 异步加载的panic不能影响调用方，结果已经通过err通知等待者，这里只打印日志
+			if r := recover(); r != nil {
+				logger.Warn("异步加载缓存panic，key：%v，错误：%v", key, r)
+			}
+		}()
+		g.run(key, c, fn)
+	}()
+	return true
+}
+
+// run fn panic时通知等待者后继续panic
+func (g *flightGroup[K, V]) run(key K, c *flightCall[V], fn func() (V, error)) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			c.err = fmt.Errorf("cache: 加载数据panic：%v", r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+	c.value, c.err = fn()
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+const (
+	snapshotMagic = "isc-gobase/cache"
+	// SnapshotVersion 快照格式的版本，格式不兼容时递增
+	SnapshotVersion = 1
+)
+
+// ErrSnapshotVersion 快照不是当前格式的版本
+var ErrSnapshotVersion = errors.New("cache: 快照的版本不支持")
+
+func init() {
+	// hash、list、set 等操作保存的数据类型；其他存放在any中的自定义类型需要调用方 gob.Register
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register(memberSet{})
+	gob.Register(&sortedSet{})
+	gob.Register(expiringField{})
+}
+
+type snapshotHeader struct {
+	Magic     string
+	Version   int
+	CreatedAt int64
+}
+
+// snapshotChunk 每个分片写入一块，最后写入 End 为true的一块
+type snapshotChunk[K comparable, V any] struct {
+	Entries []snapshotEntry[K, V]
+	End     bool
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+	// 过期的时间点（UnixNano），0表示永不过期
+	ExpireAt int64
+}
+
+// SaveTo 使用gob将没有过期的数据以及过期时间写入w
+func (c *shardedCache[K, V]) SaveTo(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Magic: snapshotMagic, Version: SnapshotVersion, CreatedAt: time.Now().UnixNano()}); err != nil {
+		return err
+	}
+	for _, s := range c.shards {
+		if err := c.saveShard(enc, s); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(snapshotChunk[K, V]{End: true})
+}
+
+// saveShard 持有读锁编码，hash以及list的数据在写锁内修改，编码时不会被并发修改
+func (c *shardedCache[K, V]) saveShard(enc *gob.Encoder, s *shard[K, V]) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now().UnixNano()
+	chunk := snapshotChunk[K, V]{Entries: make([]snapshotEntry[K, V], 0, len(s.items))}
+	for _, e := range s.items {
+		if !e.expired(now) {
+			chunk.Entries = append(chunk.Entries, snapshotEntry[K, V]{Key: e.key, Value: e.value, ExpireAt: e.expireAt})
+		}
+	}
+	return enc.Encode(chunk)
+}
+
+// LoadFrom 读取 SaveTo 写入的快照，跳过已经过期的数据，返回加载的条数
+func (c *shardedCache[K, V]) LoadFrom(r io.Reader) (int, error) {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, err
+	}
+	if header.Magic != snapshotMagic || header.Version != SnapshotVersion {
+		return 0, fmt.Errorf("%w：%s %d", ErrSnapshotVersion, header.Magic, header.Version)
+	}
+	loaded := 0
+	for {
+		var chunk snapshotChunk[K, V]
+		if err := dec.Decode(&chunk); err != nil {
+			return loaded, err
+		}
+		if chunk.End {
+			return loaded, nil
+		}
+		now := time.Now().UnixNano()
+		for _, e := range chunk.Entries {
+			ttl := NoExpiration
+			if e.ExpireAt > 0 {
+				if now > e.ExpireAt {
+					continue
+				}
+				ttl = time.Duration(e.ExpireAt - now)
+			}
+			c.SetWithTTL(e.Key, e.Value, ttl)
+			loaded++
+		}
+	}
+}
+
+// SaveFile 将快照写入文件，先写临时文件再重命名，避免进程退出时留下不完整的快照
+func (c *shardedCache[K, V]) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if err := c.SaveTo(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile 从文件加载快照，文件不存在时不加载
+func (c *shardedCache[K, V]) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return c.LoadFrom(f)
+}
+
+// snapshot 定时快照以及关闭时的快照
+func (c *shardedCache[K, V]) snapshot() {
+	if c.snapshotFile == "" {
+		return
+	}
+	if err := c.SaveFile(c.snapshotFile); err != nil {
+		logger.Warn("保存缓存快照失败，文件：%s，错误：%v", c.snapshotFile, err)
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := New()
+	defer c.Close()
+	_ = c.Set("string", "value")
+	_ = c.SetWithTTL("ttl", 1, time.Hour)
+	_ = c.SetWithTTL("expired", 1, 10*time.Millisecond)
+	_ = c.SetHash("hash", "field", "value")
+	_ = c.AddItem("list", "a", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.Equal(t, c.SaveTo(&buf), nil)
+
+	restored := New()
+	defer restored.Close()
+	n, err := restored.LoadFrom(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 4)
+
+	v, _ := restored.Get("string")
+	assert.Equal(t, v, "value")
+	_, found := restored.Get("expired")
+	assert.Equal(t, found, false)
+	v, _ = restored.GetHash("hash", "field")
+	assert.Equal(t, v, "value")
+	assert.Equal(t, restored.GetItem("list"), []any{"a", 1})
+
+	// 过期时间保留
+	s := restored.shardOf("ttl")
+	expireAt := s.items["ttl"].expireAt
+	assert.Equal(t, expireAt > time.Now().Add(59*time.Minute).UnixNano(), true)
+	assert.Equal(t, expireAt <= time.Now().Add(time.Hour).UnixNano(), true)
+}
+
+func TestSnapshotSkipExpired(t *testing.T) {
+	c := NewTyped[int, string](Config[int, string]{})
+	defer c.Close()
+	c.SetWithTTL(1, "short", 20*time.Millisecond)
+	c.Set(2, "forever")
+
+	var buf bytes.Buffer
+	assert.Equal(t, c.SaveTo(&buf), nil)
+	// 保存之后过期的数据在加载时跳过
+	time.Sleep(30 * time.Millisecond)
+
+	restored := NewTyped[int, string](Config[int, string]{})
+	defer restored.Close()
+	n, err := restored.LoadFrom(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 1)
+	v, _ := restored.Get(2)
+	assert.Equal(t, v, "forever")
+
+	_, err = restored.LoadFrom(bytes.NewReader(nil))
+	assert.Equal(t, err != nil, true)
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	c := NewTyped[string, int](Config[string, int]{})
+	defer c.Close()
+	var buf bytes.Buffer
+	assert.Equal(t, c.SaveTo(&buf), nil)
+	data := bytes.Replace(buf.Bytes(), []byte(snapshotMagic), []byte("isc-gobase/other"), 1)
+	_, err := c.LoadFrom(bytes.NewReader(data))
+	assert.Equal(t, errors.Is(err, ErrSnapshotVersion), true)
+}
+
+func TestSnapshotFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.snapshot")
+	c := NewWithOptions(Options{SnapshotFile: file, SnapshotInterval: 10 * time.Millisecond})
+	_ = c.Set("a", "1")
+	_ = c.SetHash("h", "f", 2)
+	time.Sleep(30 * time.Millisecond)
+
+	// 定时保存的快照
+	periodic := NewTyped[string, any](Config[string, any]{SnapshotFile: file})
+	v, _ := periodic.Get("a")
+	assert.Equal(t, v, "1")
+	periodic.Close()
+
+	// 关闭时保存，重新创建时加载
+	_ = c.Set("b", "2")
+	c.Close()
+	restored := NewWithOptions(Options{SnapshotFile: file})
+	defer restored.Close()
+	v, _ = restored.Get("b")
+	assert.Equal(t, v, "2")
+	v, _ = restored.GetHash("h", "f")
+	assert.Equal(t, v, 2)
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Stats 缓存的统计
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	// 当前的条数
+	Entries int
+	// 当前占用的内存（估算值），只有配置了 MaxMemory 时才统计
+	Memory int64
+}
+
+// HitRate 命中率
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type statsCounter struct {
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+func (s *statsCounter) record(hit bool) {
+	if hit {
+		atomic.AddUint64(&s.hits, 1)
+	} else {
+		atomic.AddUint64(&s.misses, 1)
+	}
+}
+
+func (s *statsCounter) evicted() {
+	atomic.AddUint64(&s.evictions, 1)
+}
+
+func (s *statsCounter) expired() {
+	atomic.AddUint64(&s.expirations, 1)
+}
+
+func (s *Stats) add(counter *statsCounter) {
+	s.Hits += atomic.LoadUint64(&counter.hits)
+	s.Misses += atomic.LoadUint64(&counter.misses)
+	s.Evictions += atomic.LoadUint64(&counter.evictions)
+	s.Expirations += atomic.LoadUint64(&counter.expirations)
+}
+
+// EstimateSize 按照数据的类型递归估算占用的内存
+func EstimateSize(value any) int64 {
+	if value == nil {
+		return 0
+	}
+	return estimateSize(reflect.ValueOf(value), 0)
+}
+
+func estimateSize(v reflect.Value, depth int) int64 {
+	if depth > 8 || !v.IsValid() {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return int64(16 + v.Len())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return int64(24 + v.Len())
+		}
+		size := int64(24)
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Array:
+		size := int64(0)
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(48)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimateSize(iter.Key(), depth+1) + estimateSize(iter.Value(), depth+1)
+		}
+		return size
+	case reflect.Struct:
+		size := int64(0)
+		for i := 0; i < v.NumField(); i++ {
+			size += estimateSize(v.Field(i), depth+1)
+		}
+		return size
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 8
+		}
+		return 8 + estimateSize(v.Elem(), depth+1)
+	default:
+		return int64(v.Type().Size())
+	}
+}
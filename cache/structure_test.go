@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"bytes"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSet(t *testing.T) {
+	c := New()
+	defer c.Close()
+	added, err := c.SAdd("tags", "go", "redis", "go")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, added, 2)
+	added, _ = c.SAdd("tags", "cache", "go")
+	assert.Equal(t, added, 1)
+
+	members, _ := c.SMembers("tags")
+	assert.Equal(t, members, []string{"cache", "go", "redis"})
+	exist, _ := c.SIsMember("tags", "redis")
+	assert.Equal(t, exist, true)
+
+	removed, _ := c.SRem("tags", "cache", "go", "redis", "none")
+	assert.Equal(t, removed, 3)
+	// 集合为空时删除key
+	_, found := c.Get("tags")
+	assert.Equal(t, found, false)
+
+	_ = c.Set("string", "value")
+	_, err = c.SAdd("string", "a")
+	assert.Equal(t, err, errNotSet)
+}
+
+func TestSortedSet(t *testing.T) {
+	c := New()
+	defer c.Close()
+	added, err := c.ZAdd("rank", ZMember{"a", 3}, ZMember{"b", 1}, ZMember{"c", 2}, ZMember{"d", 2})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, added, 4)
+	// 更新分数
+	added, _ = c.ZAdd("rank", ZMember{"a", 0})
+	assert.Equal(t, added, 0)
+
+	members, _ := c.ZRange("rank", 0, -1)
+	assert.Equal(t, members, []ZMember{{"a", 0}, {"b", 1}, {"c", 2}, {"d", 2}})
+	members, _ = c.ZRange("rank", -2, 10)
+	assert.Equal(t, members, []ZMember{{"c", 2}, {"d", 2}})
+
+	members, _ = c.ZRangeByScore("rank", 1, 2)
+	assert.Equal(t, members, []ZMember{{"b", 1}, {"c", 2}, {"d", 2}})
+	members, _ = c.ZRangeByScore("rank", math.Inf(-1), 0.5)
+	assert.Equal(t, members, []ZMember{{"a", 0}})
+
+	score, _ := c.ZIncrBy("rank", 5, "b")
+	assert.Equal(t, score, float64(6))
+	score, exist, _ := c.ZScore("rank", "b")
+	assert.Equal(t, score, float64(6))
+	assert.Equal(t, exist, true)
+
+	removed, _ := c.ZRem("rank", "b", "none")
+	assert.Equal(t, removed, 1)
+	n, _ := c.ZCard("rank")
+	assert.Equal(t, n, 3)
+}
+
+func TestSortedSetNaN(t *testing.T) {
+	c := New()
+	defer c.Close()
+	_, err := c.ZAdd("rank", ZMember{"a", math.NaN()})
+	assert.Equal(t, err, errZScoreNaN)
+	_, found := c.Get("rank")
+	assert.Equal(t, found, false)
+
+	added, err := c.ZAdd("rank", ZMember{"a", math.Inf(1)})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, added, 1)
+	_, err = c.ZIncrBy("rank", math.NaN(), "a")
+	assert.Equal(t, err, errZScoreNaN)
+	_, err = c.ZIncrBy("rank", math.Inf(-1), "a")
+	assert.Equal(t, err, errZScoreNaN)
+
+	// 原有成员的分数没有被破坏，可以正常更新
+	added, err = c.ZAdd("rank", ZMember{"a", 1})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, added, 0)
+	members, _ := c.ZRange("rank", 0, -1)
+	assert.Equal(t, members, []ZMember{{"a", 1}})
+}
+
+func TestCounter(t *testing.T) {
+	c := NewWithExpiration(time.Hour)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.Incr("counter")
+		}()
+	}
+	wg.Wait()
+	v, _ := c.Get("counter")
+	assert.Equal(t, v, int64(100))
+
+	n, _ := c.DecrBy("counter", 30)
+	assert.Equal(t, n, int64(70))
+
+	// 和redis一样接受数字字符串
+	_ = c.Set("string", "41")
+	n, _ = c.Incr("string")
+	assert.Equal(t, n, int64(42))
+
+	_ = c.Set("text", "abc")
+	_, err := c.Incr("text")
+	assert.Equal(t, err, errNotInteger)
+
+	_ = c.Set("max", int64(math.MaxInt64))
+	_, err = c.Incr("max")
+	assert.Equal(t, err, errOverflow)
+}
+
+func TestListPop(t *testing.T) {
+	c := New()
+	defer c.Close()
+	_ = c.RPush("list", 1, 2)
+	_ = c.LPush("list", 3, 4)
+	assert.Equal(t, c.GetItem("list"), []any{4, 3, 1, 2})
+
+	v, popped, _ := c.LPop("list")
+	assert.Equal(t, v, 4)
+	assert.Equal(t, popped, true)
+	v, _, _ = c.RPop("list")
+	assert.Equal(t, v, 2)
+	n, _ := c.LLen("list")
+	assert.Equal(t, n, 2)
+
+	_, _, _ = c.LPop("list")
+	_, _, _ = c.LPop("list")
+	_, popped, _ = c.LPop("list")
+	assert.Equal(t, popped, false)
+	_, found := c.Get("list")
+	assert.Equal(t, found, false)
+}
+
+func TestBlockingPop(t *testing.T) {
+	c := New()
+	defer c.Close()
+
+	_, _, err := c.BLPop(20*time.Millisecond, "queue")
+	assert.Equal(t, err, ErrTimeout)
+
+	type popResult struct {
+		key   string
+		value any
+	}
+	results := make(chan popResult, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			key, value, err := c.BRPop(time.Second, "queue", "other")
+			if err == nil {
+				results <- popResult{key, value}
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	_ = c.AddItem("other", "a")
+	_ = c.AddItem("queue", "b")
+
+	got := map[string]any{}
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-results:
+			got[r.key] = r.value
+		case <-time.After(time.Second):
+			t.Fatal("等待超时")
+		}
+	}
+	assert.Equal(t, got, map[string]any{"other": "a", "queue": "b"})
+}
+
+func TestHashFieldTTL(t *testing.T) {
+	c := New()
+	defer c.Close()
+	_ = c.SetHash("user", "name", "vector")
+	_ = c.SetHash("user", "token", "abc")
+
+	exist, _ := c.HExpire("user", "token", 20*time.Millisecond)
+	assert.Equal(t, exist, true)
+	exist, _ = c.HExpire("user", "none", time.Second)
+	assert.Equal(t, exist, false)
+
+	v, found := c.GetHash("user", "token")
+	assert.Equal(t, v, "abc")
+	assert.Equal(t, found, true)
+	ttl, _, _ := c.HTTL("user", "token")
+	assert.Equal(t, ttl > 0 && ttl <= 20*time.Millisecond, true)
+	ttl, _, _ = c.HTTL("user", "name")
+	assert.Equal(t, ttl, NoExpiration)
+
+	// 快照中保留字段的过期时间
+	var buf bytes.Buffer
+	assert.Equal(t, c.SaveTo(&buf), nil)
+	restored := New()
+	defer restored.Close()
+	_, _ = restored.LoadFrom(&buf)
+
+	time.Sleep(30 * time.Millisecond)
+	for _, cache := range []*cache{c, restored} {
+		_, found = cache.GetHash("user", "token")
+		assert.Equal(t, found, false)
+		all, _ := cache.HGetAll("user")
+		assert.Equal(t, all, map[string]any{"name": "vector"})
+	}
+
+	// 重新设置字段会清除过期时间，所有字段过期后删除key
+	_ = c.SetHash("user", "token", "def")
+	_, _ = c.HExpire("user", "token", 10*time.Millisecond)
+	_ = c.SetHash("user", "token", "ghi")
+	persisted, _ := c.HPersist("user", "token")
+	assert.Equal(t, persisted, false)
+	_, _ = c.HExpire("user", "name", 0)
+	_, _ = c.HExpire("user", "token", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	_, found = c.GetHash("user", "token")
+	assert.Equal(t, found, false)
+	_, found = c.Get("user")
+	assert.Equal(t, found, false)
+}
@@ -17,6 +17,7 @@ func MD5String(s string) string {
 
 func MD5File(filePath string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		m := md5.New()
 		_, _ = io.Copy(m, file)
 		return fmt.Sprintf("%x", m.Sum(nil)), nil
@@ -32,6 +33,7 @@ func Sha1String(s string) string {
 
 func Sha1File(filePath string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		s := sha1.New()
 		_, _ = io.Copy(s, file)
 		return fmt.Sprintf("%x", s.Sum(nil)), nil
@@ -48,6 +50,7 @@ func Sha256String(s string) string {
 
 func Sha256File(filePath string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		s := sha256.New()
 		_, _ = io.Copy(s, file)
 		return fmt.Sprintf("%x", s.Sum(nil)), nil
@@ -64,6 +67,7 @@ func HMacMD5String(s string, key string) string {
 
 func HMacMD5File(filePath string, key string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		h := hmac.New(md5.New, []byte(key))
 		_, _ = io.Copy(h, file)
 		return fmt.Sprintf("%x", h.Sum(nil)), nil
@@ -80,6 +84,7 @@ func HMacSha1String(s string, key string) string {
 
 func HMacSha1File(filePath string, key string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		h := hmac.New(sha1.New, []byte(key))
 		_, _ = io.Copy(h, file)
 		return fmt.Sprintf("%x", h.Sum(nil)), nil
@@ -96,6 +101,7 @@ func HMacSha256String(s string, key string) string {
 
 func HMacSha256File(filePath string, key string) (string, error) {
 	if file, err := os.Open(filePath); err == nil {
+		defer func() { _ = file.Close() }()
 		h := hmac.New(sha256.New, []byte(key))
 		_, _ = io.Copy(h, file)
 		return fmt.Sprintf("%x", h.Sum(nil)), nil
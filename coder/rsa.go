@@ -59,23 +59,10 @@ func RSAGenerateKeyPair(size int, privateKeyPath string, publicKeyPath string) e
 }
 
 func RSAEncrypt(content string, publicKeyPath string) (string, error) {
-	file, err := os.Open(publicKeyPath)
+	pubKey, err := RSALoadPublicKey(publicKeyPath)
 	if err != nil {
 		return "", err
 	}
-	defer func(file *os.File) { _ = file.Close() }(file)
-	info, _ := file.Stat()
-	buf := make([]byte, info.Size())
-	_, err = file.Read(buf)
-	if err != nil {
-		return "", err
-	}
-	block, _ := pem.Decode(buf)
-	pubKeyIntf, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err != nil {
-		return "", err
-	}
-	pubKey := pubKeyIntf.(*rsa.PublicKey)
 	text, err := rsa.EncryptPKCS1v15(rand.Reader, pubKey, []byte(content))
 	if err != nil {
 		return "", err
@@ -84,29 +71,69 @@ func RSAEncrypt(content string, publicKeyPath string) (string, error) {
 }
 
 func RSADecrypt(content string, privateKeyPath string) (string, error) {
-	file, err := os.Open(privateKeyPath)
+	privKey, err := RSALoadPrivateKey(privateKeyPath)
 	if err != nil {
 		return "", err
 	}
-	defer func(file *os.File) { _ = file.Close() }(file)
-	info, _ := file.Stat()
-	buf := make([]byte, info.Size())
-	_, err = file.Read(buf)
+	b, err := hex.DecodeString(content)
 	if err != nil {
 		return "", err
 	}
-	block, _ := pem.Decode(buf)
-	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	text, err := rsa.DecryptPKCS1v15(rand.Reader, privKey, b)
 	if err != nil {
 		return "", err
 	}
-	b, err := hex.DecodeString(content)
+	return string(text), nil
+}
+
+// RSALoadPublicKey 读取pem格式的公钥，支持PKIX和PKCS1
+func RSALoadPublicKey(publicKeyPath string) (*rsa.PublicKey, error) {
+	block, err := readPemBlock(publicKeyPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	text, err := rsa.DecryptPKCS1v15(rand.Reader, privKey, b)
+	if pubKey, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pubKey, nil
+	}
+	pubKeyIntf, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(text), nil
+	pubKey, ok := pubKeyIntf.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是rsa公钥", publicKeyPath)
+	}
+	return pubKey, nil
+}
+
+// RSALoadPrivateKey 读取pem格式的私钥，支持PKCS1和PKCS8
+func RSALoadPrivateKey(privateKeyPath string) (*rsa.PrivateKey, error) {
+	block, err := readPemBlock(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privKey, nil
+	}
+	privKeyIntf, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privKey, ok := privKeyIntf.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是rsa私钥", privateKeyPath)
+	}
+	return privKey, nil
+}
+
+func readPemBlock(path string) (*pem.Block, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是pem格式的文件", path)
+	}
+	return block, nil
 }
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"strings"
 )
@@ -35,3 +36,13 @@ func GzipDecompress(data []byte) ([]byte, error) {
 	}
 	return d, err
 }
+
+// GzipCopy 将src流式压缩后写入dst，返回读取的原始字节数，不会将全部内容读入内存
+func GzipCopy(dst io.Writer, src io.Reader) (int64, error) {
+	gz := gzip.NewWriter(dst)
+	n, err := io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
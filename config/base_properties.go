@@ -94,11 +94,11 @@ type RedisConfig struct {
 	Username string
 
 	// 单节点
-	Standalone RedisStandaloneConfig
+	Standalone RedisStandaloneConfig `match:"check"`
 	// 哨兵
-	Sentinel RedisSentinelConfig
+	Sentinel RedisSentinelConfig `match:"check"`
 	// 集群
-	Cluster RedisClusterConfig
+	Cluster RedisClusterConfig `match:"check"`
 
 	// ----- 命令执行失败配置 -----
 	// 命令执行失败时候，最大重试次数，默认3次，-1（不是0）则不重试
@@ -131,6 +131,13 @@ type RedisConfig struct {
 	IdleTimeout int
 	// （单位毫秒）空闲链接核查频率，默认1分钟。-1禁止空闲链接核查，即使配置了IdleTime也不行
 	IdleCheckFrequency int
+
+	// （单位毫秒）执行时间超过该值的命令打印告警日志，默认100毫秒，-1则不打印
+	SlowThreshold int
+
+	// 命名的实例：base.redis.instances.{name}，配置和默认实例相同，通过 redis.GetClientByName 获取
+	// 类型是递归的，disable避免核查时递归搜集核查器，map中每个实例的配置仍然会核查
+	Instances map[string]RedisConfig `disable:"true"`
 }
 
 // base.redis.standalone
@@ -138,7 +145,7 @@ type RedisStandaloneConfig struct {
 	Addr     string
 	Database int
 	// 网络类型，tcp或者unix，默认tcp
-	Network  string `match:"value={tcp, unix}"  errMsg:"network值不合法，只可为两个值：tcp和unix"`
+	Network  string `match:"isBlank value={tcp, unix}"  errMsg:"network值不合法，只可为两个值：tcp和unix"`
 	ReadOnly bool
 }
 
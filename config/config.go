@@ -62,6 +62,27 @@ func LoadConfigFromAbsPath(resourceAbsPath string) {
 	}
 }
 
+// LoadYamlContent 从yaml内容加载配置，覆盖已有的配置，用于测试等不读取配置文件的场景
+func LoadYamlContent(content string) error {
+	property, err := ParseYamlContent(content)
+	if err != nil {
+		return err
+	}
+
+	loadLock.Lock()
+	defer loadLock.Unlock()
+	appProperty = property
+	configExist = true
+	configLoaded = true
+
+	ApiModule = GetValueString("api-module")
+	BaseCfg = BaseConfig{}
+	if err := GetValueObject("base", &BaseCfg); err != nil {
+		log.Printf("加载 Base 配置失败(%v)", err)
+	}
+	return nil
+}
+
 // AppendConfigFromRelativePath 追加配置：相对路径的配置文件
 func AppendConfigFromRelativePath(fileName string) {
 	dir, _ := os.Getwd()
@@ -529,290 +550,319 @@ func SetValue(key string, value any) {
 }
 
 func GetValueString(key string) string {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return ""
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToString(value)
 	}
 	return ""
 }
 
 func GetValueInt(key string) int {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt(value)
 	}
 	return 0
 }
 
 func GetValueInt8(key string) int8 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt8(value)
 	}
 	return 0
 }
 
 func GetValueInt16(key string) int16 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt16(value)
 	}
 	return 0
 }
 
 func GetValueInt32(key string) int32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt32(value)
 	}
 	return 0
 }
 
 func GetValueInt64(key string) int64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt64(value)
 	}
 	return 0
 }
 
 func GetValueUInt(key string) uint {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt(value)
 	}
 	return 0
 }
 
 func GetValueUInt8(key string) uint8 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt8(value)
 	}
 	return 0
 }
 
 func GetValueUInt16(key string) uint16 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt16(value)
 	}
 	return 0
 }
 
 func GetValueUInt32(key string) uint32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt32(value)
 	}
 	return 0
 }
 
 func GetValueUInt64(key string) uint64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt64(value)
 	}
 	return 0
 }
 
 func GetValueFloat32(key string) float32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToFloat32(value)
 	}
 	return 0
 }
 
 func GetValueFloat64(key string) float64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return 0
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToFloat64(value)
 	}
 	return 0
 }
 
 func GetValueBool(key string) bool {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return false
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToBool(value)
 	}
 	return false
 }
 
 func GetValueStringDefault(key, defaultValue string) string {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToString(value)
 	}
 	return defaultValue
 }
 
 func GetValueIntDefault(key string, defaultValue int) int {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt(value)
 	}
 	return defaultValue
 }
 
 func GetValueInt8Default(key string, defaultValue int8) int8 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt8(value)
 	}
 	return defaultValue
 }
 
 func GetValueInt16Default(key string, defaultValue int16) int16 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt16(value)
 	}
 	return defaultValue
 }
 
 func GetValueInt32Default(key string, defaultValue int32) int32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt32(value)
 	}
 	return defaultValue
 }
 
 func GetValueInt64Default(key string, defaultValue int64) int64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToInt64(value)
 	}
 	return defaultValue
 }
 
 func GetValueUIntDefault(key string, defaultValue uint) uint {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt(value)
 	}
 	return defaultValue
 }
 
 func GetValueUInt8Default(key string, defaultValue uint8) uint8 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt8(value)
 	}
 	return defaultValue
 }
 
 func GetValueUInt16Default(key string, defaultValue uint16) uint16 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt16(value)
 	}
 	return defaultValue
 }
 
 func GetValueUInt32Default(key string, defaultValue uint32) uint32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt32(value)
 	}
 	return defaultValue
 }
 
 func GetValueUInt64Default(key string, defaultValue uint64) uint64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToUInt64(value)
 	}
 	return defaultValue
 }
 
 func GetValueFloat32Default(key string, defaultValue float32) float32 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToFloat32(value)
 	}
 	return defaultValue
 }
 
 func GetValueFloat64Default(key string, defaultValue float64) float64 {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToFloat64(value)
 	}
 	return defaultValue
 }
 
 func GetValueBoolDefault(key string, defaultValue bool) bool {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return defaultValue
 	}
-	if value, exist := appProperty.ValueMap[key]; exist {
+	if value, exist := property.ValueMap[key]; exist {
 		return isc.ToBool(value)
 	}
 	return defaultValue
 }
 
 func GetValueObject(key string, targetPtrObj any) error {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return nil
 	}
-	data := doGetValue(appProperty.ValueDeepMap, key)
+	data := doGetValue(property.ValueDeepMap, key)
 	err := isc.DataToObject(data, targetPtrObj)
 	if err != nil {
 		return err
@@ -821,12 +871,13 @@ func GetValueObject(key string, targetPtrObj any) error {
 }
 
 func GetValueArray(key string) []any {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return nil
 	}
 
 	var arrayResult []any
-	data := doGetValue(appProperty.ValueDeepMap, key)
+	data := doGetValue(property.ValueDeepMap, key)
 	err := isc.DataToObject(data, &arrayResult)
 	if err != nil {
 		return arrayResult
@@ -835,12 +886,13 @@ func GetValueArray(key string) []any {
 }
 
 func GetValueArrayInt(key string) []int {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return nil
 	}
 
 	var arrayResult []int
-	data := doGetValue(appProperty.ValueDeepMap, key)
+	data := doGetValue(property.ValueDeepMap, key)
 	err := isc.DataToObject(data, &arrayResult)
 	if err != nil {
 		return arrayResult
@@ -849,10 +901,11 @@ func GetValueArrayInt(key string) []int {
 }
 
 func GetValue(key string) any {
-	if nil == appProperty {
+	property := currentProperty()
+	if nil == property {
 		return nil
 	}
-	return doGetValue(appProperty.ValueDeepMap, key)
+	return doGetValue(property.ValueDeepMap, key)
 }
 
 func doGetValue(parentValue any, key string) any {
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"sync/atomic"
+
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/isc"
+)
+
+// 当前协程覆盖的配置，overrideCount 为0时不读取协程存储
+var overrideStorage = goid.NewLocalStorage()
+var overrideCount int32
+
+// ParseYamlContent 解析yaml内容为配置，不修改全局的配置
+func ParseYamlContent(content string) (*ApplicationProperty, error) {
+	property, err := isc.YamlToProperties(content)
+	if err != nil {
+		return nil, err
+	}
+	valueMap, err := isc.PropertiesToMap(property)
+	if err != nil {
+		return nil, err
+	}
+	yamlMap, err := isc.YamlToMap(content)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplicationProperty{ValueMap: valueMap, ValueDeepMap: yamlMap}, nil
+}
+
+// WithProperties fn执行期间当前协程的 GetValueXxx 读取property，不修改全局的配置，用于测试等需要隔离配置的场景
+// 只对当前协程生效，fn中使用 goid.Go 启动的协程会继承，其他协程仍然读取全局的配置
+func WithProperties(property *ApplicationProperty, fn func()) {
+	atomic.AddInt32(&overrideCount, 1)
+	previous := overrideStorage.Set(property)
+	defer func() {
+		if previous == nil {
+			overrideStorage.Del()
+		} else {
+			overrideStorage.Set(previous)
+		}
+		atomic.AddInt32(&overrideCount, -1)
+	}()
+	fn()
+}
+
+// IsOverridden 当前协程是否在 WithProperties 中
+func IsOverridden() bool {
+	return overriddenProperty() != nil
+}
+
+func overriddenProperty() *ApplicationProperty {
+	if atomic.LoadInt32(&overrideCount) == 0 {
+		return nil
+	}
+	property, _ := overrideStorage.Get().(*ApplicationProperty)
+	return property
+}
+
+func currentProperty() *ApplicationProperty {
+	if property := overriddenProperty(); property != nil {
+		return property
+	}
+	return appProperty
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/isyscore/isc-gobase/server/req"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// DefaultPageSize 分页请求中没有指定每页大小时使用
+var DefaultPageSize = 10
+
+// RowMapper 将当前行转换为记录
+type RowMapper[R any] func(rows *sql.Rows) (R, error)
+
+// Page 分页查询，返回的记录为列名（驼峰）到值的map
+// query为不带分页的查询语句，先执行count查询，再按照数据库类型添加分页语句查询当前页的数据
+func Page[P any](db *sql.DB, dbType DatabaseType, page req.PageRequest[P], query string, args ...any) (rsp.PagedData[map[string]string], error) {
+	return pageQuery(db, dbType, page, query, args, func(rows *sql.Rows) ([]map[string]string, error) {
+		return fetchRows(rows, nil)
+	})
+}
+
+// PageOf 分页查询，通过mapper将每行转换为记录
+func PageOf[P any, R any](db *sql.DB, dbType DatabaseType, page req.PageRequest[P], query string, mapper RowMapper[R], args ...any) (rsp.PagedData[R], error) {
+	return pageQuery(db, dbType, page, query, args, func(rows *sql.Rows) ([]R, error) {
+		return mapRows(rows, mapper)
+	})
+}
+
+func pageQuery[P any, R any](db *sql.DB, dbType DatabaseType, page req.PageRequest[P], query string, args []any, fetch func(rows *sql.Rows) ([]R, error)) (rsp.PagedData[R], error) {
+	if page.Size <= 0 {
+		page.Size = DefaultPageSize
+	}
+	if page.Current <= 0 {
+		page.Current = 1
+	}
+	data := rsp.PagedData[R]{Size: int64(page.Size), Current: int64(page.Current), IsSearchCount: true, Records: []R{}}
+
+	if err := db.QueryRow(CountSql(query), args...).Scan(&data.Total); err != nil {
+		return data, err
+	}
+	data.Pages = (data.Total + data.Size - 1) / data.Size
+	if int64(page.Start()) >= data.Total {
+		return data, nil
+	}
+
+	rows, err := db.Query(LimitSql(dbType, query, page.Start(), page.Size), args...)
+	if err != nil {
+		return data, err
+	}
+	records, err := fetch(rows)
+	if err != nil {
+		return data, err
+	}
+	data.Records = records
+	return data, nil
+}
+
+func mapRows[R any](rows *sql.Rows, mapper RowMapper[R]) ([]R, error) {
+	defer func() { _ = rows.Close() }()
+	var records []R
+	for rows.Next() {
+		record, err := mapper(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if records == nil {
+		records = []R{}
+	}
+	return records, rows.Err()
+}
+
+// CountSql 生成count语句，去掉最外层的 ORDER BY
+func CountSql(query string) string {
+	return "SELECT COUNT(*) FROM (" + trimOrderBy(query) + ") count_t"
+}
+
+// LimitSql 按照数据库类型添加分页语句
+// SQL Server 和 Oracle 使用 OFFSET ... FETCH（SQL Server 2012、Oracle 12c 及以上），SQL Server没有 ORDER BY 时自动添加
+func LimitSql(dbType DatabaseType, query string, offset int, limit int) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	switch dbType {
+	case SqlServer:
+		if orderByIndex(query) < 0 {
+			query += " ORDER BY (SELECT NULL)"
+		}
+		return fmt.Sprintf("%s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", query, offset, limit)
+	case Oracle:
+		return fmt.Sprintf("%s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", query, offset, limit)
+	default:
+		return fmt.Sprintf("%s LIMIT %d OFFSET %d", query, limit, offset)
+	}
+}
+
+// Placeholder 按照数据库类型返回第index（从1开始）个参数的占位符
+func Placeholder(dbType DatabaseType, index int) string {
+	switch dbType {
+	case PostgreSql:
+		return fmt.Sprintf("$%d", index)
+	case SqlServer:
+		return fmt.Sprintf("@p%d", index)
+	case Oracle:
+		return fmt.Sprintf(":%d", index)
+	default:
+		return "?"
+	}
+}
+
+func trimOrderBy(query string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if index := orderByIndex(query); index >= 0 {
+		return strings.TrimSpace(query[:index])
+	}
+	return query
+}
+
+// orderByIndex 最外层（不在括号和字符串中）的最后一个 ORDER BY 的位置
+func orderByIndex(query string) int {
+	upper := strings.ToUpper(query)
+	depth := 0
+	quoted := false
+	index := -1
+	for i := 0; i < len(upper); i++ {
+		switch ch := upper[i]; {
+		case ch == '\'':
+			quoted = !quoted
+		case quoted:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case depth == 0 && ch == 'O' && strings.HasPrefix(upper[i:], "ORDER") && (i == 0 || isSpace(upper[i-1])):
+			rest := strings.TrimLeft(upper[i+5:], " \t\r\n")
+			if len(rest) < len(upper[i+5:]) && strings.HasPrefix(rest, "BY") {
+				index = i
+			}
+		}
+	}
+	return index
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+// KeysetRequest 游标（keyset）分页的请求，适合深分页以及数据持续变化的场景
+type KeysetRequest[P any] struct {
+	// 上一页返回的 NextCursor，为空则从第一页开始
+	Cursor string `json:"cursor"`
+	Size   int    `json:"size"`
+	Param  P      `json:"param"`
+}
+
+// KeysetOption 游标分页的排序列
+type KeysetOption[R any] struct {
+	// 唯一且有序的列，例如：id
+	Column string
+	// 是否倒序
+	Desc bool
+	// 从记录中获取排序列的值，用于生成下一页的游标
+	Key func(record R) any
+}
+
+// KeysetPage 游标分页：按照排序列比较游标，不使用OFFSET，不执行count查询
+// query为不带 ORDER BY 的查询语句，其中的参数占位符按照数据库类型书写
+func KeysetPage[P any, R any](db *sql.DB, dbType DatabaseType, page KeysetRequest[P], query string, option KeysetOption[R], mapper RowMapper[R], args ...any) (rsp.CursorData[R], error) {
+	if page.Size <= 0 {
+		page.Size = DefaultPageSize
+	}
+	data := rsp.CursorData[R]{Size: int64(page.Size), Records: []R{}}
+	if option.Column == "" || option.Key == nil || mapper == nil {
+		return data, errors.New("游标分页需要指定排序列 Column、获取排序列值的 Key 以及 mapper")
+	}
+
+	compare, order := ">", "ASC"
+	if option.Desc {
+		compare, order = "<", "DESC"
+	}
+	column := "keyset_t." + option.Column
+	keysetQuery := "SELECT * FROM (" + trimOrderBy(query) + ") keyset_t"
+	if page.Cursor != "" {
+		cursor, err := DecodeCursor(page.Cursor)
+		if err != nil {
+			return data, err
+		}
+		args = append(args, cursor)
+		keysetQuery += fmt.Sprintf(" WHERE %s %s %s", column, compare, Placeholder(dbType, len(args)))
+	}
+	keysetQuery += fmt.Sprintf(" ORDER BY %s %s", column, order)
+
+	// 多查询一条用于判断是否还有下一页
+	rows, err := db.Query(LimitSql(dbType, keysetQuery, 0, page.Size+1), args...)
+	if err != nil {
+		return data, err
+	}
+	records, err := mapRows(rows, mapper)
+	if err != nil {
+		return data, err
+	}
+	if len(records) > page.Size {
+		records = records[:page.Size]
+		data.HasMore = true
+		if data.NextCursor, err = EncodeCursor(option.Key(records[page.Size-1])); err != nil {
+			return data, err
+		}
+	}
+	data.Records = records
+	return data, nil
+}
+
+// EncodeCursor 将排序列的值编码为游标
+func EncodeCursor(key any) (string, error) {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+// DecodeCursor 解码游标，数字解码为int64或者float64
+func DecodeCursor(cursor string) (any, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("游标格式错误：%w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var key any
+	if err = decoder.Decode(&key); err != nil {
+		return nil, fmt.Errorf("游标格式错误：%w", err)
+	}
+	if number, ok := key.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			return i, nil
+		}
+		return number.Float64()
+	}
+	return key, nil
+}
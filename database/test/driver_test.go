@@ -0,0 +1,133 @@
+package test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// scriptQuery 期望执行的查询以及返回的数据
+type scriptQuery struct {
+	query   string
+	args    []driver.Value
+	columns []string
+	rows    [][]driver.Value
+}
+
+// scriptDriver 按照顺序核对执行的查询并返回预先设置的数据，用于不依赖数据库测试分页
+type scriptDriver struct {
+	lock    sync.Mutex
+	queries []scriptQuery
+}
+
+var script = &scriptDriver{}
+
+func init() {
+	sql.Register("script", script)
+}
+
+// openScript 设置期望的查询，测试结束时检查是否全部执行
+func openScript(t *testing.T, queries ...scriptQuery) *sql.DB {
+	script.lock.Lock()
+	script.queries = queries
+	script.lock.Unlock()
+
+	db, err := sql.Open("script", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+		script.lock.Lock()
+		defer script.lock.Unlock()
+		if len(script.queries) != 0 {
+			t.Errorf("还有没有执行的查询：%s", script.queries[0].query)
+		}
+	})
+	return db
+}
+
+func (d *scriptDriver) Open(string) (driver.Conn, error) {
+	return &scriptConn{driver: d}, nil
+}
+
+func (d *scriptDriver) next(query string, args []driver.Value) (*scriptRows, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.queries) == 0 {
+		return nil, fmt.Errorf("没有期望的查询：%s", query)
+	}
+	expected := d.queries[0]
+	d.queries = d.queries[1:]
+	if expected.query != query {
+		return nil, fmt.Errorf("期望的查询：%s，实际：%s", expected.query, query)
+	}
+	if fmt.Sprint(expected.args) != fmt.Sprint(args) {
+		return nil, fmt.Errorf("期望的参数：%v，实际：%v", expected.args, args)
+	}
+	return &scriptRows{columns: expected.columns, rows: expected.rows}, nil
+}
+
+type scriptConn struct {
+	driver *scriptDriver
+}
+
+func (c *scriptConn) Prepare(query string) (driver.Stmt, error) {
+	return &scriptStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *scriptConn) Close() error {
+	return nil
+}
+
+func (c *scriptConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("不支持事务")
+}
+
+type scriptStmt struct {
+	driver *scriptDriver
+	query  string
+}
+
+func (s *scriptStmt) Close() error {
+	return nil
+}
+
+func (s *scriptStmt) NumInput() int {
+	return -1
+}
+
+func (s *scriptStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("不支持更新")
+}
+
+func (s *scriptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.driver.next(s.query, args)
+}
+
+type scriptRows struct {
+	columns []string
+	rows    [][]driver.Value
+	index   int
+}
+
+func (r *scriptRows) Columns() []string {
+	return r.columns
+}
+
+func (r *scriptRows) Close() error {
+	return nil
+}
+
+func (r *scriptRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.index])
+	r.index++
+	return nil
+}
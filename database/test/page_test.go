@@ -0,0 +1,124 @@
+package test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/isyscore/isc-gobase/database"
+	"github.com/isyscore/isc-gobase/server/req"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCountSql(t *testing.T) {
+	assert.Equal(t, database.CountSql("select * from user where name = ? order by id desc;"),
+		"SELECT COUNT(*) FROM (select * from user where name = ?) count_t")
+	// 子查询以及字符串中的 order by 不处理
+	assert.Equal(t, database.CountSql("select * from (select * from user order by id) u where remark = 'order by'"),
+		"SELECT COUNT(*) FROM (select * from (select * from user order by id) u where remark = 'order by') count_t")
+}
+
+func TestLimitSql(t *testing.T) {
+	query := "SELECT * FROM user ORDER BY id"
+	assert.Equal(t, database.LimitSql(database.MySQL, query, 20, 10), "SELECT * FROM user ORDER BY id LIMIT 10 OFFSET 20")
+	assert.Equal(t, database.LimitSql(database.PostgreSql, query, 20, 10), "SELECT * FROM user ORDER BY id LIMIT 10 OFFSET 20")
+	assert.Equal(t, database.LimitSql(database.Oracle, query, 20, 10), "SELECT * FROM user ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY")
+	assert.Equal(t, database.LimitSql(database.SqlServer, query, 20, 10), "SELECT * FROM user ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY")
+	// SQL Server 的 OFFSET 必须有 ORDER BY
+	assert.Equal(t, database.LimitSql(database.SqlServer, "SELECT * FROM user", 0, 10), "SELECT * FROM user ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY")
+}
+
+func TestPlaceholder(t *testing.T) {
+	assert.Equal(t, database.Placeholder(database.MySQL, 2), "?")
+	assert.Equal(t, database.Placeholder(database.PostgreSql, 2), "$2")
+	assert.Equal(t, database.Placeholder(database.SqlServer, 2), "@p2")
+	assert.Equal(t, database.Placeholder(database.Oracle, 2), ":2")
+}
+
+func TestCursor(t *testing.T) {
+	cursor, _ := database.EncodeCursor(int64(1024))
+	key, err := database.DecodeCursor(cursor)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, key, int64(1024))
+
+	cursor, _ = database.EncodeCursor("2022-01-01")
+	key, _ = database.DecodeCursor(cursor)
+	assert.Equal(t, key, "2022-01-01")
+
+	_, err = database.DecodeCursor("!!")
+	assert.Equal(t, err != nil, true)
+}
+
+type pageUser struct {
+	Id   int64
+	Name string
+}
+
+func mapUser(rows *sql.Rows) (pageUser, error) {
+	var u pageUser
+	err := rows.Scan(&u.Id, &u.Name)
+	return u, err
+}
+
+const userQuery = "select id, name from user where age > ? order by id"
+
+var userColumns = []string{"id", "name"}
+
+func TestPage(t *testing.T) {
+	db := openScript(t,
+		scriptQuery{query: "SELECT COUNT(*) FROM (select id, name from user where age > ?) count_t", args: []driver.Value{int64(18)}, columns: []string{"count"}, rows: [][]driver.Value{{int64(3)}}},
+		scriptQuery{query: userQuery + " LIMIT 2 OFFSET 2", args: []driver.Value{int64(18)}, columns: userColumns, rows: [][]driver.Value{{int64(3), "c"}}},
+	)
+	data, err := database.Page(db, database.MySQL, req.PageRequest[any]{Current: 2, Size: 2}, userQuery, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, data.Total, int64(3))
+	assert.Equal(t, data.Pages, int64(2))
+	assert.Equal(t, data.Current, int64(2))
+	assert.Equal(t, data.Records, []map[string]string{{"id": "3", "name": "c"}})
+}
+
+func TestPageOf(t *testing.T) {
+	db := openScript(t,
+		scriptQuery{query: "SELECT COUNT(*) FROM (select id, name from user where age > ?) count_t", args: []driver.Value{int64(18)}, columns: []string{"count"}, rows: [][]driver.Value{{int64(3)}}},
+		scriptQuery{query: userQuery + " LIMIT 2 OFFSET 0", args: []driver.Value{int64(18)}, columns: userColumns, rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}},
+		// 超过最后一页时不查询数据
+		scriptQuery{query: "SELECT COUNT(*) FROM (select id, name from user where age > ?) count_t", args: []driver.Value{int64(18)}, columns: []string{"count"}, rows: [][]driver.Value{{int64(3)}}},
+	)
+	data, err := database.PageOf(db, database.MySQL, req.PageRequest[any]{Size: 2}, userQuery, mapUser, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, data.Total, int64(3))
+	assert.Equal(t, data.Current, int64(1))
+	assert.Equal(t, data.Records, []pageUser{{1, "a"}, {2, "b"}})
+
+	data, err = database.PageOf(db, database.MySQL, req.PageRequest[any]{Current: 3, Size: 2}, userQuery, mapUser, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, data.Records, []pageUser{})
+}
+
+func TestKeysetPage(t *testing.T) {
+	const keysetQuery = "SELECT * FROM (select id, name from user where age > ?) keyset_t"
+	db := openScript(t,
+		scriptQuery{query: keysetQuery + " ORDER BY keyset_t.id ASC LIMIT 3 OFFSET 0", args: []driver.Value{int64(18)}, columns: userColumns, rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}},
+		scriptQuery{query: keysetQuery + " WHERE keyset_t.id > ? ORDER BY keyset_t.id ASC LIMIT 3 OFFSET 0", args: []driver.Value{int64(18), int64(2)}, columns: userColumns, rows: [][]driver.Value{{int64(3), "c"}}},
+	)
+	option := database.KeysetOption[pageUser]{Column: "id", Key: func(u pageUser) any {
+		return u.Id
+	}}
+
+	data, err := database.KeysetPage(db, database.MySQL, database.KeysetRequest[any]{Size: 2}, userQuery, option, mapUser, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, data.Records, []pageUser{{1, "a"}, {2, "b"}})
+	assert.Equal(t, data.HasMore, true)
+	cursor, _ := database.EncodeCursor(int64(2))
+	assert.Equal(t, data.NextCursor, cursor)
+
+	data, err = database.KeysetPage(db, database.MySQL, database.KeysetRequest[any]{Cursor: data.NextCursor, Size: 2}, userQuery, option, mapUser, 18)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, data.Records, []pageUser{{3, "c"}})
+	assert.Equal(t, data.HasMore, false)
+	assert.Equal(t, data.NextCursor, "")
+
+	// 没有Key时返回错误，不执行查询
+	_, err = database.KeysetPage(db, database.MySQL, database.KeysetRequest[any]{Size: 2}, userQuery, database.KeysetOption[pageUser]{Column: "id"}, mapUser, 18)
+	assert.Equal(t, err != nil, true)
+}
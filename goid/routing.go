@@ -54,9 +54,8 @@ func InheritContext(ic *ImmutableContext) {
 
 // NewLocalStorage create and return an new LocalStorage instance.
 func NewLocalStorage() LocalStorage {
-	t := new(storage)
-	t.Clear()
-	return t
+	// 新的id没有任何值，不需要Clear，Clear会删除当前协程中其他storage的值
+	return newStorage()
 }
 
 // Goid return the current goroutine's unique id.
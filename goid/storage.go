@@ -4,7 +4,6 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-	"unsafe"
 )
 
 var (
@@ -12,6 +11,7 @@ var (
 	storageLock       sync.Mutex         // The Lock to control accessing of storages
 	storageGCTimer    *time.Timer        // The timer of storage's garbage collector
 	storageGCInterval = time.Second * 30 // The pre-defined gc interval
+	storageSeq        uint64             // The sequence of storage's id
 )
 
 func init() {
@@ -30,18 +30,24 @@ type store struct {
 	values map[uintptr]any
 }
 
+// storage 使用递增的id作为key，空结构体的指针可能相同，不能用地址区分
 type storage struct {
+	id uintptr
+}
+
+func newStorage() *storage {
+	return &storage{id: uintptr(atomic.AddUint64(&storageSeq, 1))}
 }
 
 func (t *storage) Get() (v any) {
 	s := loadCurrentStore()
-	id := uintptr(unsafe.Pointer(t))
+	id := t.id
 	return s.values[id]
 }
 
 func (t *storage) Set(v any) (oldValue any) {
 	s := loadCurrentStore()
-	id := uintptr(unsafe.Pointer(t))
+	id := t.id
 	oldValue = s.values[id]
 	s.values[id] = v
 	atomic.StoreUint32(&s.count, uint32(len(s.values)))
@@ -59,7 +65,7 @@ func (t *storage) Set(v any) (oldValue any) {
 
 func (t *storage) Del() (v any) {
 	s := loadCurrentStore()
-	id := uintptr(unsafe.Pointer(t))
+	id := t.id
 	v = s.values[id]
 	delete(s.values, id)
 	atomic.StoreUint32(&s.count, uint32(len(s.values)))
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/isyscore/isc-gobase/goid"
+	"github.com/magiconair/properties/assert"
 )
 
 func TestTraceId(t *testing.T) {
@@ -14,3 +15,21 @@ func TestTraceId(t *testing.T) {
 	tid3 := goid.GenerateTraceID()
 	t.Logf("trace id: %s", tid3)
 }
+
+func TestTraceIdWithOtherStorage(t *testing.T) {
+	goid.SetTraceID("abc")
+	defer goid.DelTraceID()
+
+	// 同一个协程中的其他storage不影响traceId
+	first := goid.NewLocalStorage()
+	second := goid.NewLocalStorage()
+	first.Set(42)
+	second.Set("value")
+	assert.Equal(t, goid.GetTraceID(), "abc")
+	assert.Equal(t, first.Get(), 42)
+	assert.Equal(t, second.Get(), "value")
+
+	second.Del()
+	assert.Equal(t, first.Get(), 42)
+	assert.Equal(t, goid.GetTraceID(), "abc")
+}
@@ -13,6 +13,27 @@ var digits = []uint8{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b',
 
 const max = 8000
 
+// 包初始化时 storages 还未初始化，因此不使用 NewLocalStorage
+var traceIdStorage LocalStorage = newStorage()
+
+// SetTraceID 设置当前协程的traceId，通过 goid.Go 启动的协程会继承
+func SetTraceID(traceId string) {
+	traceIdStorage.Set(traceId)
+}
+
+// GetTraceID 获取当前协程的traceId，没有时返回空
+func GetTraceID() string {
+	if traceId, ok := traceIdStorage.Get().(string); ok {
+		return traceId
+	}
+	return ""
+}
+
+// DelTraceID 删除当前协程的traceId
+func DelTraceID() {
+	traceIdStorage.Del()
+}
+
 func GenerateTraceID() string {
 	buffer := make([]byte, 16)
 
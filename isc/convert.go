@@ -622,6 +622,17 @@ func doInvokeValue(fieldMapValue reflect.Value, field reflect.StructField, field
 		return
 	}
 
+	// 基本类型的指针字段，用于区分没有配置和配置为零值
+	if field.Type.Kind() == reflect.Ptr && IsBaseType(field.Type) {
+		targetValue := valueToTarget(fValue, field.Type.Elem())
+		if targetValue.IsValid() && fieldValue.CanSet() {
+			pointer := reflect.New(field.Type.Elem())
+			pointer.Elem().Set(targetValue.Convert(field.Type.Elem()))
+			fieldValue.Set(pointer)
+		}
+		return
+	}
+
 	if fieldValue.Kind() == reflect.Ptr {
 		fValue = fValue.Elem()
 	}
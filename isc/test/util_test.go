@@ -307,6 +307,25 @@ func TestMapToObject13(t *testing.T) {
 	test.Equal(t, "{\"age\":12}", isc.ToJsonString(inner2))
 }
 
+type ValuePointerEntity struct {
+	Name   *string
+	Enable *bool
+	Size   *int
+}
+
+func TestMapToObject14(t *testing.T) {
+	inner1 := map[string]any{}
+	inner1["name"] = "zhou"
+	inner1["enable"] = false
+
+	entity := ValuePointerEntity{}
+	_ = isc.MapToObject(inner1, &entity)
+	test.Equal(t, "zhou", *entity.Name)
+	test.Equal(t, false, *entity.Enable)
+	// 没有配置的字段保持为nil
+	test.Equal(t, true, entity.Size == nil)
+}
+
 // dataToObject
 func TestDataToObject1(t *testing.T) {
 	inner1 := map[string]any{}
@@ -1,11 +1,15 @@
 package redis
 
 import (
+	"sync"
+	"time"
+
 	goredis "github.com/go-redis/redis/v8"
 	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/listener"
 	"github.com/isyscore/isc-gobase/logger"
 	baseTime "github.com/isyscore/isc-gobase/time"
-	"time"
+	"github.com/isyscore/isc-gobase/validate"
 )
 
 type ConfigError struct {
@@ -16,6 +20,12 @@ func (error *ConfigError) Error() string {
 	return error.ErrMsg
 }
 
+var (
+	clientLock sync.Mutex
+	// 已经创建的客户端，key为实例名，默认实例为空字符串
+	clients = map[string]goredis.UniversalClient{}
+)
+
 func init() {
 	config.LoadConfig()
 
@@ -25,114 +35,177 @@ func init() {
 			logger.Warn("读取redis配置异常")
 			return
 		}
+		if result, errMsg := validate.Check(config.RedisCfg); !result {
+			logger.Warn("redis配置不合法：%v", errMsg)
+		}
 	}
+
+	listener.AddListener(listener.EventOfServerStop, func(event listener.BaseEvent) {
+		if err := Close(); err != nil {
+			logger.Warn("关闭redis客户端异常：%v", err)
+		}
+	})
 }
 
+// GetClient 获取默认实例（base.redis）的客户端，多次调用返回同一个客户端
 func GetClient() (goredis.UniversalClient, error) {
-	if config.RedisCfg.Sentinel.Master != "" {
-		return goredis.NewFailoverClient(getSentinelConfig()), nil
-	} else if len(config.RedisCfg.Cluster.Addrs) != 0 {
-		return goredis.NewClusterClient(getClusterConfig()), nil
+	return GetClientByName("")
+}
+
+// GetClientByName 获取命名实例（base.redis.instances.{name}）的客户端，每个实例只创建一个客户端，并添加命令日志以及指标的hook
+func GetClientByName(name string) (goredis.UniversalClient, error) {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	if client, exist := clients[name]; exist {
+		return client, nil
+	}
+
+	redisCfg, err := getRedisConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	client := newClient(redisCfg)
+	client.AddHook(newCommandHook(name, redisCfg.SlowThreshold))
+	clients[name] = client
+	return client, nil
+}
+
+// Close 关闭所有已经创建的客户端，返回第一个关闭失败的错误；服务关闭时自动调用，关闭之后再获取会重新创建
+func Close() error {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	var closeErr error
+	for name, client := range clients {
+		if err := client.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+		delete(clients, name)
+	}
+	return closeErr
+}
+
+func getRedisConfig(name string) (*config.RedisConfig, error) {
+	redisCfg := config.RedisCfg
+	if name != "" {
+		instanceCfg, exist := config.RedisCfg.Instances[name]
+		if !exist {
+			return nil, &ConfigError{ErrMsg: "redis实例没有配置：" + name}
+		}
+		redisCfg = instanceCfg
+	}
+	// 命名实例在默认实例的配置中已经核查，这里只核查本实例的配置
+	redisCfg.Instances = nil
+	if result, errMsg := validate.Check(redisCfg); !result {
+		return nil, &ConfigError{ErrMsg: "redis配置不合法：" + errMsg}
+	}
+	return &redisCfg, nil
+}
+
+func newClient(redisCfg *config.RedisConfig) goredis.UniversalClient {
+	if redisCfg.Sentinel.Master != "" {
+		return goredis.NewFailoverClient(getSentinelConfig(redisCfg))
+	} else if len(redisCfg.Cluster.Addrs) != 0 {
+		return goredis.NewClusterClient(getClusterConfig(redisCfg))
 	} else {
-		return goredis.NewClient(getStandaloneConfig()), nil
+		return goredis.NewClient(getStandaloneConfig(redisCfg))
 	}
 }
 
-func getStandaloneConfig() *goredis.Options {
+func getStandaloneConfig(redisCfg *config.RedisConfig) *goredis.Options {
 	addr := "127.0.0.1:6379"
-	if config.RedisCfg.Standalone.Addr != "" {
-		addr = config.RedisCfg.Standalone.Addr
+	if redisCfg.Standalone.Addr != "" {
+		addr = redisCfg.Standalone.Addr
 	}
 
 	redisConfig := &goredis.Options{
 		Addr: addr,
 
-		DB:       config.RedisCfg.Standalone.Database,
-		Network:  config.RedisCfg.Standalone.Network,
-		Username: config.RedisCfg.Username,
-		Password: config.RedisCfg.Password,
-
-		MaxRetries:      config.RedisCfg.MaxRetries,
-		MinRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MinRetryBackoff, time.Millisecond),
-		MaxRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MaxRetryBackoff, time.Millisecond),
-
-		DialTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.DialTimeout, time.Millisecond),
-		ReadTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.ReadTimeout, time.Millisecond),
-		WriteTimeout: baseTime.NumToTimeDuration(config.RedisCfg.WriteTimeout, time.Millisecond),
-
-		PoolFIFO:           config.RedisCfg.PoolFIFO,
-		PoolSize:           config.RedisCfg.PoolSize,
-		MinIdleConns:       config.RedisCfg.MinIdleConns,
-		MaxConnAge:         baseTime.NumToTimeDuration(config.RedisCfg.MaxConnAge, time.Millisecond),
-		PoolTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.PoolTimeout, time.Millisecond),
-		IdleTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.IdleTimeout, time.Millisecond),
-		IdleCheckFrequency: baseTime.NumToTimeDuration(config.RedisCfg.IdleCheckFrequency, time.Millisecond),
+		DB:       redisCfg.Standalone.Database,
+		Network:  redisCfg.Standalone.Network,
+		Username: redisCfg.Username,
+		Password: redisCfg.Password,
+
+		MaxRetries:      redisCfg.MaxRetries,
+		MinRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MinRetryBackoff, time.Millisecond),
+		MaxRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MaxRetryBackoff, time.Millisecond),
+
+		DialTimeout:  baseTime.NumToTimeDuration(redisCfg.DialTimeout, time.Millisecond),
+		ReadTimeout:  baseTime.NumToTimeDuration(redisCfg.ReadTimeout, time.Millisecond),
+		WriteTimeout: baseTime.NumToTimeDuration(redisCfg.WriteTimeout, time.Millisecond),
+
+		PoolFIFO:           redisCfg.PoolFIFO,
+		PoolSize:           redisCfg.PoolSize,
+		MinIdleConns:       redisCfg.MinIdleConns,
+		MaxConnAge:         baseTime.NumToTimeDuration(redisCfg.MaxConnAge, time.Millisecond),
+		PoolTimeout:        baseTime.NumToTimeDuration(redisCfg.PoolTimeout, time.Millisecond),
+		IdleTimeout:        baseTime.NumToTimeDuration(redisCfg.IdleTimeout, time.Millisecond),
+		IdleCheckFrequency: baseTime.NumToTimeDuration(redisCfg.IdleCheckFrequency, time.Millisecond),
 	}
 	return redisConfig
 }
 
-func getSentinelConfig() *goredis.FailoverOptions {
+func getSentinelConfig(redisCfg *config.RedisConfig) *goredis.FailoverOptions {
 	redisConfig := &goredis.FailoverOptions{
-		SentinelAddrs: config.RedisCfg.Sentinel.Addrs,
-		MasterName:    config.RedisCfg.Sentinel.Master,
-
-		DB:               config.RedisCfg.Sentinel.Database,
-		Username:         config.RedisCfg.Username,
-		Password:         config.RedisCfg.Password,
-		SentinelUsername: config.RedisCfg.Sentinel.SentinelUser,
-		SentinelPassword: config.RedisCfg.Sentinel.SentinelPassword,
-
-		MaxRetries:      config.RedisCfg.MaxRetries,
-		MinRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MinRetryBackoff, time.Millisecond),
-		MaxRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MaxRetryBackoff, time.Millisecond),
-
-		DialTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.DialTimeout, time.Millisecond),
-		ReadTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.ReadTimeout, time.Millisecond),
-		WriteTimeout: baseTime.NumToTimeDuration(config.RedisCfg.WriteTimeout, time.Millisecond),
-
-		PoolFIFO:           config.RedisCfg.PoolFIFO,
-		PoolSize:           config.RedisCfg.PoolSize,
-		MinIdleConns:       config.RedisCfg.MinIdleConns,
-		MaxConnAge:         baseTime.NumToTimeDuration(config.RedisCfg.MaxConnAge, time.Millisecond),
-		PoolTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.PoolTimeout, time.Millisecond),
-		IdleTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.IdleTimeout, time.Millisecond),
-		IdleCheckFrequency: baseTime.NumToTimeDuration(config.RedisCfg.IdleCheckFrequency, time.Millisecond),
+		SentinelAddrs: redisCfg.Sentinel.Addrs,
+		MasterName:    redisCfg.Sentinel.Master,
+
+		DB:               redisCfg.Sentinel.Database,
+		Username:         redisCfg.Username,
+		Password:         redisCfg.Password,
+		SentinelUsername: redisCfg.Sentinel.SentinelUser,
+		SentinelPassword: redisCfg.Sentinel.SentinelPassword,
+
+		MaxRetries:      redisCfg.MaxRetries,
+		MinRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MinRetryBackoff, time.Millisecond),
+		MaxRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MaxRetryBackoff, time.Millisecond),
+
+		DialTimeout:  baseTime.NumToTimeDuration(redisCfg.DialTimeout, time.Millisecond),
+		ReadTimeout:  baseTime.NumToTimeDuration(redisCfg.ReadTimeout, time.Millisecond),
+		WriteTimeout: baseTime.NumToTimeDuration(redisCfg.WriteTimeout, time.Millisecond),
+
+		PoolFIFO:           redisCfg.PoolFIFO,
+		PoolSize:           redisCfg.PoolSize,
+		MinIdleConns:       redisCfg.MinIdleConns,
+		MaxConnAge:         baseTime.NumToTimeDuration(redisCfg.MaxConnAge, time.Millisecond),
+		PoolTimeout:        baseTime.NumToTimeDuration(redisCfg.PoolTimeout, time.Millisecond),
+		IdleTimeout:        baseTime.NumToTimeDuration(redisCfg.IdleTimeout, time.Millisecond),
+		IdleCheckFrequency: baseTime.NumToTimeDuration(redisCfg.IdleCheckFrequency, time.Millisecond),
 	}
 
 	return redisConfig
 }
 
-func getClusterConfig() *goredis.ClusterOptions {
-	if len(config.RedisCfg.Cluster.Addrs) == 0 {
-		config.RedisCfg.Cluster.Addrs = []string{"127.0.0.1:6379"}
+func getClusterConfig(redisCfg *config.RedisConfig) *goredis.ClusterOptions {
+	if len(redisCfg.Cluster.Addrs) == 0 {
+		redisCfg.Cluster.Addrs = []string{"127.0.0.1:6379"}
 	}
 
 	redisConfig := &goredis.ClusterOptions{
-		Addrs: config.RedisCfg.Cluster.Addrs,
-
-		Username: config.RedisCfg.Username,
-		Password: config.RedisCfg.Password,
-
-		MaxRedirects:   config.RedisCfg.Cluster.MaxRedirects,
-		ReadOnly:       config.RedisCfg.Cluster.ReadOnly,
-		RouteByLatency: config.RedisCfg.Cluster.RouteByLatency,
-		RouteRandomly:  config.RedisCfg.Cluster.RouteRandomly,
-
-		MaxRetries:      config.RedisCfg.MaxRetries,
-		MinRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MinRetryBackoff, time.Millisecond),
-		MaxRetryBackoff: baseTime.NumToTimeDuration(config.RedisCfg.MaxRetryBackoff, time.Millisecond),
-
-		DialTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.DialTimeout, time.Millisecond),
-		ReadTimeout:  baseTime.NumToTimeDuration(config.RedisCfg.ReadTimeout, time.Millisecond),
-		WriteTimeout: baseTime.NumToTimeDuration(config.RedisCfg.WriteTimeout, time.Millisecond),
-		PoolFIFO:     config.RedisCfg.PoolFIFO,
-		PoolSize:     config.RedisCfg.PoolSize,
-		MinIdleConns: config.RedisCfg.MinIdleConns,
-
-		MaxConnAge:         baseTime.NumToTimeDuration(config.RedisCfg.MaxConnAge, time.Millisecond),
-		PoolTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.PoolTimeout, time.Millisecond),
-		IdleTimeout:        baseTime.NumToTimeDuration(config.RedisCfg.IdleTimeout, time.Millisecond),
-		IdleCheckFrequency: baseTime.NumToTimeDuration(config.RedisCfg.IdleCheckFrequency, time.Millisecond),
+		Addrs: redisCfg.Cluster.Addrs,
+
+		Username: redisCfg.Username,
+		Password: redisCfg.Password,
+
+		MaxRedirects:   redisCfg.Cluster.MaxRedirects,
+		ReadOnly:       redisCfg.Cluster.ReadOnly,
+		RouteByLatency: redisCfg.Cluster.RouteByLatency,
+		RouteRandomly:  redisCfg.Cluster.RouteRandomly,
+
+		MaxRetries:      redisCfg.MaxRetries,
+		MinRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MinRetryBackoff, time.Millisecond),
+		MaxRetryBackoff: baseTime.NumToTimeDuration(redisCfg.MaxRetryBackoff, time.Millisecond),
+
+		DialTimeout:  baseTime.NumToTimeDuration(redisCfg.DialTimeout, time.Millisecond),
+		ReadTimeout:  baseTime.NumToTimeDuration(redisCfg.ReadTimeout, time.Millisecond),
+		WriteTimeout: baseTime.NumToTimeDuration(redisCfg.WriteTimeout, time.Millisecond),
+		PoolFIFO:     redisCfg.PoolFIFO,
+		PoolSize:     redisCfg.PoolSize,
+		MinIdleConns: redisCfg.MinIdleConns,
+
+		MaxConnAge:         baseTime.NumToTimeDuration(redisCfg.MaxConnAge, time.Millisecond),
+		PoolTimeout:        baseTime.NumToTimeDuration(redisCfg.PoolTimeout, time.Millisecond),
+		IdleTimeout:        baseTime.NumToTimeDuration(redisCfg.IdleTimeout, time.Millisecond),
+		IdleCheckFrequency: baseTime.NumToTimeDuration(redisCfg.IdleCheckFrequency, time.Millisecond),
 	}
 	return redisConfig
 }
@@ -0,0 +1,266 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/rs/zerolog"
+)
+
+// 没有配置 slow-threshold 时慢命令的阈值
+const defaultSlowThreshold = 100 * time.Millisecond
+
+type hookContextKey int
+
+const (
+	startTimeKey hookContextKey = iota
+	traceIdKey
+)
+
+// CommandMetrics 一个命令的执行指标，redis.Nil不算错误
+type CommandMetrics struct {
+	Count      uint64        `json:"count"`
+	Errors     uint64        `json:"errors"`
+	TotalNanos time.Duration `json:"totalNanos"`
+	MaxNanos   time.Duration `json:"maxNanos"`
+}
+
+type commandCounter struct {
+	count, errors   uint64
+	total, maxNanos int64
+}
+
+// metricsOfInstance 实例名为key，默认实例为default，值为命令名到 *commandCounter 的映射
+var metricsOfInstance sync.Map
+
+// commandHook 打印命令的debug日志以及慢命令日志，统计每个命令的耗时以及错误
+type commandHook struct {
+	name          string
+	slowThreshold time.Duration
+	counters      *sync.Map
+}
+
+func newCommandHook(name string, slowThreshold int) *commandHook {
+	if name == "" {
+		name = "default"
+	}
+	threshold := time.Duration(slowThreshold) * time.Millisecond
+	if slowThreshold == 0 {
+		threshold = defaultSlowThreshold
+	}
+	counters, _ := metricsOfInstance.LoadOrStore(name, &sync.Map{})
+	return &commandHook{name: name, slowThreshold: threshold, counters: counters.(*sync.Map)}
+}
+
+// WithTraceID 将traceId设置到ctx中，命令日志中优先使用ctx中的traceId
+func WithTraceID(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceIdKey, traceId)
+}
+
+// TraceID ctx中的traceId，没有时使用协程存储中的traceId
+func TraceID(ctx context.Context) string {
+	if traceId, ok := ctx.Value(traceIdKey).(string); ok && traceId != "" {
+		return traceId
+	}
+	return goid.GetTraceID()
+}
+
+// Metrics 每个实例中每个命令的执行指标
+// pipeline中的命令按照各自的命令名统计次数和错误，不统计耗时；整个pipeline的次数、错误以及耗时统计在pipeline下
+func Metrics() map[string]map[string]CommandMetrics {
+	result := map[string]map[string]CommandMetrics{}
+	metricsOfInstance.Range(func(name, counters any) bool {
+		commands := map[string]CommandMetrics{}
+		counters.(*sync.Map).Range(func(command, counter any) bool {
+			c := counter.(*commandCounter)
+			commands[command.(string)] = CommandMetrics{
+				Count:      atomic.LoadUint64(&c.count),
+				Errors:     atomic.LoadUint64(&c.errors),
+				TotalNanos: time.Duration(atomic.LoadInt64(&c.total)),
+				MaxNanos:   time.Duration(atomic.LoadInt64(&c.maxNanos)),
+			}
+			return true
+		})
+		result[name.(string)] = commands
+		return true
+	})
+	return result
+}
+
+func (h *commandHook) BeforeProcess(ctx context.Context, _ goredis.Cmder) (context.Context, error) {
+	return h.before(ctx), nil
+}
+
+func (h *commandHook) AfterProcess(ctx context.Context, cmd goredis.Cmder) error {
+	cost := time.Since(startTime(ctx))
+	h.record(cmd.Name(), cost, isError(cmd.Err()))
+	if h.shouldLog(cost) {
+		h.log(ctx, cost, maskCommand(cmd), cmd.Err())
+	}
+	return nil
+}
+
+func (h *commandHook) BeforeProcessPipeline(ctx context.Context, _ []goredis.Cmder) (context.Context, error) {
+	return h.before(ctx), nil
+}
+
+func (h *commandHook) AfterProcessPipeline(ctx context.Context, cmds []goredis.Cmder) error {
+	cost := time.Since(startTime(ctx))
+	var err error
+	for _, cmd := range cmds {
+		if isError(cmd.Err()) {
+			err = cmd.Err()
+			break
+		}
+	}
+	for _, cmd := range cmds {
+		h.count(cmd.Name(), isError(cmd.Err()))
+	}
+	h.record("pipeline", cost, err != nil)
+	if h.shouldLog(cost) {
+		commands := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			commands[i] = maskCommand(cmd)
+		}
+		h.log(ctx, cost, "pipeline["+strings.Join(commands, "; ")+"]", err)
+	}
+	return nil
+}
+
+// before 记录开始的时间，并将协程存储中的traceId传递到ctx中，集群的pipeline在其他协程中执行
+func (h *commandHook) before(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(traceIdKey).(string); !ok {
+		if traceId := goid.GetTraceID(); traceId != "" {
+			ctx = WithTraceID(ctx, traceId)
+		}
+	}
+	return context.WithValue(ctx, startTimeKey, time.Now())
+}
+
+// shouldLog 开启debug日志或者是慢命令时打印，避免没有打印时隐藏参数的开销
+func (h *commandHook) shouldLog(cost time.Duration) bool {
+	return zerolog.GlobalLevel() <= zerolog.DebugLevel || h.isSlow(cost)
+}
+
+func (h *commandHook) isSlow(cost time.Duration) bool {
+	return h.slowThreshold > 0 && cost >= h.slowThreshold
+}
+
+func (h *commandHook) log(ctx context.Context, cost time.Duration, command string, err error) {
+	if !isError(err) {
+		err = nil
+	}
+	if h.isSlow(cost) {
+		logger.Warn("redis慢命令：%s，实例：%s，耗时：%v，错误：%v，traceId：%s", command, h.name, cost, err, TraceID(ctx))
+	} else {
+		logger.Debug("redis命令：%s，实例：%s，耗时：%v，错误：%v，traceId：%s", command, h.name, cost, err, TraceID(ctx))
+	}
+}
+
+func (h *commandHook) record(command string, cost time.Duration, failed bool) {
+	c := h.count(command, failed)
+	atomic.AddInt64(&c.total, int64(cost))
+	for {
+		old := atomic.LoadInt64(&c.maxNanos)
+		if int64(cost) <= old || atomic.CompareAndSwapInt64(&c.maxNanos, old, int64(cost)) {
+			break
+		}
+	}
+}
+
+// count 只统计次数和错误，pipeline中单个命令的耗时无法区分
+func (h *commandHook) count(command string, failed bool) *commandCounter {
+	counter, exist := h.counters.Load(command)
+	if !exist {
+		counter, _ = h.counters.LoadOrStore(command, &commandCounter{})
+	}
+	c := counter.(*commandCounter)
+	atomic.AddUint64(&c.count, 1)
+	if failed {
+		atomic.AddUint64(&c.errors, 1)
+	}
+	return c
+}
+
+func startTime(ctx context.Context) time.Time {
+	if start, ok := ctx.Value(startTimeKey).(time.Time); ok {
+		return start
+	}
+	return time.Now()
+}
+
+func isError(err error) bool {
+	return err != nil && !errors.Is(err, goredis.Nil)
+}
+
+// 参数全部隐藏的命令
+var secretCommands = map[string]bool{"auth": true, "hello": true, "migrate": true, "acl": true, "config": true}
+
+// 参数全部是key的命令
+var keysCommands = map[string]bool{"del": true, "unlink": true, "exists": true, "mget": true, "touch": true, "watch": true, "type": true, "ttl": true, "pttl": true}
+
+// maskCommand 命令名以及key，其他的参数（值、脚本的参数等）使用***代替
+func maskCommand(cmd goredis.Cmder) string {
+	args := cmd.Args()
+	name := strings.ToLower(cmd.Name())
+	parts := []string{name}
+	switch {
+	case len(args) <= 1:
+	case secretCommands[name]:
+		parts = append(parts, "***")
+	case keysCommands[name]:
+		for _, arg := range args[1:] {
+			parts = append(parts, toString(arg))
+		}
+	case name == "eval" || name == "evalsha":
+		parts = append(parts, maskScriptArgs(name, args)...)
+	default:
+		parts = append(parts, toString(args[1]))
+		if len(args) > 2 {
+			parts = append(parts, "***")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// maskScriptArgs eval/evalsha 显示sha以及key，脚本内容以及ARGV隐藏
+func maskScriptArgs(name string, args []any) []string {
+	parts := []string{"***"}
+	if name == "evalsha" {
+		parts[0] = toString(args[1])
+	}
+	if len(args) < 3 {
+		return parts
+	}
+	numKeys, ok := args[2].(int)
+	if !ok {
+		return append(parts, "***")
+	}
+	parts = append(parts, toString(args[2]))
+	for i := 3; i < len(args) && i < 3+numKeys; i++ {
+		parts = append(parts, toString(args[i]))
+	}
+	if len(args) > 3+numKeys {
+		parts = append(parts, "***")
+	}
+	return parts
+}
+
+func toString(arg any) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
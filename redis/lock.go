@@ -0,0 +1,430 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+var (
+	// ErrLockNotAcquired 锁被其他持有者持有，等待超时仍然没有获取到
+	ErrLockNotAcquired = errors.New("redis: 获取锁失败")
+	// ErrLockNotHeld 锁已经释放、过期或者被其他持有者获取
+	ErrLockNotHeld = errors.New("redis: 锁没有被持有")
+)
+
+const (
+	// 没有指定ttl时锁的过期时间
+	defaultLockTTL = 30 * time.Second
+	// 等待锁时默认的重试间隔
+	defaultLockRetryInterval = 100 * time.Millisecond
+)
+
+// 锁保存为hash：owner为持有者，count为重入的次数，token为首次获取时分配的fencing token
+// KEYS[1]为锁，KEYS[2]为fencing token的计数器，两个key使用相同的hash tag，集群中在同一个slot
+var lockScript = goredis.NewScript(`
+if redis.call('exists', KEYS[1]) == 0 then
+	local token = redis.call('incr', KEYS[2])
+	redis.call('hset', KEYS[1], 'owner', ARGV[1], 'count', 1, 'token', token)
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return token
+end
+if redis.call('hget', KEYS[1], 'owner') == ARGV[1] then
+	redis.call('hincrby', KEYS[1], 'count', 1)
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return tonumber(redis.call('hget', KEYS[1], 'token'))
+end
+return 0
+`)
+
+// 持有者相同时减少重入次数，减到0时删除；不是持有者时返回-1
+var unlockScript = goredis.NewScript(`
+if redis.call('hget', KEYS[1], 'owner') ~= ARGV[1] then
+	return -1
+end
+local count = redis.call('hincrby', KEYS[1], 'count', -1)
+if count > 0 then
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return count
+end
+redis.call('del', KEYS[1])
+return 0
+`)
+
+var renewScript = goredis.NewScript(`
+if redis.call('hget', KEYS[1], 'owner') == ARGV[1] then
+	return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// LockOptions 分布式锁的配置
+type LockOptions struct {
+	// key的前缀，默认为 lock:
+	Prefix string
+	// 等待锁时重试的间隔，默认100毫秒，实际的间隔会加上随机的抖动
+	RetryInterval time.Duration
+	// 关闭自动续期，锁在ttl之后过期
+	DisableWatchdog bool
+}
+
+// Locker 分布式锁，同一个goroutine可以重入；持有期间每隔ttl/3自动续期，释放时只删除自己持有的锁
+type Locker struct {
+	clients []goredis.UniversalClient
+	quorum  int
+	options LockOptions
+	// 本进程的标识，和goroutine id组成锁的持有者
+	id string
+
+	mu     sync.Mutex
+	leases map[string]*lease
+}
+
+// lease 同一个持有者重入时共享一个续期，全部释放之后停止
+type lease struct {
+	refs int
+	stop chan struct{}
+	lost chan struct{}
+}
+
+// LockHandle 获取到的锁，每次获取都需要调用一次 Unlock
+type LockHandle struct {
+	locker   *Locker
+	key      string
+	redisKey string
+	owner    string
+	ttl      time.Duration
+	token    int64
+	lease    *lease
+	released int32
+}
+
+// NewLocker 使用一个redis实例的分布式锁
+func NewLocker(client goredis.UniversalClient, options LockOptions) *Locker {
+	return NewRedLocker([]goredis.UniversalClient{client}, options)
+}
+
+// NewRedLocker RedLock模式的分布式锁，clients为多个独立的redis实例，在超过半数的实例上获取成功才算获取到锁
+func NewRedLocker(clients []goredis.UniversalClient, options LockOptions) *Locker {
+	if options.Prefix == "" {
+		options.Prefix = "lock:"
+	}
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultLockRetryInterval
+	}
+	return &Locker{
+		clients: clients,
+		quorum:  len(clients)/2 + 1,
+		options: options,
+		id:      goid.GenerateUUID(),
+		leases:  map[string]*lease{},
+	}
+}
+
+var (
+	defaultLockerLock sync.Mutex
+	defaultLocker     *Locker
+)
+
+// getDefaultLocker 默认实例的锁，客户端关闭重新创建之后锁也重新创建
+func getDefaultLocker() (*Locker, error) {
+	client, err := GetClient()
+	if err != nil {
+		return nil, err
+	}
+	defaultLockerLock.Lock()
+	defer defaultLockerLock.Unlock()
+	if defaultLocker == nil || defaultLocker.clients[0] != client {
+		defaultLocker = NewLocker(client, LockOptions{})
+	}
+	return defaultLocker, nil
+}
+
+// Lock 使用默认实例获取锁，一直等待直到获取成功或者ctx结束
+func Lock(ctx context.Context, key string, ttl time.Duration) (*LockHandle, error) {
+	locker, err := getDefaultLocker()
+	if err != nil {
+		return nil, err
+	}
+	return locker.Lock(ctx, key, ttl)
+}
+
+// TryLock 使用默认实例获取锁，最多等待timeout，timeout为0时只尝试一次
+func TryLock(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) (*LockHandle, error) {
+	locker, err := getDefaultLocker()
+	if err != nil {
+		return nil, err
+	}
+	return locker.TryLock(ctx, key, ttl, timeout)
+}
+
+// Lock 获取锁，一直等待直到获取成功或者ctx结束；ttl为锁的过期时间，小于等于0时为30秒
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (*LockHandle, error) {
+	return l.lock(ctx, key, ttl, time.Time{})
+}
+
+// TryLock 获取锁，最多等待timeout，timeout为0时只尝试一次；没有获取到时返回 ErrLockNotAcquired
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) (*LockHandle, error) {
+	return l.lock(ctx, key, ttl, time.Now().Add(timeout))
+}
+
+func (l *Locker) lock(ctx context.Context, key string, ttl time.Duration, deadline time.Time) (*LockHandle, error) {
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	redisKey := l.options.Prefix + "{" + key + "}"
+	owner := l.id + ":" + strconv.FormatInt(goid.Goid(), 10)
+	for {
+		token, acquired, err := l.acquire(ctx, redisKey, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return &LockHandle{
+				locker:   l,
+				key:      key,
+				redisKey: redisKey,
+				owner:    owner,
+				ttl:      ttl,
+				token:    token,
+				lease:    l.acquireLease(redisKey, owner, ttl),
+			}, nil
+		}
+
+		delay := l.options.RetryInterval/2 + time.Duration(rand.Int63n(int64(l.options.RetryInterval)))
+		if !deadline.IsZero() {
+			remain := time.Until(deadline)
+			if remain <= 0 {
+				return nil, ErrLockNotAcquired
+			}
+			if delay > remain {
+				delay = remain
+			}
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire 在所有实例上获取锁，RedLock模式下获取成功的实例数不够或者耗时超过ttl时释放已经获取的实例
+func (l *Locker) acquire(ctx context.Context, redisKey, owner string, ttl time.Duration) (int64, bool, error) {
+	keys := []string{redisKey, redisKey + ":fencing"}
+	if len(l.clients) == 1 {
+		token, err := lockScript.Run(ctx, l.clients[0], keys, owner, ttl.Milliseconds()).Int64()
+		if err != nil {
+			return 0, false, err
+		}
+		return token, token > 0, nil
+	}
+
+	start := time.Now()
+	tokens := make([]int64, len(l.clients))
+	errs := make([]error, len(l.clients))
+	l.each(func(i int, client goredis.UniversalClient) {
+		tokens[i], errs[i] = lockScript.Run(ctx, client, keys, owner, ttl.Milliseconds()).Int64()
+	})
+
+	acquired := 0
+	for i := range l.clients {
+		if errs[i] == nil && tokens[i] > 0 {
+			acquired++
+		}
+	}
+	// 扣除获取的耗时以及实例之间的时钟漂移
+	drift := ttl/100 + 2*time.Millisecond
+	// 各个实例的计数器是独立的，获取成功的实例每次可能不同，无法得到单调递增的fencing token
+	if acquired >= l.quorum && ttl-time.Since(start)-drift > 0 {
+		return 0, true, nil
+	}
+
+	// 明确没有获取到的实例不释放，避免减少自己重入的次数
+	l.each(func(i int, client goredis.UniversalClient) {
+		if errs[i] != nil || tokens[i] > 0 {
+			_ = unlockScript.Run(context.Background(), client, []string{redisKey}, owner, ttl.Milliseconds()).Err()
+		}
+	})
+	if acquired < l.quorum && acquired+countErrors(errs) >= l.quorum {
+		return 0, false, firstError(errs)
+	}
+	return 0, false, nil
+}
+
+// release 释放锁，超过半数的实例上仍然持有时才算释放成功
+func (l *Locker) release(ctx context.Context, redisKey, owner string, ttl time.Duration) (bool, error) {
+	return l.runOwned(ctx, unlockScript, redisKey, owner, ttl, func(result int64) bool {
+		return result >= 0
+	})
+}
+
+// renew 续期，超过半数的实例上续期成功才算仍然持有锁
+func (l *Locker) renew(ctx context.Context, redisKey, owner string, ttl time.Duration) (bool, error) {
+	return l.runOwned(ctx, renewScript, redisKey, owner, ttl, func(result int64) bool {
+		return result == 1
+	})
+}
+
+func (l *Locker) runOwned(ctx context.Context, script *goredis.Script, redisKey, owner string, ttl time.Duration, held func(int64) bool) (bool, error) {
+	results := make([]int64, len(l.clients))
+	errs := make([]error, len(l.clients))
+	l.each(func(i int, client goredis.UniversalClient) {
+		results[i], errs[i] = script.Run(ctx, client, []string{redisKey}, owner, ttl.Milliseconds()).Int64()
+	})
+	count := 0
+	for i := range l.clients {
+		if errs[i] == nil && held(results[i]) {
+			count++
+		}
+	}
+	if count >= l.quorum {
+		return true, nil
+	}
+	return false, firstError(errs)
+}
+
+// each 并发的在每个实例上执行
+func (l *Locker) each(f func(i int, client goredis.UniversalClient)) {
+	if len(l.clients) == 1 {
+		f(0, l.clients[0])
+		return
+	}
+	var wg sync.WaitGroup
+	for i, client := range l.clients {
+		wg.Add(1)
+		go func(i int, client goredis.UniversalClient) {
+			defer wg.Done()
+			f(i, client)
+		}(i, client)
+	}
+	wg.Wait()
+}
+
+func countErrors(errs []error) int {
+	count := 0
+	for _, err := range errs {
+		if err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquireLease 首次获取时启动续期，重入时增加引用
+func (l *Locker) acquireLease(redisKey, owner string, ttl time.Duration) *lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	leaseKey := redisKey + "\n" + owner
+	ls, exist := l.leases[leaseKey]
+	if !exist {
+		ls = &lease{stop: make(chan struct{}), lost: make(chan struct{})}
+		l.leases[leaseKey] = ls
+		if !l.options.DisableWatchdog {
+			go l.watch(leaseKey, redisKey, owner, ttl, ls)
+		}
+	}
+	ls.refs++
+	return ls
+}
+
+// releaseLease 所有的重入都释放之后停止续期
+func (l *Locker) releaseLease(h *LockHandle) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h.lease.refs--
+	if h.lease.refs > 0 {
+		return
+	}
+	leaseKey := h.redisKey + "\n" + h.owner
+	if l.leases[leaseKey] == h.lease {
+		delete(l.leases, leaseKey)
+	}
+	close(h.lease.stop)
+}
+
+// watch 每隔ttl/3续期；锁被其他持有者获取，或者超过ttl没有续期成功时认为锁已经丢失
+func (l *Locker) watch(leaseKey, redisKey, owner string, ttl time.Duration, ls *lease) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ls.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		held, err := l.renew(ctx, redisKey, owner, ttl)
+		cancel()
+		if held {
+			renewedAt = time.Now()
+			continue
+		}
+		if err != nil && time.Since(renewedAt) < ttl {
+			logger.Warn("锁续期失败，key：%s，错误：%v", redisKey, err)
+			continue
+		}
+
+		logger.Warn("锁已经丢失，key：%s", redisKey)
+		l.mu.Lock()
+		if l.leases[leaseKey] == ls {
+			delete(l.leases, leaseKey)
+		}
+		l.mu.Unlock()
+		close(ls.lost)
+		return
+	}
+}
+
+// Key 获取锁时的key，不包含前缀
+func (h *LockHandle) Key() string {
+	return h.key
+}
+
+// Token 首次获取锁时分配的fencing token，同一个key单调递增，重入时不变；写入其他存储时携带token，拒绝比已经写入的token小的请求
+// RedLock模式下没有单调递增的来源，总是返回0
+func (h *LockHandle) Token() int64 {
+	return h.token
+}
+
+// Lost 续期失败，锁已经丢失时关闭；关闭自动续期时不会关闭
+func (h *LockHandle) Lost() <-chan struct{} {
+	return h.lease.lost
+}
+
+// Unlock 释放锁，重入时减少一次重入的次数；重复释放或者锁已经丢失时返回 ErrLockNotHeld
+func (h *LockHandle) Unlock(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&h.released, 0, 1) {
+		return ErrLockNotHeld
+	}
+	h.locker.releaseLease(h)
+	held, err := h.locker.release(ctx, h.redisKey, h.owner, h.ttl)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/redis"
+	"github.com/isyscore/isc-gobase/validate"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestGetClientByName(t *testing.T) {
+	defaultServer := miniredis.RunT(t)
+	orderServer := miniredis.RunT(t)
+	config.RedisCfg = config.RedisConfig{
+		Standalone: config.RedisStandaloneConfig{Addr: defaultServer.Addr()},
+		Instances: map[string]config.RedisConfig{
+			"order": {Standalone: config.RedisStandaloneConfig{Addr: orderServer.Addr(), Network: "tcp"}},
+			"wrong": {Standalone: config.RedisStandaloneConfig{Addr: orderServer.Addr(), Network: "udp"}},
+		},
+	}
+	defer func() {
+		_ = redis.Close()
+		config.RedisCfg = config.RedisConfig{}
+	}()
+
+	ctx := context.Background()
+	rdb, err := redis.GetClient()
+	assert.Equal(t, err, nil)
+	rdb.Set(ctx, "key", "default", 0)
+	orderRdb, err := redis.GetClientByName("order")
+	assert.Equal(t, err, nil)
+	orderRdb.Set(ctx, "key", "order", 0)
+
+	v, _ := defaultServer.Get("key")
+	assert.Equal(t, v, "default")
+	v, _ = orderServer.Get("key")
+	assert.Equal(t, v, "order")
+
+	// 每个实例只有一个客户端
+	again, _ := redis.GetClientByName("order")
+	assert.Equal(t, again == orderRdb, true)
+
+	_, err = redis.GetClientByName("none")
+	var configErr *redis.ConfigError
+	assert.Equal(t, errors.As(err, &configErr), true)
+	_, err = redis.GetClientByName("wrong")
+	assert.Equal(t, errors.As(err, &configErr), true)
+	// 整体核查包含命名实例
+	result, _ := validate.Check(config.RedisCfg)
+	assert.Equal(t, result, false)
+
+	// 关闭之后重新创建
+	assert.Equal(t, redis.Close(), nil)
+	assert.Equal(t, orderRdb.Ping(ctx).Err() != nil, true)
+	orderRdb, _ = redis.GetClientByName("order")
+	assert.Equal(t, orderRdb.Get(ctx, "key").Val(), "order")
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/redis"
+	"github.com/magiconair/properties/assert"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestCommandHook(t *testing.T) {
+	server := miniredis.RunT(t)
+	config.RedisCfg = config.RedisConfig{
+		Instances: map[string]config.RedisConfig{
+			"hook": {Standalone: config.RedisStandaloneConfig{Addr: server.Addr()}, SlowThreshold: 20},
+		},
+	}
+	var buf bytes.Buffer
+	oldLogger, oldLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&buf)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	defer func() {
+		log.Logger = oldLogger
+		zerolog.SetGlobalLevel(oldLevel)
+		_ = redis.Close()
+		config.RedisCfg = config.RedisConfig{}
+	}()
+
+	rdb, err := redis.GetClientByName("hook")
+	assert.Equal(t, err, nil)
+	ctx := context.Background()
+	// 指标是累计的
+	before := redis.Metrics()["hook"]
+
+	goid.SetTraceID("trace-1")
+	rdb.Set(ctx, "user:1", "secret-value", 0)
+	goid.DelTraceID()
+	rdb.Get(ctx, "user:1")
+	rdb.Get(redis.WithTraceID(ctx, "trace-2"), "none")
+	rdb.Incr(ctx, "user:1")
+	pipe := rdb.Pipeline()
+	pipe.Set(ctx, "a", "1", 0)
+	pipe.Del(ctx, "a", "b")
+	_, _ = pipe.Exec(ctx)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		server.Lpush("queue", "task")
+	}()
+	rdb.BLPop(ctx, time.Second, "queue")
+
+	logs := buf.String()
+	assert.Matches(t, logs, `redis命令：set user:1 \*\*\*，实例：hook，.*traceId：trace-1`)
+	assert.Matches(t, logs, `redis命令：get none，.*traceId：trace-2`)
+	assert.Matches(t, logs, `redis命令：pipeline\[set a \*\*\*; del a b\]`)
+	assert.Matches(t, logs, `"level":"warn".*redis慢命令：blpop queue \*\*\*`)
+	assert.Equal(t, bytes.Contains(buf.Bytes(), []byte("secret-value")), false)
+
+	metrics := redis.Metrics()["hook"]
+	// redis.Nil不算错误
+	assert.Equal(t, metrics["get"].Count-before["get"].Count, uint64(2))
+	assert.Equal(t, metrics["get"].Errors-before["get"].Errors, uint64(0))
+	assert.Equal(t, metrics["incr"].Errors-before["incr"].Errors, uint64(1))
+	assert.Equal(t, metrics["pipeline"].Count-before["pipeline"].Count, uint64(1))
+	// pipeline中的命令按照命令名统计，耗时只统计在pipeline下
+	assert.Equal(t, metrics["set"].Count-before["set"].Count, uint64(2))
+	assert.Equal(t, metrics["del"].Count-before["del"].Count, uint64(1))
+	assert.Equal(t, metrics["del"].TotalNanos-before["del"].TotalNanos, time.Duration(0))
+	assert.Equal(t, metrics["pipeline"].TotalNanos > before["pipeline"].TotalNanos, true)
+	assert.Equal(t, metrics["blpop"].MaxNanos >= 30*time.Millisecond, true)
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/redis"
+	"github.com/magiconair/properties/assert"
+)
+
+func newLocker(t *testing.T, options redis.LockOptions) (*redis.Locker, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return redis.NewLocker(client, options), server
+}
+
+// tryLockAsync 在其他goroutine中获取锁，不是同一个持有者
+func tryLockAsync(locker *redis.Locker, key string, timeout time.Duration) (*redis.LockHandle, error) {
+	type result struct {
+		handle *redis.LockHandle
+		err    error
+	}
+	ch := make(chan result)
+	go func() {
+		handle, err := locker.TryLock(context.Background(), key, time.Second, timeout)
+		ch <- result{handle, err}
+	}()
+	r := <-ch
+	return r.handle, r.err
+}
+
+func TestLock(t *testing.T) {
+	locker, server := newLocker(t, redis.LockOptions{})
+	ctx := context.Background()
+
+	handle, err := locker.Lock(ctx, "order", time.Second)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, handle.Key(), "order")
+	assert.Equal(t, handle.Token(), int64(1))
+	assert.Equal(t, server.Exists("lock:{order}"), true)
+
+	_, err = tryLockAsync(locker, "order", 0)
+	assert.Equal(t, err, redis.ErrLockNotAcquired)
+
+	assert.Equal(t, handle.Unlock(ctx), nil)
+	assert.Equal(t, server.Exists("lock:{order}"), false)
+	assert.Equal(t, handle.Unlock(ctx), redis.ErrLockNotHeld)
+
+	// fencing token递增
+	other, err := tryLockAsync(locker, "order", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, other.Token(), int64(2))
+	assert.Equal(t, other.Unlock(ctx), nil)
+}
+
+func TestLockReentrant(t *testing.T) {
+	locker, server := newLocker(t, redis.LockOptions{})
+	ctx := context.Background()
+
+	first, _ := locker.Lock(ctx, "order", time.Second)
+	second, err := locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, second.Token(), first.Token())
+
+	assert.Equal(t, second.Unlock(ctx), nil)
+	_, err = tryLockAsync(locker, "order", 0)
+	assert.Equal(t, err, redis.ErrLockNotAcquired)
+
+	assert.Equal(t, first.Unlock(ctx), nil)
+	assert.Equal(t, server.Exists("lock:{order}"), false)
+}
+
+func TestLockWait(t *testing.T) {
+	locker, _ := newLocker(t, redis.LockOptions{RetryInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	handle, _ := locker.Lock(ctx, "order", time.Second)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = handle.Unlock(ctx)
+	}()
+	other, err := tryLockAsync(locker, "order", time.Second)
+	assert.Equal(t, err, nil)
+
+	// 等待直到ctx结束
+	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	errCh := make(chan error)
+	go func() {
+		_, err := locker.Lock(timeoutCtx, "order", time.Second)
+		errCh <- err
+	}()
+	assert.Equal(t, errors.Is(<-errCh, context.DeadlineExceeded), true)
+	assert.Equal(t, other.Unlock(ctx), nil)
+}
+
+func TestLockWatchdog(t *testing.T) {
+	locker, server := newLocker(t, redis.LockOptions{})
+	ctx := context.Background()
+
+	handle, _ := locker.Lock(ctx, "order", 300*time.Millisecond)
+	server.SetTTL("lock:{order}", time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+	// 续期之后重新设置为ttl
+	assert.Equal(t, server.TTL("lock:{order}"), 300*time.Millisecond)
+
+	// 锁被删除之后续期失败
+	server.Del("lock:{order}")
+	select {
+	case <-handle.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("没有检测到锁丢失")
+	}
+	assert.Equal(t, handle.Unlock(ctx), redis.ErrLockNotHeld)
+}
+
+func TestRedLock(t *testing.T) {
+	var clients []goredis.UniversalClient
+	var servers []*miniredis.Miniredis
+	for i := 0; i < 3; i++ {
+		server := miniredis.RunT(t)
+		client := goredis.NewClient(&goredis.Options{Addr: server.Addr(), MaxRetries: -1})
+		defer client.Close()
+		servers = append(servers, server)
+		clients = append(clients, client)
+	}
+	locker := redis.NewRedLocker(clients, redis.LockOptions{})
+	ctx := context.Background()
+
+	// 一个实例已经被其他持有者占用，其他两个实例超过半数
+	servers[0].HSet("lock:{order}", "owner", "other", "count", "1", "token", "1")
+	handle, err := locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, servers[1].Exists("lock:{order}"), true)
+	assert.Equal(t, handle.Unlock(ctx), nil)
+	assert.Equal(t, servers[1].Exists("lock:{order}"), false)
+	assert.Equal(t, servers[0].Exists("lock:{order}"), true)
+
+	// 不足半数时释放已经获取的实例
+	servers[1].HSet("lock:{order}", "owner", "other", "count", "1", "token", "1")
+	_, err = locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, redis.ErrLockNotAcquired)
+	assert.Equal(t, servers[2].Exists("lock:{order}"), false)
+
+	// 实例不可用
+	servers[0].Del("lock:{order}")
+	servers[1].Close()
+	handle, err = locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, nil)
+	servers[2].Close()
+	assert.Equal(t, handle.Unlock(ctx) != nil, true)
+}
+
+func TestRedLockToken(t *testing.T) {
+	var clients []goredis.UniversalClient
+	var servers []*miniredis.Miniredis
+	for i := 0; i < 3; i++ {
+		server := miniredis.RunT(t)
+		client := goredis.NewClient(&goredis.Options{Addr: server.Addr(), MaxRetries: -1})
+		defer client.Close()
+		servers = append(servers, server)
+		clients = append(clients, client)
+	}
+	locker := redis.NewRedLocker(clients, redis.LockOptions{})
+	ctx := context.Background()
+
+	// 实例的计数器相差很大时，取最大值会得到6之后的2，RedLock模式下不提供fencing token
+	_ = servers[0].Set("lock:{order}:fencing", "5")
+	handle, err := locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, handle.Token(), int64(0))
+	assert.Equal(t, handle.Unlock(ctx), nil)
+
+	servers[0].HSet("lock:{order}", "owner", "other", "count", "1", "token", "1")
+	handle, err = locker.TryLock(ctx, "order", time.Second, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, handle.Token(), int64(0))
+	assert.Equal(t, handle.Unlock(ctx), nil)
+}
@@ -2,12 +2,26 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/isyscore/isc-gobase/isc"
 	. "github.com/isyscore/isc-gobase/isc"
+	"github.com/isyscore/isc-gobase/server/rsp"
 )
 
+type VersionSource int
+
+const (
+	VsHeader VersionSource = iota // 请求头
+	VsQuery                       // 查询参数
+	VsPath                        // 路径参数
+)
+
+// DefaultVersionName 默认的版本名字（header名、查询参数名或路径参数名）
+var DefaultVersionName = "isc-api-version"
+
 type ApiPath struct {
 	Path     string
 	Handler  gin.HandlerFunc
@@ -15,16 +29,42 @@ type ApiPath struct {
 	Versions ISCList[*ApiVersion]
 }
 
+// VersionOption 版本路由的配置
+type VersionOption struct {
+	Source VersionSource // 版本的来源，默认：请求头
+	Name   string        // 版本所在的请求头、查询参数或者路径参数的名字，默认：isc-api-version
+	// 精确版本，比如：1.0；或者版本范围，比如：>=1.2 <2、^1.2、1.0 || >=2.1
+	Version string
+	// 默认版本：请求中没有携带版本时使用
+	Default bool
+	// 已废弃：响应中返回 Deprecation 头
+	Deprecated bool
+	// 下线时间：响应中返回 Sunset 头
+	Sunset time.Time
+}
+
+// VersionCondition 版本匹配的条件
+type VersionCondition struct {
+	Source VersionSource
+	Name   string
+	Range  *VersionRange
+}
+
 type ApiVersion struct {
+	// 兼容 RegisterRouteWithHeaders：版本所在的请求头以及对应的版本
 	Header  []string
 	Version []string
 	Handler gin.HandlerFunc
-}
 
-var apiVersionList ISCList[*ApiPath]
+	Conditions []*VersionCondition
+	Default    bool
+	Deprecated bool
+	Sunset     time.Time
+}
 
+// GetApiPath 当前engine上已经注册的版本路由
 func GetApiPath(path string, method HttpMethod) *ApiPath {
-	v := apiVersionList.Find(func(ap *ApiPath) bool {
+	v := currentRegistry().apiPaths.Find(func(ap *ApiPath) bool {
 		return ap.Path == path && ap.Method == method
 	})
 	if v == nil {
@@ -39,34 +79,158 @@ func NewApiPath(path string, method HttpMethod) *ApiPath {
 		Path:   path,
 		Method: method,
 	}
-	v.Handler = func(c *gin.Context) {
-		v.Versions.ForEach(func(a *ApiVersion) {
-			// 取出所有已定义header所对应的值
-			t := isc.ListToMapFrom[string, string](a.Header).Map(func(item string) string {
-				return c.GetHeader(item)
-			})
-			if isc.ListEquals(a.Version, t) {
-				// 找到符合条件的路由版本，并转发请求
-				a.Handler(c)
-			}
-		})
-	}
+	v.Handler = v.handle
 	// 将路由添加到维护列表中，只有第一次添加时，会注册到gin
-	apiVersionList = append(apiVersionList, &v)
+	registry := currentRegistry()
+	registry.apiPaths = append(registry.apiPaths, &v)
 	return &v
 }
 
+// handle 找到匹配的版本并转发请求：
+// 携带了版本但没有匹配的版本返回406；没有携带版本时使用默认版本，没有默认版本则返回404
+func (ap *ApiPath) handle(c *gin.Context) {
+	var matched *ApiVersion
+	versionCarried := false
+	for _, av := range ap.Versions {
+		carried, match := av.match(c)
+		versionCarried = versionCarried || carried
+		// 精确匹配的版本优先于范围匹配的版本
+		if match && (matched == nil || (!matched.isExact() && av.isExact())) {
+			matched = av
+		}
+	}
+
+	if matched == nil && !versionCarried {
+		matched = ap.defaultVersion()
+	}
+
+	if matched == nil {
+		if versionCarried {
+			rsp.FailedOfStatus(c, http.StatusNotAcceptable, "不支持请求的api版本")
+		} else {
+			rsp.FailedOfStatus(c, http.StatusNotFound, "请求中没有携带api版本")
+		}
+		c.Abort()
+		return
+	}
+
+	if matched.Deprecated {
+		c.Header("Deprecation", "true")
+	}
+	if !matched.Sunset.IsZero() {
+		c.Header("Sunset", matched.Sunset.UTC().Format(http.TimeFormat))
+	}
+	matched.Handler(c)
+}
+
+func (ap *ApiPath) defaultVersion() *ApiVersion {
+	v := ap.Versions.Find(func(a *ApiVersion) bool {
+		return a.Default
+	})
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
 func (ap *ApiPath) AddVersion(header []string, version []string, handler gin.HandlerFunc) {
 	// 查找指定版本的路由是否已经存在
 	av := ap.Versions.Find(func(a *ApiVersion) bool {
 		return isc.ListEquals(a.Header, header) && isc.ListEquals(a.Version, version)
 	})
-	if av == nil {
-		// 不存在，则添加一个
-		a := &ApiVersion{Header: header, Version: version, Handler: handler}
-		ap.Versions = append(ap.Versions, a)
-	} else {
+	if av != nil {
 		// 不允许重复添加
 		panic(fmt.Sprintf("版本 %s-%s 已经存在", header, version))
 	}
+
+	a := &ApiVersion{Header: header, Version: version, Handler: handler}
+	for i, h := range header {
+		express := ""
+		if i < len(version) {
+			express = version[i]
+		}
+		a.Conditions = append(a.Conditions, &VersionCondition{Source: VsHeader, Name: h, Range: exactRange(express)})
+	}
+	ap.Versions = append(ap.Versions, a)
+}
+
+// AddVersionOption 按照配置添加版本，版本可以是范围
+func (ap *ApiPath) AddVersionOption(option VersionOption, handler gin.HandlerFunc) {
+	if option.Name == "" {
+		option.Name = DefaultVersionName
+	}
+	versionRange, err := ParseVersionRange(option.Version)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	if ap.Versions.Any(func(a *ApiVersion) bool {
+		return len(a.Conditions) == 1 && a.Conditions[0].Source == option.Source && a.Conditions[0].Name == option.Name && a.Conditions[0].Range.Express == option.Version
+	}) {
+		panic(fmt.Sprintf("版本 %s-%s 已经存在", option.Name, option.Version))
+	}
+	if option.Default && ap.defaultVersion() != nil {
+		panic(fmt.Sprintf("路由 %s 的默认版本已经存在", ap.Path))
+	}
+
+	a := &ApiVersion{
+		Handler:    handler,
+		Conditions: []*VersionCondition{{Source: option.Source, Name: option.Name, Range: versionRange}},
+		Default:    option.Default,
+		Deprecated: option.Deprecated,
+		Sunset:     option.Sunset,
+	}
+	if option.Source == VsHeader {
+		a.Header = []string{option.Name}
+		a.Version = []string{option.Version}
+	}
+	ap.Versions = append(ap.Versions, a)
+}
+
+// match 返回请求是否携带了该版本需要的版本信息，以及是否匹配；
+// 兼容 AddVersion 的精确匹配，期望的版本为空时匹配没有携带该请求头的请求
+func (av *ApiVersion) match(c *gin.Context) (bool, bool) {
+	carried, matched, expectEmpty := false, true, true
+	for _, condition := range av.Conditions {
+		value := condition.value(c)
+		if condition.Range.Express != "" {
+			expectEmpty = false
+		}
+		if value == "" {
+			if condition.Range.Express != "" {
+				matched = false
+			}
+			continue
+		}
+		carried = true
+		if !condition.Range.Match(value) {
+			matched = false
+		}
+	}
+	return carried, matched && (carried || expectEmpty)
+}
+
+func (av *ApiVersion) isExact() bool {
+	for _, condition := range av.Conditions {
+		if !condition.Range.IsExact() {
+			return false
+		}
+	}
+	return true
+}
+
+func (vc *VersionCondition) value(c *gin.Context) string {
+	switch vc.Source {
+	case VsQuery:
+		return c.Query(vc.Name)
+	case VsPath:
+		return c.Param(vc.Name)
+	default:
+		return c.GetHeader(vc.Name)
+	}
+}
+
+// exactRange 兼容原有的精确匹配
+func exactRange(version string) *VersionRange {
+	return &VersionRange{Express: version, groups: [][]versionComparator{{{op: "", version: version}}}}
 }
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// PrincipalKey 认证主体在gin上下文中的key
+const PrincipalKey = "isc-auth-principal"
+
+// ErrUnauthorized 请求携带了凭证，但是凭证不合法
+var ErrUnauthorized = errors.New("认证失败")
+
+// Config base.server.auth 配置
+type Config struct {
+	Enable bool
+	// 需要认证的路由，为空则全部需要认证；路径以*结尾时按照前缀匹配
+	Include []string
+	// 不需要认证的路由，优先于include
+	Exclude []string
+	Jwt     JwtConfig
+	ApiKey  ApiKeyConfig
+	Basic   BasicConfig
+}
+
+// Principal 认证通过的主体
+type Principal struct {
+	// 认证方式：jwt、api-key、basic 或者自定义认证器的名字
+	Type  string
+	Name  string
+	Roles []string
+	// jwt的claims
+	Claims map[string]any
+}
+
+// HasRole 是否拥有角色
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator 认证器：请求没有携带对应的凭证时返回 nil, nil，交给下一个认证器；凭证不合法时返回错误
+type Authenticator interface {
+	Name() string
+	// Challenge 认证失败时返回的 WWW-Authenticate 头，可以为空
+	Challenge() string
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+var customAuthenticators []Authenticator
+var customLock sync.RWMutex
+
+// RegisterAuthenticator 注册自定义的认证器，位于配置的认证器之后
+func RegisterAuthenticator(authenticator Authenticator) {
+	customLock.Lock()
+	defer customLock.Unlock()
+	customAuthenticators = append(customAuthenticators, authenticator)
+}
+
+// principalStorage 认证主体独立的协程存储，和traceId等其他存储互不影响
+var principalStorage = goid.NewLocalStorage()
+
+// GetPrincipal 获取请求的认证主体，未认证时返回nil；有gin上下文时优先使用
+func GetPrincipal(c *gin.Context) *Principal {
+	value, _ := c.Get(PrincipalKey)
+	principal, _ := value.(*Principal)
+	return principal
+}
+
+// CurrentPrincipal 获取当前协程的认证主体，用于没有gin上下文的地方，比如日志和业务代码
+func CurrentPrincipal() *Principal {
+	principal, _ := principalStorage.Get().(*Principal)
+	return principal
+}
+
+// Middleware 按照配置 base.server.auth 创建认证中间件
+func Middleware() gin.HandlerFunc {
+	cfg := Config{}
+	if err := config.GetValueObject("base.server.auth", &cfg); err != nil {
+		logger.Warn("读取认证配置异常: %v", err)
+	}
+	return NewMiddleware(cfg)
+}
+
+// NewMiddleware 创建认证中间件：依次使用jwt、api-key、basic以及注册的认证器，全部失败时返回401
+func NewMiddleware(cfg Config) gin.HandlerFunc {
+	var authenticators []Authenticator
+	if cfg.Jwt.Enable {
+		if jwt, err := NewJwtAuthenticator(cfg.Jwt); err != nil {
+			logger.Error("创建jwt认证器失败: %v", err)
+		} else {
+			authenticators = append(authenticators, jwt)
+		}
+	}
+	if cfg.ApiKey.Enable {
+		authenticators = append(authenticators, NewApiKeyAuthenticator(cfg.ApiKey))
+	}
+	if cfg.Basic.Enable {
+		authenticators = append(authenticators, NewBasicAuthenticator(cfg.Basic))
+	}
+
+	return func(c *gin.Context) {
+		if !needAuth(cfg, c) {
+			c.Next()
+			return
+		}
+
+		customLock.RLock()
+		all := append(authenticators[:len(authenticators):len(authenticators)], customAuthenticators...)
+		customLock.RUnlock()
+
+		var challenges []string
+		for _, authenticator := range all {
+			principal, err := authenticator.Authenticate(c)
+			if err != nil {
+				logger.Debug("%s 认证失败: %v", authenticator.Name(), err)
+				unauthorized(c, []string{authenticator.Challenge()})
+				return
+			}
+			if principal != nil {
+				if principal.Type == "" {
+					principal.Type = authenticator.Name()
+				}
+				c.Set(PrincipalKey, principal)
+				// 结束时恢复之前的值，只影响认证主体的存储
+				previous := principalStorage.Set(principal)
+				defer restorePrincipal(previous)
+				c.Next()
+				return
+			}
+			if challenge := authenticator.Challenge(); challenge != "" {
+				challenges = append(challenges, challenge)
+			}
+		}
+		unauthorized(c, challenges)
+	}
+}
+
+func restorePrincipal(previous any) {
+	if previous == nil {
+		principalStorage.Del()
+	} else {
+		principalStorage.Set(previous)
+	}
+}
+
+func unauthorized(c *gin.Context, challenges []string) {
+	for _, challenge := range challenges {
+		if challenge != "" {
+			c.Writer.Header().Add("WWW-Authenticate", challenge)
+		}
+	}
+	rsp.FailedOfStatus(c, http.StatusUnauthorized, "未认证或者认证已失效")
+	c.Abort()
+}
+
+func needAuth(cfg Config, c *gin.Context) bool {
+	// 跨域预检请求不携带凭证；只跳过真正的预检请求，普通的OPTIONS请求仍然需要认证
+	if c.Request.Method == http.MethodOptions && c.GetHeader("Origin") != "" && c.GetHeader("Access-Control-Request-Method") != "" {
+		return false
+	}
+	for _, pattern := range cfg.Exclude {
+		if matchPath(pattern, c) {
+			return false
+		}
+	}
+	if len(cfg.Include) == 0 {
+		return true
+	}
+	for _, pattern := range cfg.Include {
+		if matchPath(pattern, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath 以*结尾时按照前缀匹配请求路径，否则匹配gin的路由路径或者请求路径
+func matchPath(pattern string, c *gin.Context) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(c.Request.URL.Path, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == c.FullPath() || pattern == c.Request.URL.Path
+}
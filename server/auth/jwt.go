@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/coder"
+)
+
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// JwtConfig base.server.auth.jwt 配置
+type JwtConfig struct {
+	Enable bool
+	// 签名算法：HS256、HS384、HS512、RS256、RS384、RS512，默认：HS256
+	Algorithm string
+	// HS算法的密钥
+	Secret string
+	// RS算法的公钥文件，pem格式，与 coder.RSAGenerateKeyPair 生成的格式一致
+	PublicKeyFile string
+	// token所在的请求头，默认：Authorization
+	Header string
+	// token的前缀，默认：Bearer
+	Scheme string
+	// 校验签发者和接收者，为空则不校验
+	Issuer   string
+	Audience string
+	// 校验过期时间时允许的时钟偏差，单位秒
+	Leeway int64
+	// 主体名和角色对应的claim，默认：sub、roles
+	NameClaim  string
+	RolesClaim string
+}
+
+type jwtAuthenticator struct {
+	cfg       JwtConfig
+	hash      crypto.Hash
+	secret    []byte
+	publicKey *rsa.PublicKey
+}
+
+// NewJwtAuthenticator 创建jwt认证器，只接受配置的签名算法
+func NewJwtAuthenticator(cfg JwtConfig) (Authenticator, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = "HS256"
+	}
+	cfg.Algorithm = strings.ToUpper(cfg.Algorithm)
+	if cfg.Header == "" {
+		cfg.Header = "Authorization"
+	}
+	if cfg.Scheme == "" {
+		cfg.Scheme = "Bearer"
+	}
+	if cfg.NameClaim == "" {
+		cfg.NameClaim = "sub"
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+
+	hash, ok := jwtHashes[cfg.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的jwt算法: %s", cfg.Algorithm)
+	}
+	a := &jwtAuthenticator{cfg: cfg, hash: hash}
+	if strings.HasPrefix(cfg.Algorithm, "HS") {
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt的密钥 secret 不能为空")
+		}
+		a.secret = []byte(cfg.Secret)
+	} else {
+		publicKey, err := coder.RSALoadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取jwt公钥异常: %v", err)
+		}
+		a.publicKey = publicKey
+	}
+	return a, nil
+}
+
+func (a *jwtAuthenticator) Name() string {
+	return "jwt"
+}
+
+func (a *jwtAuthenticator) Challenge() string {
+	return a.cfg.Scheme
+}
+
+func (a *jwtAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	value := c.GetHeader(a.cfg.Header)
+	prefix := a.cfg.Scheme + " "
+	if len(value) <= len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return nil, nil
+	}
+	claims, err := a.Verify(strings.TrimSpace(value[len(prefix):]))
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &Principal{Claims: claims}
+	principal.Name, _ = claims[a.cfg.NameClaim].(string)
+	switch roles := claims[a.cfg.RolesClaim].(type) {
+	case string:
+		principal.Roles = strings.Fields(strings.ReplaceAll(roles, ",", " "))
+	case []any:
+		for _, role := range roles {
+			if r, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, r)
+			}
+		}
+	}
+	return principal, nil
+}
+
+// Verify 校验token的签名以及exp、nbf、iss、aud，返回claims
+func (a *jwtAuthenticator) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt格式不正确")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	// 只接受配置的算法，避免算法混淆攻击（比如none）
+	if header.Alg != a.cfg.Algorithm {
+		return nil, fmt.Errorf("jwt算法 %s 与配置的算法不一致", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("jwt签名格式不正确")
+	}
+	signed := parts[0] + "." + parts[1]
+	if a.publicKey != nil {
+		h := a.hash.New()
+		h.Write([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(a.publicKey, a.hash, h.Sum(nil), signature); err != nil {
+			return nil, errors.New("jwt签名不正确")
+		}
+	} else {
+		h := hmac.New(a.hash.New, a.secret)
+		h.Write([]byte(signed))
+		if !hmac.Equal(h.Sum(nil), signature) {
+			return nil, errors.New("jwt签名不正确")
+		}
+	}
+
+	claims := map[string]any{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a *jwtAuthenticator) validateClaims(claims map[string]any) error {
+	now := time.Now().Unix()
+	if exp, ok := claims["exp"].(float64); ok && now > int64(exp)+a.cfg.Leeway {
+		return errors.New("jwt已过期")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now+a.cfg.Leeway < int64(nbf) {
+		return errors.New("jwt尚未生效")
+	}
+	if a.cfg.Issuer != "" && claims["iss"] != a.cfg.Issuer {
+		return errors.New("jwt的签发者不正确")
+	}
+	if a.cfg.Audience != "" {
+		matched := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			matched = aud == a.cfg.Audience
+		case []any:
+			for _, item := range aud {
+				if item == a.cfg.Audience {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return errors.New("jwt的接收者不正确")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(segment string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return errors.New("jwt格式不正确")
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return errors.New("jwt格式不正确")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/coder"
+)
+
+// ApiKeyConfig base.server.auth.api-key 配置
+type ApiKeyConfig struct {
+	Enable bool
+	// key所在的请求头，默认：X-API-Key
+	Header string
+	// key所在的请求参数，为空则只从请求头获取
+	Query string
+	Keys  []ApiKey
+}
+
+// ApiKey 静态的api key
+type ApiKey struct {
+	Key   string
+	Name  string
+	Roles []string
+}
+
+// BasicConfig base.server.auth.basic 配置
+type BasicConfig struct {
+	Enable bool
+	// 默认：isc-gobase
+	Realm string
+	Users []BasicUser
+}
+
+// BasicUser basic认证的用户，密码支持明文以及 {md5}、{sha256} 开头的摘要（coder.MD5String、coder.Sha256String）
+type BasicUser struct {
+	Username string
+	Password string
+	Roles    []string
+}
+
+type apiKeyAuthenticator struct {
+	cfg ApiKeyConfig
+}
+
+// NewApiKeyAuthenticator 创建api key认证器
+func NewApiKeyAuthenticator(cfg ApiKeyConfig) Authenticator {
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+	return &apiKeyAuthenticator{cfg: cfg}
+}
+
+func (a *apiKeyAuthenticator) Name() string {
+	return "api-key"
+}
+
+func (a *apiKeyAuthenticator) Challenge() string {
+	return ""
+}
+
+func (a *apiKeyAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	key := c.GetHeader(a.cfg.Header)
+	if key == "" && a.cfg.Query != "" {
+		key = c.Query(a.cfg.Query)
+	}
+	if key == "" {
+		return nil, nil
+	}
+	for _, item := range a.cfg.Keys {
+		if subtle.ConstantTimeCompare([]byte(item.Key), []byte(key)) == 1 {
+			return &Principal{Name: item.Name, Roles: item.Roles}, nil
+		}
+	}
+	return nil, errors.New("api key不正确")
+}
+
+type basicAuthenticator struct {
+	cfg BasicConfig
+}
+
+// NewBasicAuthenticator 创建basic认证器
+func NewBasicAuthenticator(cfg BasicConfig) Authenticator {
+	if cfg.Realm == "" {
+		cfg.Realm = "isc-gobase"
+	}
+	return &basicAuthenticator{cfg: cfg}
+}
+
+func (a *basicAuthenticator) Name() string {
+	return "basic"
+}
+
+func (a *basicAuthenticator) Challenge() string {
+	return `Basic realm="` + a.cfg.Realm + `"`
+}
+
+func (a *basicAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	for _, user := range a.cfg.Users {
+		if user.Username == username && matchPassword(user.Password, password) {
+			return &Principal{Name: username, Roles: user.Roles}, nil
+		}
+	}
+	return nil, errors.New("用户名或者密码不正确")
+}
+
+func matchPassword(expected string, password string) bool {
+	switch {
+	case strings.HasPrefix(expected, "{md5}"):
+		expected, password = strings.ToLower(strings.TrimPrefix(expected, "{md5}")), coder.MD5String(password)
+	case strings.HasPrefix(expected, "{sha256}"):
+		expected, password = strings.ToLower(strings.TrimPrefix(expected, "{sha256}")), coder.Sha256String(password)
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+}
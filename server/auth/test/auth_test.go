@@ -0,0 +1,191 @@
+package test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/coder"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/server/auth"
+	"github.com/magiconair/properties/assert"
+)
+
+func encodeSegment(value any) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hsToken(secret string, claims map[string]any) string {
+	signed := encodeSegment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func rsToken(key *rsa.PrivateKey, claims map[string]any) string {
+	signed := encodeSegment(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newAuthEngine(cfg auth.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(auth.NewMiddleware(cfg))
+	handler := func(c *gin.Context) {
+		principal := auth.GetPrincipal(c)
+		if principal == nil {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		// 协程内也可以获取到
+		c.String(http.StatusOK, principal.Type+":"+auth.CurrentPrincipal().Name)
+	}
+	engine.GET("/api/data", handler)
+	engine.GET("/api/public/info", handler)
+	return engine
+}
+
+func doAuthRequest(engine *gin.Engine, url string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestJwtHs(t *testing.T) {
+	engine := newAuthEngine(auth.Config{
+		Exclude: []string{"/api/public/*"},
+		Jwt:     auth.JwtConfig{Enable: true, Secret: "isyscore", Issuer: "isc"},
+	})
+
+	w := doAuthRequest(engine, "/api/public/info", nil)
+	assert.Equal(t, w.Body.String(), "anonymous")
+
+	w = doAuthRequest(engine, "/api/data", nil)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+
+	token := hsToken("isyscore", map[string]any{"sub": "zhou", "iss": "isc", "exp": time.Now().Add(time.Minute).Unix()})
+	w = doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "jwt:zhou")
+
+	// 签名不正确、已过期、签发者不正确
+	token = hsToken("other", map[string]any{"sub": "zhou", "iss": "isc"})
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token}).Code, http.StatusUnauthorized)
+	token = hsToken("isyscore", map[string]any{"sub": "zhou", "iss": "isc", "exp": time.Now().Add(-time.Minute).Unix()})
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token}).Code, http.StatusUnauthorized)
+	token = hsToken("isyscore", map[string]any{"sub": "zhou", "iss": "other"})
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token}).Code, http.StatusUnauthorized)
+
+	// 不接受none算法
+	token = encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(map[string]any{"sub": "zhou", "iss": "isc"}) + "."
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token}).Code, http.StatusUnauthorized)
+}
+
+func TestJwtRs(t *testing.T) {
+	dir := t.TempDir()
+	privateKeyPath, publicKeyPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+	if err := coder.RSAGenerateKeyPair(coder.RSA_KEY_SIZE_2048, privateKeyPath, publicKeyPath); err != nil {
+		t.Fatal(err)
+	}
+	privateKey, _ := coder.RSALoadPrivateKey(privateKeyPath)
+
+	engine := newAuthEngine(auth.Config{
+		Jwt: auth.JwtConfig{Enable: true, Algorithm: "RS256", PublicKeyFile: publicKeyPath, Audience: "app"},
+	})
+	token := rsToken(privateKey, map[string]any{"sub": "zhou", "aud": []string{"app", "web"}, "roles": []string{"admin"}})
+	w := doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "jwt:zhou")
+
+	// 使用HS算法以公钥作为密钥伪造
+	token = hsToken("fake", map[string]any{"sub": "zhou", "aud": "app"})
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": "Bearer " + token}).Code, http.StatusUnauthorized)
+}
+
+func TestApiKeyAndBasic(t *testing.T) {
+	engine := newAuthEngine(auth.Config{
+		Include: []string{"/api/data"},
+		ApiKey:  auth.ApiKeyConfig{Enable: true, Query: "api-key", Keys: []auth.ApiKey{{Key: "k-123", Name: "job"}}},
+		Basic: auth.BasicConfig{Enable: true, Users: []auth.BasicUser{
+			{Username: "admin", Password: "{sha256}" + coder.Sha256String("123456")},
+		}},
+	})
+
+	// 没有包含的路由不需要认证
+	assert.Equal(t, doAuthRequest(engine, "/api/public/info", nil).Body.String(), "anonymous")
+
+	w := doAuthRequest(engine, "/api/data", nil)
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+	assert.Equal(t, w.Header().Get("WWW-Authenticate"), `Basic realm="isc-gobase"`)
+
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"X-API-Key": "k-123"}).Body.String(), "api-key:job")
+	assert.Equal(t, doAuthRequest(engine, "/api/data?api-key=k-123", nil).Body.String(), "api-key:job")
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"X-API-Key": "wrong"}).Code, http.StatusUnauthorized)
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:123456"))
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": basic}).Body.String(), "basic:admin")
+	basic = "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:wrong"))
+	assert.Equal(t, doAuthRequest(engine, "/api/data", map[string]string{"Authorization": basic}).Code, http.StatusUnauthorized)
+}
+
+type headerAuthenticator struct{}
+
+func (headerAuthenticator) Name() string      { return "custom" }
+func (headerAuthenticator) Challenge() string { return "" }
+func (headerAuthenticator) Authenticate(c *gin.Context) (*auth.Principal, error) {
+	if user := c.GetHeader("X-User"); user != "" {
+		return &auth.Principal{Name: user}, nil
+	}
+	return nil, nil
+}
+
+func TestCustomAuthenticator(t *testing.T) {
+	engine := newAuthEngine(auth.Config{Include: []string{"/api/public/*"}})
+	auth.RegisterAuthenticator(headerAuthenticator{})
+
+	assert.Equal(t, doAuthRequest(engine, "/api/public/info", map[string]string{"X-User": "zhou"}).Body.String(), "custom:zhou")
+	assert.Equal(t, doAuthRequest(engine, "/api/public/info", nil).Code, http.StatusUnauthorized)
+	assert.Equal(t, auth.CurrentPrincipal() == nil, true)
+}
+
+func TestPrincipalWithTraceId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var traceAfterAuth string
+	// 和server.Trace()一样设置traceId
+	engine.Use(func(c *gin.Context) {
+		goid.SetTraceID("trace-1")
+		defer goid.DelTraceID()
+		c.Next()
+		traceAfterAuth = goid.GetTraceID()
+	})
+	engine.Use(auth.NewMiddleware(auth.Config{}))
+	engine.GET("/api/data", func(c *gin.Context) {
+		c.String(http.StatusOK, auth.CurrentPrincipal().Name+":"+goid.GetTraceID())
+	})
+	auth.RegisterAuthenticator(headerAuthenticator{})
+
+	w := doAuthRequest(engine, "/api/data", map[string]string{"X-User": "zhou"})
+	assert.Equal(t, w.Body.String(), "zhou:trace-1")
+	// 认证结束时不删除traceId
+	assert.Equal(t, traceAfterAuth, "trace-1")
+	assert.Equal(t, auth.CurrentPrincipal() == nil, true)
+}
@@ -1,34 +1,264 @@
 package server
 
 import (
-	"log"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/logger"
 )
 
-// Cors 配置允许跨域请求
+var defaultAllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodHead, http.MethodOptions}
+var defaultAllowHeaders = []string{"Authorization", "Content-Type", "Content-Length", "X-CSRF-Token", "Token", "session"}
+
+// CorsConfig base.server.cors 配置
+type CorsConfig struct {
+	// 允许的来源：*、通配符（https://*.isyscore.com）或者正则（regex:^https://.*\.isyscore\.com$），默认：*
+	AllowOrigins []string
+	// 允许的方法，默认：GET、POST、PUT、DELETE、PATCH、HEAD、OPTIONS
+	AllowMethods []string
+	// 允许的请求头，配置*则允许请求中的所有头
+	AllowHeaders []string
+	// 允许前端读取的响应头
+	ExposeHeaders []string
+	// 预检请求的缓存时间，单位秒，默认：172800
+	MaxAge int
+	// 是否允许携带凭证，默认：false；允许全部来源时不可开启
+	AllowCredentials bool
+	// 指定路由的跨域配置，没有配置的项使用上面的配置
+	Routes []CorsRouteConfig
+}
+
+// CorsRouteConfig 指定路由的跨域配置
+type CorsRouteConfig struct {
+	// 路由路径，支持路径参数（/api/data/:id）以及以*结尾的前缀匹配
+	Path          string
+	AllowOrigins  []string
+	AllowMethods  []string
+	AllowHeaders  []string
+	ExposeHeaders []string
+	MaxAge        int
+	// 是否允许携带凭证，没有配置时使用上面的配置，配置为false时关闭
+	AllowCredentials *bool
+}
+
+type corsPolicy struct {
+	path             string
+	allowAllOrigins  bool
+	origins          []string
+	originRegexes    []*regexp.Regexp
+	allowMethods     string
+	allowHeaders     string
+	allowAllHeaders  bool
+	exposeHeaders    string
+	maxAge           string
+	allowCredentials bool
+}
+
+// Cors 按照配置 base.server.cors 创建跨域处理
 func Cors() gin.HandlerFunc {
+	cfg := CorsConfig{}
+	if err := config.GetValueObject("base.server.cors", &cfg); err != nil {
+		logger.Warn("读取跨域配置异常: %v", err)
+	}
+	return NewCors(cfg)
+}
+
+// NewCors 创建跨域处理：预检请求直接返回，不再进入后续处理；来源不允许时不返回跨域头
+func NewCors(cfg CorsConfig) gin.HandlerFunc {
+	global := newCorsPolicy("", cfg.AllowOrigins, cfg.AllowMethods, cfg.AllowHeaders, cfg.ExposeHeaders, cfg.MaxAge, cfg.AllowCredentials)
+	var routes []*corsPolicy
+	for _, route := range cfg.Routes {
+		routes = append(routes, newCorsPolicy(route.Path,
+			firstNotEmptyList(route.AllowOrigins, cfg.AllowOrigins),
+			firstNotEmptyList(route.AllowMethods, cfg.AllowMethods),
+			firstNotEmptyList(route.AllowHeaders, cfg.AllowHeaders),
+			firstNotEmptyList(route.ExposeHeaders, cfg.ExposeHeaders),
+			firstPositiveInt(route.MaxAge, cfg.MaxAge),
+			firstBool(route.AllowCredentials, cfg.AllowCredentials)))
+	}
+
 	return func(c *gin.Context) {
-		method := c.Request.Method
-		origin := c.Request.Header.Get("Origin")
-		if origin != "" {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE,UPDATE")
-			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Length, X-CSRF-Token, Token,session")
-			c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers")
-			c.Header("Access-Control-Max-Age", "172800")
-			c.Header("Access-Control-Allow-Credentials", "true")
-		}
-		//允许类型校验
-		if method == "OPTIONS" {
-			c.JSON(http.StatusOK, "ok!")
-		}
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("跨域中断: %v\n", err)
+		policy := global
+		for _, route := range routes {
+			if matchRoutePath(route.path, c.Request.URL.Path) {
+				policy = route
+				break
+			}
+		}
+
+		header := c.Writer.Header()
+		// 响应随来源变化时，没有来源以及来源不允许的响应也要携带 Vary，避免共享缓存把它们返回给其他来源
+		if !policy.allowAllOrigins {
+			header.Add("Vary", "Origin")
+		}
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !policy.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
 			}
-		}()
+			c.Next()
+			return
+		}
+
+		if policy.allowAllOrigins {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", policy.allowMethods)
+			if policy.allowAllHeaders {
+				if requestHeaders := c.GetHeader("Access-Control-Request-Headers"); requestHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", requestHeaders)
+				}
+			} else if policy.allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", policy.allowHeaders)
+			}
+			if policy.maxAge != "" {
+				header.Set("Access-Control-Max-Age", policy.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if policy.exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+		}
 		c.Next()
 	}
 }
+
+func newCorsPolicy(path string, origins, methods, headers, exposeHeaders []string, maxAge int, credentials bool) *corsPolicy {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	if len(methods) == 0 {
+		methods = defaultAllowMethods
+	}
+	if len(headers) == 0 {
+		headers = defaultAllowHeaders
+	}
+	if maxAge == 0 {
+		maxAge = 172800
+	}
+
+	policy := &corsPolicy{
+		path:             path,
+		allowMethods:     strings.ToUpper(strings.Join(methods, ", ")),
+		allowHeaders:     strings.Join(headers, ", "),
+		exposeHeaders:    strings.Join(exposeHeaders, ", "),
+		allowCredentials: credentials,
+	}
+	if maxAge > 0 {
+		policy.maxAge = strconv.Itoa(maxAge)
+	}
+	for _, header := range headers {
+		if header == "*" {
+			policy.allowAllHeaders = true
+		}
+	}
+
+	for _, origin := range origins {
+		switch {
+		case origin == "*":
+			policy.allowAllOrigins = true
+		case strings.HasPrefix(origin, "regex:"):
+			if reg, err := regexp.Compile(strings.TrimPrefix(origin, "regex:")); err != nil {
+				logger.Error("跨域来源 %s 的正则不合法: %v", origin, err)
+			} else {
+				policy.originRegexes = append(policy.originRegexes, reg)
+			}
+		case strings.Contains(origin, "*"):
+			expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[^/]*`) + "$"
+			policy.originRegexes = append(policy.originRegexes, regexp.MustCompile(expr))
+		default:
+			policy.origins = append(policy.origins, strings.ToLower(origin))
+		}
+	}
+
+	if policy.allowAllOrigins && policy.allowCredentials {
+		logger.Warn("跨域配置允许全部来源时不能携带凭证，已关闭 allow-credentials，请配置具体的来源")
+		policy.allowCredentials = false
+	}
+	return policy
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range p.origins {
+		if o == origin {
+			return true
+		}
+	}
+	for _, reg := range p.originRegexes {
+		if reg.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoutePath 路径匹配：支持路径参数（:id）以及以*结尾的前缀匹配
+func matchRoutePath(pattern string, path string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	}
+	patternItems := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathItems := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternItems) != len(pathItems) {
+		return false
+	}
+	for i, item := range patternItems {
+		if strings.HasPrefix(item, ":") {
+			continue
+		}
+		if item != pathItems[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func firstNotEmptyList(values ...[]string) []string {
+	for _, v := range values {
+		if len(v) != 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+func firstPositiveInt(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// firstBool 配置了value时使用value，否则使用默认值
+func firstBool(value *bool, defaultValue bool) bool {
+	if value != nil {
+		return *value
+	}
+	return defaultValue
+}
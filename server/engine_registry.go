@@ -0,0 +1,35 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/isyscore/isc-gobase/isc"
+)
+
+// engineRegistry 一个engine上注册的版本路由以及生成openapi文档需要的信息，不同的engine互不影响
+type engineRegistry struct {
+	apiPaths     ISCList[*ApiPath]
+	routeMeta    map[string]*routeMeta
+	openApiPaths []string
+}
+
+var registryLock sync.Mutex
+var registries = map[*gin.Engine]*engineRegistry{}
+
+// registryOf engine对应的注册信息，不存在则创建
+func registryOf(e *gin.Engine) *engineRegistry {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	r, ok := registries[e]
+	if !ok {
+		r = &engineRegistry{routeMeta: map[string]*routeMeta{}}
+		registries[e] = r
+	}
+	return r
+}
+
+// currentRegistry 当前engine的注册信息，WithEngine 执行期间为指定的engine
+func currentRegistry() *engineRegistry {
+	return registryOf(currentEngine())
+}
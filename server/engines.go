@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+// ManagementEngineName 管理端口的engine名字
+const ManagementEngineName = "management"
+
+type namedEngine struct {
+	name   string
+	port   int
+	engine *gin.Engine
+}
+
+var namedEngines []*namedEngine
+var namedEngineLock sync.Mutex
+
+// NewEngine 创建额外的命名engine，比如用于内部的api；port为0时读取配置 base.server.engines.{name}.port
+// 与主服务使用相同的标准中间件（跨域、请求体限制、响应处理、限流等），需要在 Run 之前创建，和主服务一起启动以及优雅关闭
+func NewEngine(name string, port int) *gin.Engine {
+	return newNamedEngine(name, port, true)
+}
+
+func newNamedEngine(name string, port int, accessControl bool) *gin.Engine {
+	namedEngineLock.Lock()
+	defer namedEngineLock.Unlock()
+
+	for _, ne := range namedEngines {
+		if ne.name == name {
+			logger.Warn("engine %s 已经存在", name)
+			return ne.engine
+		}
+	}
+	if port == 0 {
+		port = config.GetValueIntDefault(fmt.Sprintf("base.server.engines.%s.port", name), 0)
+	}
+	if port <= 0 {
+		logger.Error("engine %s 没有配置端口", name)
+		return nil
+	}
+
+	e := newEngine(accessControl)
+	namedEngines = append(namedEngines, &namedEngine{name: name, port: port, engine: e})
+	return e
+}
+
+// RemoveEngine 删除命名的engine，服务启动之后删除不会关闭已经在监听的端口，主要用于测试
+func RemoveEngine(name string) {
+	namedEngineLock.Lock()
+	defer namedEngineLock.Unlock()
+	for i, ne := range namedEngines {
+		if ne.name == name {
+			namedEngines = append(namedEngines[:i], namedEngines[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetEngine 获取命名的engine，不存在时返回nil
+func GetEngine(name string) *gin.Engine {
+	namedEngineLock.Lock()
+	defer namedEngineLock.Unlock()
+	for _, ne := range namedEngines {
+		if ne.name == name {
+			return ne.engine
+		}
+	}
+	return nil
+}
+
+// ManagementEngine 管理端口（base.server.management.port）的engine，没有配置管理端口时返回主服务的engine
+func ManagementEngine() *gin.Engine {
+	if e := GetEngine(ManagementEngineName); e != nil {
+		return e
+	}
+	return currentEngine()
+}
+
+// initManagementEngine 配置了与主服务不同的管理端口时，健康检查、指标、配置以及bean等endpoint注册到管理端口；
+// 管理端口供探针以及监控访问，不使用限流和认证
+func initManagementEngine() {
+	port := config.GetValueIntDefault("base.server.management.port", 0)
+	if port <= 0 || port == config.GetValueIntDefault("base.server.port", 8080) || GetEngine(ManagementEngineName) != nil {
+		return
+	}
+	newNamedEngine(ManagementEngineName, port, false)
+}
+
+func newNamedEngineServers() []*engineServer {
+	namedEngineLock.Lock()
+	defer namedEngineLock.Unlock()
+
+	var servers []*engineServer
+	for _, ne := range namedEngines {
+		logger.Info("%s 服务端口号: %d", ne.name, ne.port)
+		servers = append(servers, &engineServer{server: &http.Server{Addr: fmt.Sprintf(":%d", ne.port), Handler: ne.engine}})
+	}
+	return servers
+}
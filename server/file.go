@@ -0,0 +1,457 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/coder"
+	"github.com/isyscore/isc-gobase/compress"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// ErrUploadType 上传的文件类型不允许
+var ErrUploadType = errors.New("不允许上传该类型的文件")
+
+// ErrUploadChecksum 上传的文件校验和不一致
+var ErrUploadChecksum = errors.New("文件校验和不一致")
+
+// UploadConfig 上传的配置
+type UploadConfig struct {
+	// 保存的目录，默认：系统临时目录
+	Dir string
+	// 单个文件的最大值，支持：1024、512KB、10MB、1GB，为空则不限制
+	MaxSize string
+	// 最多的文件数，0则不限制
+	MaxFiles int
+	// 允许的类型：扩展名（.png）或者mime（image/png、image/*），mime按照文件内容识别，为空则不限制
+	AllowTypes []string
+	// 计算文件的校验和：md5、sha256，为空则不计算
+	Checksum string
+}
+
+// UploadedFile 上传后保存到磁盘的文件
+type UploadedFile struct {
+	// 表单的字段名
+	Field string
+	// 客户端的文件名
+	FileName string
+	// 保存的路径
+	Path        string
+	Size        int64
+	ContentType string
+	// 按照 UploadConfig.Checksum 计算的校验和
+	Checksum string
+}
+
+// UploadResult 上传的文件以及表单中的其他字段
+type UploadResult struct {
+	Files  []UploadedFile
+	Fields map[string]string
+}
+
+// File 获取字段对应的第一个文件
+func (r *UploadResult) File(field string) *UploadedFile {
+	for i := range r.Files {
+		if r.Files[i].Field == field {
+			return &r.Files[i]
+		}
+	}
+	return nil
+}
+
+// Remove 删除保存的文件
+func (r *UploadResult) Remove() {
+	for _, f := range r.Files {
+		_ = os.Remove(f.Path)
+	}
+}
+
+// Upload 注册上传的路由，文件流式保存到磁盘后调用handler，handler返回错误时删除保存的文件
+func Upload[Rsp any](path string, cfg UploadConfig, handler TypedHandler[*UploadResult, Rsp]) gin.IRoutes {
+	return Post(path, HandleUpload(cfg, handler))
+}
+
+// HandleUpload 将上传的处理函数适配为 gin.HandlerFunc，返回值自动包装为 rsp.DataResponse
+func HandleUpload[Rsp any](cfg UploadConfig, handler TypedHandler[*UploadResult, Rsp]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := SaveUpload(c, cfg)
+		if err != nil {
+			rejectUpload(c, err)
+			return
+		}
+
+		data, err := handler(c, result)
+		if err != nil {
+			result.Remove()
+		}
+		if c.Writer.Written() || c.IsAborted() {
+			return
+		}
+		if err != nil {
+			rsp.FailedOfError(c, err)
+			return
+		}
+		rsp.SuccessOfData(c, data)
+	}
+}
+
+func rejectUpload(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		rejectBodyTooLarge(c)
+	case errors.Is(err, ErrUploadType):
+		rsp.FailedOfStatus(c, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		rsp.FailedOfStandard(c, rsp.CodeBadRequest, err.Error())
+	}
+}
+
+// SaveUpload 流式读取multipart请求，文件逐个写入磁盘，不会将文件读入内存
+// 客户端可以通过字段 <文件字段名>_md5、<文件字段名>_sha256 或者请求头 X-Checksum-Md5、X-Checksum-Sha256（只有一个文件时）携带校验和，不一致时返回 ErrUploadChecksum
+// 出错时删除已经保存的文件
+func SaveUpload(c *gin.Context, cfg UploadConfig) (*UploadResult, error) {
+	maxSize := int64(-1)
+	if cfg.MaxSize != "" {
+		size, err := ParseSize(cfg.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		maxSize = size
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	result := &UploadResult{Fields: map[string]string{}}
+	if err = readUploadParts(reader, cfg, dir, maxSize, result); err == nil {
+		err = verifyUploadChecksum(c, cfg, result)
+	}
+	if err != nil {
+		result.Remove()
+		return nil, err
+	}
+	return result, nil
+}
+
+func readUploadParts(reader *multipart.Reader, cfg UploadConfig, dir string, maxSize int64, result *UploadResult) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if part.FileName() == "" {
+			// 普通字段限制为1MB
+			value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+			_ = part.Close()
+			if err != nil {
+				return err
+			}
+			result.Fields[part.FormName()] = string(value)
+			continue
+		}
+
+		if cfg.MaxFiles > 0 && len(result.Files) >= cfg.MaxFiles {
+			_ = part.Close()
+			return fmt.Errorf("上传的文件数超过了限制：%d", cfg.MaxFiles)
+		}
+		file, err := saveUploadPart(part, cfg, dir, maxSize)
+		_ = part.Close()
+		if file != nil {
+			result.Files = append(result.Files, *file)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func saveUploadPart(part *multipart.Part, cfg UploadConfig, dir string, maxSize int64) (*UploadedFile, error) {
+	fileName := filepath.Base(part.FileName())
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	// 读取文件头用于识别类型
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowUploadType(cfg.AllowTypes, ext, contentType) {
+		return nil, fmt.Errorf("%w：%s(%s)", ErrUploadType, fileName, contentType)
+	}
+
+	out, err := os.CreateTemp(dir, "upload-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	file := &UploadedFile{Field: part.FormName(), FileName: fileName, Path: out.Name(), ContentType: contentType}
+
+	var src io.Reader = io.MultiReader(bytes.NewReader(head), part)
+	if maxSize >= 0 {
+		// 多读一个字节用于判断是否超过限制
+		src = io.LimitReader(src, maxSize+1)
+	}
+	file.Size, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return file, err
+	}
+	if maxSize >= 0 && file.Size > maxSize {
+		return file, fmt.Errorf("%w：%s", ErrBodyTooLarge, fileName)
+	}
+
+	if cfg.Checksum != "" {
+		if file.Checksum, err = fileChecksum(cfg.Checksum, file.Path); err != nil {
+			return file, err
+		}
+	}
+	return file, nil
+}
+
+func allowUploadType(allowTypes []string, ext string, contentType string) bool {
+	if len(allowTypes) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, allow := range allowTypes {
+		allow = strings.ToLower(strings.TrimSpace(allow))
+		switch {
+		case strings.HasPrefix(allow, "."):
+			if allow == ext {
+				return true
+			}
+		case strings.HasSuffix(allow, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(allow, "*")) {
+				return true
+			}
+		case allow == mediaType:
+			return true
+		}
+	}
+	return false
+}
+
+func verifyUploadChecksum(c *gin.Context, cfg UploadConfig, result *UploadResult) error {
+	for i := range result.Files {
+		file := &result.Files[i]
+		for _, algorithm := range []string{"md5", "sha256"} {
+			expected := result.Fields[file.Field+"_"+algorithm]
+			if expected == "" && len(result.Files) == 1 {
+				expected = c.GetHeader(checksumHeaders[algorithm])
+			}
+			if expected == "" {
+				continue
+			}
+
+			actual := file.Checksum
+			if !strings.EqualFold(cfg.Checksum, algorithm) {
+				var err error
+				if actual, err = fileChecksum(algorithm, file.Path); err != nil {
+					return err
+				}
+			}
+			if !strings.EqualFold(actual, expected) {
+				return fmt.Errorf("%w：%s", ErrUploadChecksum, file.FileName)
+			}
+		}
+	}
+	return nil
+}
+
+var checksumHeaders = map[string]string{"md5": "X-Checksum-Md5", "sha256": "X-Checksum-Sha256"}
+
+func fileChecksum(algorithm string, path string) (string, error) {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return coder.MD5File(path)
+	case "sha256":
+		return coder.Sha256File(path)
+	default:
+		return "", fmt.Errorf("不支持的校验和算法：%s", algorithm)
+	}
+}
+
+// DownloadOption 下载的选项
+type DownloadOption struct {
+	// 作为附件下载，为false则在浏览器中直接展示
+	Attachment bool
+	// 下载的文件名，默认：文件本身的名字
+	Name string
+	// 客户端支持时使用gzip压缩文本类的文件，有Range请求时不压缩
+	Gzip bool
+	// 压缩的最小文件大小，默认：1KB
+	GzipMinSize int64
+	// 使用文件的校验和（md5、sha256）作为ETag并通过响应头 X-Checksum-Md5、X-Checksum-Sha256 返回，为空则使用修改时间和大小生成ETag
+	Checksum string
+}
+
+// Download 注册下载的路由，path下的子路径对应root目录下的文件，例如：Download("/api/files", "/data/files", option)
+func Download(path string, root string, option DownloadOption) gin.IRoutes {
+	return Get(strings.TrimSuffix(path, "/")+"/*filepath", func(c *gin.Context) {
+		name := filepath.FromSlash(filepath.Clean("/" + c.Param("filepath")))
+		ServeFile(c, filepath.Join(root, name), option)
+	})
+}
+
+// ServeFile 返回文件，支持Range断点续传、ETag、Last-Modified以及gzip压缩
+func ServeFile(c *gin.Context, filePath string, option DownloadOption) {
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		rsp.FailedOfStatus(c, http.StatusNotFound, "文件不存在")
+		return
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		rsp.FailedOfStatus(c, http.StatusNotFound, "文件不存在")
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	name := option.Name
+	if name == "" {
+		name = info.Name()
+	}
+	disposition := "inline"
+	if option.Attachment {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(name)))
+	c.Header("Accept-Ranges", "bytes")
+
+	etag := fmt.Sprintf("W/\"%x-%x\"", info.ModTime().UnixNano(), info.Size())
+	if option.Checksum != "" {
+		checksum, err := cachedChecksum(option.Checksum, filePath, info)
+		if err != nil {
+			logger.Warn("计算文件校验和异常：%s，%v", filePath, err)
+		} else {
+			etag = "\"" + checksum + "\""
+			c.Header(checksumHeaders[strings.ToLower(option.Checksum)], checksum)
+		}
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if option.Gzip && shouldGzip(c, option, info, contentType) {
+		serveGzip(c, file, info, etag, contentType)
+		return
+	}
+	c.Header("ETag", etag)
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	// 处理Range、If-Range、If-None-Match、If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), file)
+}
+
+func shouldGzip(c *gin.Context, option DownloadOption, info os.FileInfo, contentType string) bool {
+	if c.GetHeader("Range") != "" || !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		return false
+	}
+	minSize := option.GzipMinSize
+	if minSize <= 0 {
+		minSize = 1 << 10
+	}
+	if info.Size() < minSize {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, t := range []string{"json", "xml", "javascript", "yaml", "svg"} {
+		if strings.Contains(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func serveGzip(c *gin.Context, file *os.File, info os.FileInfo, etag string, contentType string) {
+	// 压缩后的内容与原文件不同，使用不同的ETag
+	etag = strings.TrimSuffix(etag, "\"") + "-gzip\""
+	c.Header("ETag", etag)
+	c.Header("Vary", "Accept-Encoding")
+	c.Header("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	if notModified(c, etag, info.ModTime()) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Encoding", "gzip")
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if _, err := compress.GzipCopy(c.Writer, file); err != nil {
+		logger.Warn("gzip压缩文件异常：%s，%v", file.Name(), err)
+	}
+}
+
+func notModified(c *gin.Context, etag string, modTime time.Time) bool {
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		return !modTime.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+type checksumEntry struct {
+	modTime  time.Time
+	size     int64
+	checksum string
+}
+
+// 文件没有变化时不重复计算校验和
+var checksumCache sync.Map
+
+func cachedChecksum(algorithm string, path string, info os.FileInfo) (string, error) {
+	key := algorithm + ":" + path
+	if value, ok := checksumCache.Load(key); ok {
+		entry := value.(checksumEntry)
+		if entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+			return entry.checksum, nil
+		}
+	}
+	checksum, err := fileChecksum(algorithm, path)
+	if err != nil {
+		return "", err
+	}
+	checksumCache.Store(key, checksumEntry{modTime: info.ModTime(), size: info.Size(), checksum: checksum})
+	return checksum, nil
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/isyscore/isc-gobase/validate"
+)
+
+// TypedHandler 类型化的处理函数：请求参数自动绑定并核查，返回值自动包装为 rsp.DataResponse
+type TypedHandler[Req any, Rsp any] func(c *gin.Context, req Req) (Rsp, error)
+
+// Handle 将 TypedHandler 适配为 gin.HandlerFunc，可以配合 RegisterRoute 以及 XxxWith 的版本路由使用
+func Handle[Req any, Rsp any](handler TypedHandler[Req, Rsp]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, err := BindRequest[Req](c)
+		if errors.Is(err, ErrBodyTooLarge) {
+			rejectBodyTooLarge(c)
+			return
+		}
+		if err != nil {
+			rsp.FailedOfStandard(c, rsp.CodeBadRequest, err.Error())
+			return
+		}
+
+		if ok, errMsg := validate.Check(req); !ok {
+			rsp.FailedOfStandard(c, rsp.CodeBadRequest, errMsg)
+			return
+		}
+
+		data, err := handler(c, req)
+		// 处理函数中已经自行返回了，则不再包装
+		if c.Writer.Written() || c.IsAborted() {
+			return
+		}
+		if err != nil {
+			rsp.FailedOfError(c, err)
+			return
+		}
+		rsp.SuccessOfData(c, data)
+	}
+}
+
+// BindRequest 将路径参数(uri)、查询参数(form)、请求头(header)和json的body绑定到请求对象
+// 只有结构体中声明了对应tag的来源才会绑定
+func BindRequest[Req any](c *gin.Context) (Req, error) {
+	var req Req
+	target := reflect.ValueOf(&req)
+	// Req 本身为指针时，先创建指向的对象
+	if reqType := reflect.TypeOf(req); reqType != nil && reqType.Kind() == reflect.Ptr {
+		target.Elem().Set(reflect.New(reqType.Elem()))
+		target = target.Elem()
+	}
+	ptr := target.Interface()
+
+	tags := getBindTags(target.Type().Elem())
+	if tags.uri && len(c.Params) != 0 {
+		if err := c.ShouldBindUri(ptr); err != nil {
+			return req, err
+		}
+	}
+	if tags.form && c.Request.URL.RawQuery != "" {
+		if err := c.ShouldBindQuery(ptr); err != nil {
+			return req, err
+		}
+	}
+	if tags.header {
+		if err := c.ShouldBindHeader(ptr); err != nil {
+			return req, err
+		}
+	}
+	if hasBody(c.Request) {
+		if err := c.ShouldBindJSON(ptr); err != nil && !errors.Is(err, io.EOF) {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return r.ContentLength > 0
+	}
+	return r.ContentLength != 0
+}
+
+type bindTags struct {
+	uri    bool
+	form   bool
+	header bool
+}
+
+var bindTagsCache sync.Map
+
+func getBindTags(t reflect.Type) bindTags {
+	if v, ok := bindTagsCache.Load(t); ok {
+		return v.(bindTags)
+	}
+	tags := bindTags{}
+	collectBindTags(t, &tags)
+	bindTagsCache.Store(t, tags)
+	return tags
+}
+
+func collectBindTags(t reflect.Type, tags *bindTags) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i, num := 0, t.NumField(); i < num; i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			collectBindTags(field.Type, tags)
+			continue
+		}
+		if _, ok := field.Tag.Lookup("uri"); ok {
+			tags.uri = true
+		}
+		if _, ok := field.Tag.Lookup("form"); ok {
+			tags.form = true
+		}
+		if _, ok := field.Tag.Lookup("header"); ok {
+			tags.header = true
+		}
+	}
+}
+
+func GetJSON[Req any, Rsp any](path string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRoute(getPathAppendApiModel(path), HmGet, handler)
+}
+
+func PostJSON[Req any, Rsp any](path string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRoute(getPathAppendApiModel(path), HmPost, handler)
+}
+
+func PutJSON[Req any, Rsp any](path string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRoute(getPathAppendApiModel(path), HmPut, handler)
+}
+
+func DeleteJSON[Req any, Rsp any](path string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRoute(getPathAppendApiModel(path), HmDelete, handler)
+}
+
+func GetJSONWith[Req any, Rsp any](path string, header []string, versionName []string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRouteWithHeaders(getPathAppendApiModel(path), HmGet, header, versionName, handler)
+}
+
+func PostJSONWith[Req any, Rsp any](path string, header []string, versionName []string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRouteWithHeaders(getPathAppendApiModel(path), HmPost, header, versionName, handler)
+}
+
+func PutJSONWith[Req any, Rsp any](path string, header []string, versionName []string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRouteWithHeaders(getPathAppendApiModel(path), HmPut, header, versionName, handler)
+}
+
+func DeleteJSONWith[Req any, Rsp any](path string, header []string, versionName []string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	return registerTypedRouteWithHeaders(getPathAppendApiModel(path), HmDelete, header, versionName, handler)
+}
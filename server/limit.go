@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// ErrBodyTooLarge 请求体超过了限制
+var ErrBodyTooLarge = errors.New("请求体超过了限制")
+
+// TimeoutConfig base.server.timeout 配置，单位毫秒，0表示不限制
+type TimeoutConfig struct {
+	// http.Server 的超时
+	Read       int64
+	ReadHeader int64
+	Write      int64
+	Idle       int64
+	// 处理请求的超时，超时后取消请求的context
+	Handler int64
+	// 指定路由的处理超时
+	Routes []TimeoutRouteConfig
+}
+
+// TimeoutRouteConfig 指定路由的处理超时
+type TimeoutRouteConfig struct {
+	// 路由路径，支持路径参数（/api/data/:id）以及以*结尾的前缀匹配
+	Path string
+	// http方法，为空则匹配全部
+	Method  string
+	Handler int64
+}
+
+// BodyLimitConfig base.server.body 配置
+type BodyLimitConfig struct {
+	// 请求体的最大值，支持：1024、512KB、10MB、1GB，为空则不限制
+	MaxSize string
+	Routes  []BodyLimitRouteConfig
+}
+
+// BodyLimitRouteConfig 指定路由的请求体最大值
+type BodyLimitRouteConfig struct {
+	Path    string
+	Method  string
+	MaxSize string
+}
+
+// SlowRequestConfig base.server.slow-request 配置
+type SlowRequestConfig struct {
+	Enable bool
+	// 慢请求的阈值，单位毫秒，默认：3000
+	Threshold int64
+}
+
+func getTimeoutConfig() TimeoutConfig {
+	cfg := TimeoutConfig{}
+	if err := config.GetValueObject("base.server.timeout", &cfg); err != nil {
+		logger.Warn("读取超时配置异常: %v", err)
+	}
+	return cfg
+}
+
+// applyServerLimits 设置 http.Server 的超时以及请求头的最大值
+func applyServerLimits(server *http.Server, cfg TimeoutConfig) {
+	server.ReadTimeout = time.Duration(cfg.Read) * time.Millisecond
+	server.ReadHeaderTimeout = time.Duration(cfg.ReadHeader) * time.Millisecond
+	server.WriteTimeout = time.Duration(cfg.Write) * time.Millisecond
+	server.IdleTimeout = time.Duration(cfg.Idle) * time.Millisecond
+	server.MaxHeaderBytes = config.GetValueIntDefault("base.server.max-header-bytes", 0)
+}
+
+// ParseSize 解析大小：1024、512KB、10MB、1GB，不区分大小写
+func ParseSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		value  int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(size, u.suffix) {
+			size, unit = strings.TrimSpace(strings.TrimSuffix(size, u.suffix)), u.value
+			break
+		}
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("大小的格式不正确: " + size)
+	}
+	return value * unit, nil
+}
+
+type routeValue[T any] struct {
+	path   string
+	method string
+	value  T
+}
+
+func matchRouteValue[T any](routes []routeValue[T], c *gin.Context, defaultValue T) T {
+	for _, route := range routes {
+		if route.method != "" && !strings.EqualFold(route.method, c.Request.Method) {
+			continue
+		}
+		if matchRoutePath(route.path, c.Request.URL.Path) {
+			return route.value
+		}
+	}
+	return defaultValue
+}
+
+// BodyLimit 按照配置 base.server.body 创建请求体大小限制
+func BodyLimit() gin.HandlerFunc {
+	cfg := BodyLimitConfig{}
+	if err := config.GetValueObject("base.server.body", &cfg); err != nil {
+		logger.Warn("读取请求体配置异常: %v", err)
+	}
+	return NewBodyLimit(cfg)
+}
+
+// NewBodyLimit 创建请求体大小限制：超过限制时返回413
+func NewBodyLimit(cfg BodyLimitConfig) gin.HandlerFunc {
+	parse := func(size string) int64 {
+		if size == "" {
+			return 0
+		}
+		value, err := ParseSize(size)
+		if err != nil {
+			logger.Error("%v", err)
+		}
+		return value
+	}
+	maxSize := parse(cfg.MaxSize)
+	var routes []routeValue[int64]
+	for _, route := range cfg.Routes {
+		routes = append(routes, routeValue[int64]{path: route.Path, method: route.Method, value: parse(route.MaxSize)})
+	}
+
+	return func(c *gin.Context) {
+		limit := matchRouteValue(routes, c, maxSize)
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			rejectBodyTooLarge(c)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: c.Request.Body, remaining: limit}
+		c.Request.Body = body
+		c.Next()
+		// 处理函数读取请求体失败后没有返回响应
+		if body.exceeded && !c.Writer.Written() {
+			rejectBodyTooLarge(c)
+		}
+	}
+}
+
+func rejectBodyTooLarge(c *gin.Context) {
+	// 不再读取剩余的请求体，直接关闭连接
+	c.Header("Connection", "close")
+	rsp.FailedOfStatus(c, http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+	c.Abort()
+}
+
+// limitedBody 读取超过限制后一直返回 ErrBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// 多读一个字节用于判断是否超过限制
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return int(b.remaining), ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// HandlerTimeout 按照配置 base.server.timeout 创建处理超时
+func HandlerTimeout() gin.HandlerFunc {
+	return NewHandlerTimeout(getTimeoutConfig())
+}
+
+// NewHandlerTimeout 创建处理超时：超时后取消请求的context，处理函数需要响应context的取消；超时且没有返回响应时返回503
+func NewHandlerTimeout(cfg TimeoutConfig) gin.HandlerFunc {
+	var routes []routeValue[time.Duration]
+	for _, route := range cfg.Routes {
+		routes = append(routes, routeValue[time.Duration]{path: route.Path, method: route.Method, value: time.Duration(route.Handler) * time.Millisecond})
+	}
+	timeout := time.Duration(cfg.Handler) * time.Millisecond
+
+	return func(c *gin.Context) {
+		d := matchRouteValue(routes, c, timeout)
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			logger.Warn("请求处理超时：%s %s，超时时间：%v，traceId：%s", c.Request.Method, c.Request.URL.Path, d, TraceId(c))
+			rsp.FailedOfStatus(c, http.StatusServiceUnavailable, "请求处理超时")
+		}
+	}
+}
+
+// SlowRequest 按照配置 base.server.slow-request 创建慢请求日志
+func SlowRequest() gin.HandlerFunc {
+	cfg := SlowRequestConfig{}
+	if err := config.GetValueObject("base.server.slow-request", &cfg); err != nil {
+		logger.Warn("读取慢请求配置异常: %v", err)
+	}
+	return NewSlowRequest(cfg)
+}
+
+// NewSlowRequest 创建慢请求日志：处理时间超过阈值时打印路由、耗时以及traceId
+func NewSlowRequest(cfg SlowRequestConfig) gin.HandlerFunc {
+	threshold := time.Duration(cfg.Threshold) * time.Millisecond
+	if threshold <= 0 {
+		threshold = 3 * time.Second
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		if cost := time.Since(start); cost >= threshold {
+			route := c.FullPath()
+			if route == "" {
+				route = c.Request.URL.Path
+			}
+			logger.Warn("慢请求：%s %s，uri：%s，状态码：%d，耗时：%v，traceId：%s", c.Request.Method, route, c.Request.RequestURI, c.Writer.Status(), cost, TraceId(c))
+		}
+	}
+}
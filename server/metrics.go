@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsProvider 指标的提供者，返回值会序列化为json
+type MetricsProvider func() any
+
+var metricsProviders = map[string]MetricsProvider{}
+var metricsLock sync.RWMutex
+var serverStartTime = time.Now()
+
+// RegisterMetrics 注册指标，在 /system/metrics 中以name作为key返回
+func RegisterMetrics(name string, provider MetricsProvider) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	metricsProviders[name] = provider
+}
+
+// RegisterMetricsEndpoint 注册指标的endpoint，配置了管理端口时注册到管理端口
+func RegisterMetricsEndpoint(apiBase string) gin.IRoutes {
+	if "" == apiBase || !checkEngine() {
+		return nil
+	}
+	e := ManagementEngine()
+	registerRouteOn(e, apiBase+"/system/metrics", HmGet, metricsHandler)
+	return e
+}
+
+func metricsHandler(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	metrics := map[string]any{
+		"uptime":     int64(time.Since(serverStartTime).Seconds()),
+		"goroutines": runtime.NumGoroutine(),
+		"memory": map[string]any{
+			"alloc":       mem.Alloc,
+			"sys":         mem.Sys,
+			"heapAlloc":   mem.HeapAlloc,
+			"heapInuse":   mem.HeapInuse,
+			"heapObjects": mem.HeapObjects,
+		},
+		"gc": map[string]any{
+			"num":        mem.NumGC,
+			"pauseTotal": time.Duration(mem.PauseTotalNs).Milliseconds(),
+		},
+	}
+
+	metricsLock.RLock()
+	for name, provider := range metricsProviders {
+		metrics[name] = provider()
+	}
+	metricsLock.RUnlock()
+	c.JSON(http.StatusOK, metrics)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	h2 "github.com/isyscore/isc-gobase/http"
+	"github.com/isyscore/isc-gobase/isc"
+	"github.com/isyscore/isc-gobase/server/openapi"
+)
+
+// swagger-ui-dist的静态文件打包在服务中，页面不依赖外部的cdn
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css swagger-ui/favicon-32x32.png
+var swaggerUiFiles embed.FS
+
+var swaggerUiAssets = map[string]string{
+	"swagger-ui-bundle.js": h2.ContentTypeJavaScript,
+	"swagger-ui.css":       h2.ContentTypeCss,
+	"favicon-32x32.png":    h2.ContentTypePng,
+}
+
+// RegisterOpenApiEndpoint 注册openapi文档，uiPath不为空时注册swagger-ui页面
+// swagger-ui的js和css默认使用服务中打包的文件，配置 base.server.openapi.cdn-url 时改为由浏览器从cdn加载
+func RegisterOpenApiEndpoint(docPath string, uiPath string) gin.IRoutes {
+	if !checkEngine() {
+		return nil
+	}
+	registry := currentRegistry()
+	registry.openApiPaths = append(registry.openApiPaths, docPath)
+	e := currentEngine()
+	e.GET(docPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, openApiDocumentOf(e))
+	})
+	if uiPath == "" {
+		return e
+	}
+	uiPath = strings.TrimSuffix(uiPath, "/")
+	registry.openApiPaths = append(registry.openApiPaths, uiPath)
+	assetUrl := strings.TrimSuffix(config.GetValueString("base.server.openapi.cdn-url"), "/")
+	if assetUrl == "" {
+		assetUrl = uiPath
+		for name, contentType := range swaggerUiAssets {
+			data, _ := swaggerUiFiles.ReadFile("swagger-ui/" + name)
+			contentType := contentType
+			registry.openApiPaths = append(registry.openApiPaths, uiPath+"/"+name)
+			e.GET(uiPath+"/"+name, func(c *gin.Context) {
+				c.Header("Cache-Control", "public, max-age=86400")
+				c.Data(http.StatusOK, contentType, data)
+			})
+		}
+	}
+	e.GET(uiPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, h2.ContentTypeHtml, []byte(fmt.Sprintf(swaggerUiHtml, config.GetValueStringDefault("base.application.name", "isc-gobase"), assetUrl, assetUrl, assetUrl, docPath)))
+	})
+	return e
+}
+
+// OpenApiDocument 根据当前engine上已经注册的路由生成openapi文档
+func OpenApiDocument() *openapi.Document {
+	return openApiDocumentOf(currentEngine())
+}
+
+func openApiDocumentOf(e *gin.Engine) *openapi.Document {
+	g := openapi.NewGenerator(config.GetValueStringDefault("base.application.name", "isc-gobase"), getVersion())
+	if e == nil {
+		return g.Document()
+	}
+
+	registry := registryOf(e)
+	for _, r := range e.Routes() {
+		if isc.ListContains(registry.openApiPaths, r.Path) {
+			continue
+		}
+		route := openapi.Route{Method: r.Method, Path: r.Path}
+		if meta, ok := registry.routeMeta[routeKey(r.Method, r.Path)]; ok {
+			route.ReqType = meta.ReqType
+			route.RspType = meta.RspType
+		}
+		route.VersionParams, route.Deprecated = versionParamsOf(registry, r.Method, r.Path)
+		g.AddRoute(route)
+	}
+	return g.Document()
+}
+
+// versionParamsOf 路由区分版本时，返回版本参数以及是否所有版本都已废弃
+func versionParamsOf(registry *engineRegistry, method string, path string) ([]openapi.VersionParam, bool) {
+	var params []openapi.VersionParam
+	deprecated := false
+	for _, ap := range registry.apiPaths {
+		if ap.Path != path || !isc.ListContains(httpMethodNames(ap.Method), method) {
+			continue
+		}
+		deprecated = len(ap.Versions) != 0
+		for _, av := range ap.Versions {
+			deprecated = deprecated && av.Deprecated
+			for _, condition := range av.Conditions {
+				params = addVersionParam(params, versionParamIn(condition.Source), condition.Name, condition.Range.Express)
+			}
+		}
+	}
+	return params, deprecated
+}
+
+func addVersionParam(params []openapi.VersionParam, in string, name string, value string) []openapi.VersionParam {
+	for i := range params {
+		if params[i].In == in && params[i].Name == name {
+			if !isc.ListContains(params[i].Values, value) {
+				params[i].Values = append(params[i].Values, value)
+			}
+			return params
+		}
+	}
+	return append(params, openapi.VersionParam{In: in, Name: name, Values: []string{value}})
+}
+
+func versionParamIn(source VersionSource) string {
+	switch source {
+	case VsQuery:
+		return "query"
+	case VsPath:
+		return "path"
+	default:
+		return "header"
+	}
+}
+
+const swaggerUiHtml = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8"/>
+  <title>%s</title>
+  <link rel="stylesheet" href="%s/swagger-ui.css"/>
+  <link rel="icon" type="image/png" href="%s/favicon-32x32.png"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="%s/swagger-ui-bundle.js"></script>
+<script>
+  window.ui = SwaggerUIBundle({url: "%s", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>`
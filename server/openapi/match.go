@@ -0,0 +1,208 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isyscore/isc-gobase/validate/constant"
+)
+
+// 可以转换为schema约束的validate标签
+var matchTags = []string{constant.Value, constant.IsBlank, constant.IsUnBlank, constant.Range, constant.Model, constant.Condition, constant.Regex, constant.Customize}
+
+var rangeRegex = regexp.MustCompile(`^([(\[])\s*([^,，]*)\s*[,，]\s*([^,，]*)\s*([)\]])$`)
+
+// applyMatch 将validate的match标签转换为schema的约束，返回该属性是否必填
+// validate中多个匹配是"或"的关系，因此多个约束时使用anyOf
+func applyMatch(schema *Schema, field reflect.StructField) bool {
+	tagMatch := field.Tag.Get(constant.MATCH)
+	if tagMatch == "" || schema.Ref != "" || field.Tag.Get(constant.Accept) == "false" || field.Tag.Get(constant.Disable) == "true" {
+		return false
+	}
+
+	var subs []*Schema
+	required := true
+	for _, judge := range splitMatch(tagMatch) {
+		sub, subRequired, ok := matchSchema(schema, judge)
+		if !ok {
+			// 存在无法转换的匹配（比如条件表达式），则不再添加约束
+			return false
+		}
+		subs = append(subs, sub)
+		required = required && subRequired
+	}
+
+	switch len(subs) {
+	case 0:
+		return false
+	case 1:
+		mergeSchema(schema, subs[0])
+	default:
+		schema.AnyOf = subs
+	}
+	return required
+}
+
+// splitMatch 按照validate的处理方式，将match拆分为多个匹配
+func splitMatch(tagMatch string) []string {
+	var indexes []int
+	for _, tag := range matchTags {
+		if index := strings.Index(tagMatch, tag); index != -1 {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	var judges []string
+	lastIndex := 0
+	for _, index := range indexes {
+		if index == lastIndex {
+			continue
+		}
+		judges = append(judges, strings.TrimSpace(tagMatch[lastIndex:index]))
+		lastIndex = index
+	}
+	return append(judges, strings.TrimSpace(tagMatch[lastIndex:]))
+}
+
+func matchSchema(schema *Schema, judge string) (*Schema, bool, bool) {
+	// isBlank、isUnBlank 可以不带值，与validate一致，相当于值为true
+	name, value := strings.TrimSpace(judge), "true"
+	if index := strings.Index(judge, constant.EQUAL); index != -1 {
+		name, value = strings.TrimSpace(judge[:index]), strings.TrimSpace(judge[index+1:])
+	} else if name != constant.IsBlank && name != constant.IsUnBlank {
+		return nil, false, false
+	}
+
+	sub := &Schema{}
+	switch name {
+	case constant.IsBlank, constant.IsUnBlank:
+		notBlank := (name == constant.IsBlank) == strings.EqualFold(value, "false")
+		if notBlank {
+			sub.MinLength = int64Ptr(1)
+		} else {
+			sub.MaxLength = int64Ptr(0)
+		}
+		return sub, notBlank, true
+	case constant.Value:
+		if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+			for _, v := range strings.Split(value[1:len(value)-1], ",") {
+				sub.Enum = append(sub.Enum, castValue(schema.Type, strings.TrimSpace(v)))
+			}
+		} else {
+			sub.Enum = []any{castValue(schema.Type, value)}
+		}
+		return sub, false, true
+	case constant.Regex:
+		sub.Pattern = value
+		return sub, false, true
+	case constant.Range:
+		return sub, false, applyRange(schema, sub, value)
+	}
+	return nil, false, false
+}
+
+func applyRange(schema *Schema, sub *Schema, express string) bool {
+	m := rangeRegex.FindStringSubmatch(express)
+	if m == nil {
+		return false
+	}
+	leftEqual, begin, end, rightEqual := m[1] == constant.LeftEqual, m[2], m[3], m[4] == constant.RightEqual
+
+	var beginNum, endNum *float64
+	if begin != "" {
+		v, err := strconv.ParseFloat(begin, 64)
+		if err != nil {
+			// 时间等范围不转换
+			return false
+		}
+		beginNum = &v
+	}
+	if end != "" {
+		v, err := strconv.ParseFloat(end, 64)
+		if err != nil {
+			return false
+		}
+		endNum = &v
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		sub.Minimum, sub.ExclusiveMinimum = beginNum, beginNum != nil && !leftEqual
+		sub.Maximum, sub.ExclusiveMaximum = endNum, endNum != nil && !rightEqual
+	case "string":
+		sub.MinLength, sub.MaxLength = lengthBound(beginNum, leftEqual, 1), lengthBound(endNum, rightEqual, -1)
+	case "array":
+		sub.MinItems, sub.MaxItems = lengthBound(beginNum, leftEqual, 1), lengthBound(endNum, rightEqual, -1)
+	default:
+		return false
+	}
+	return true
+}
+
+// lengthBound 长度只能是闭区间，开区间时向内调整
+func lengthBound(v *float64, equal bool, step int64) *int64 {
+	if v == nil {
+		return nil
+	}
+	n := int64(*v)
+	if !equal {
+		n += step
+	}
+	if n < 0 {
+		n = 0
+	}
+	return &n
+}
+
+func castValue(schemaType string, value string) any {
+	switch schemaType {
+	case "integer":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+func mergeSchema(schema *Schema, sub *Schema) {
+	if sub.Enum != nil {
+		schema.Enum = sub.Enum
+	}
+	if sub.Pattern != "" {
+		schema.Pattern = sub.Pattern
+	}
+	if sub.Minimum != nil {
+		schema.Minimum, schema.ExclusiveMinimum = sub.Minimum, sub.ExclusiveMinimum
+	}
+	if sub.Maximum != nil {
+		schema.Maximum, schema.ExclusiveMaximum = sub.Maximum, sub.ExclusiveMaximum
+	}
+	if sub.MinLength != nil {
+		schema.MinLength = sub.MinLength
+	}
+	if sub.MaxLength != nil {
+		schema.MaxLength = sub.MaxLength
+	}
+	if sub.MinItems != nil {
+		schema.MinItems = sub.MinItems
+	}
+	if sub.MaxItems != nil {
+		schema.MaxItems = sub.MaxItems
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem key为小写的http方法，比如：get、post
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path、query、header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+	MinItems             *int64             `json:"minItems,omitempty"`
+	MaxItems             *int64             `json:"maxItems,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Route 用于生成文档的路由信息
+type Route struct {
+	Method     string       // http方法，比如：GET
+	Path       string       // gin格式的路径，比如：/api/data/:id
+	ReqType    reflect.Type // 请求类型，没有则为nil
+	RspType    reflect.Type // 响应类型，没有则为nil
+	Deprecated bool
+	// 版本参数
+	VersionParams []VersionParam
+}
+
+// VersionParam 版本参数：In 为 path、query 或者 header，Values 为可选的版本（或版本范围）
+type VersionParam struct {
+	In     string
+	Name   string
+	Values []string
+}
+
+type Generator struct {
+	doc     *Document
+	schemas *schemaBuilder
+}
+
+func NewGenerator(title, version string) *Generator {
+	schemas := newSchemaBuilder()
+	return &Generator{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       Info{Title: title, Version: version},
+			Paths:      map[string]*PathItem{},
+			Components: &Components{Schemas: schemas.components},
+		},
+		schemas: schemas,
+	}
+}
+
+func (g *Generator) AddRoute(route Route) {
+	path := ToOpenApiPath(route.Path)
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+
+	method := strings.ToLower(route.Method)
+	op := &Operation{
+		OperationId: operationId(method, route.Path),
+		Deprecated:  route.Deprecated,
+		Responses:   map[string]*Response{},
+	}
+
+	op.Parameters = append(op.Parameters, g.parameters(route)...)
+	if route.ReqType != nil && allowBody(route.Method) {
+		if body := g.schemas.bodySchema(route.ReqType); body != nil {
+			op.RequestBody = &RequestBody{
+				Content: map[string]*MediaType{"application/json": {Schema: body}},
+			}
+		}
+	}
+
+	rsp := &Response{Description: "OK"}
+	if route.RspType != nil {
+		rsp.Content = map[string]*MediaType{"application/json": {Schema: g.schemas.schemaOf(route.RspType)}}
+	}
+	op.Responses["200"] = rsp
+
+	(*item)[method] = op
+}
+
+func (g *Generator) Document() *Document {
+	if g.doc.Components != nil && len(g.doc.Components.Schemas) == 0 {
+		g.doc.Components = nil
+	}
+	return g.doc
+}
+
+func (g *Generator) parameters(route Route) []*Parameter {
+	var params []*Parameter
+	exist := map[string]bool{}
+	if route.ReqType != nil {
+		for _, p := range g.schemas.parameters(route.ReqType) {
+			params = append(params, p)
+			exist[p.In+":"+p.Name] = true
+		}
+	}
+
+	for _, vp := range route.VersionParams {
+		if exist[vp.In+":"+vp.Name] {
+			continue
+		}
+		exist[vp.In+":"+vp.Name] = true
+		schema := &Schema{Type: "string"}
+		for _, v := range vp.Values {
+			schema.Enum = append(schema.Enum, v)
+		}
+		params = append(params, &Parameter{Name: vp.Name, In: vp.In, Description: "api版本", Required: vp.In == "path", Schema: schema})
+	}
+
+	// 路径中的参数必须声明
+	for _, name := range PathParams(route.Path) {
+		if !exist["path:"+name] {
+			params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return params
+}
+
+var ginParamRegex = regexp.MustCompile(`[:*]([^/]+)`)
+
+// ToOpenApiPath 将gin的路径转换为openapi的路径，比如：/data/:id 转换为 /data/{id}
+func ToOpenApiPath(path string) string {
+	return ginParamRegex.ReplaceAllString(path, "{$1}")
+}
+
+// PathParams 返回gin路径中的参数名
+func PathParams(path string) []string {
+	var names []string
+	for _, m := range ginParamRegex.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+var operationIdRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func operationId(method, path string) string {
+	return method + "_" + strings.Trim(operationIdRegex.ReplaceAllString(path, "_"), "_")
+}
+
+func allowBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
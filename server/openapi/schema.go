@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// 参数的tag和参数位置
+var parameterTags = [][2]string{{"uri", "path"}, {"form", "query"}, {"header", "header"}}
+
+type schemaBuilder struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// schemaOf 返回类型对应的schema，具名的结构体放到components中，返回引用
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t, nil)
+		}
+		return &Schema{Ref: "#/components/schemas/" + b.componentName(t)}
+	default:
+		// interface等类型，不做约束
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := typeName(t)
+	for i := 2; ; i++ {
+		if _, exist := b.components[name]; !exist {
+			break
+		}
+		name = typeName(t) + "_" + strconv.Itoa(i)
+	}
+	b.names[t] = name
+	// 先占位，防止递归类型死循环
+	b.components[name] = &Schema{}
+	*b.components[name] = *b.structSchema(t, nil)
+	return name
+}
+
+var pkgPathRegex = regexp.MustCompile(`(?:[\w\-]+[./])+(\w+)`)
+var typeNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// typeName 生成component的名字，泛型比如 DataResponse[xxx/pojo.User] 转换为 DataResponse_User
+func typeName(t reflect.Type) string {
+	name := pkgPathRegex.ReplaceAllString(t.Name(), "$1")
+	return strings.Trim(typeNameRegex.ReplaceAllString(name, "_"), "_")
+}
+
+// structSchema 结构体的schema，filter不为空时只保留filter返回true的属性
+func (b *schemaBuilder) structSchema(t reflect.Type, filter func(field reflect.StructField) bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.collectFields(t, schema, filter)
+	return schema
+}
+
+func (b *schemaBuilder) collectFields(t reflect.Type, schema *Schema, filter func(field reflect.StructField) bool) {
+	for i, num := 0, t.NumField(); i < num; i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		// 匿名的结构体，属性平铺
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			b.collectFields(fieldType, schema, filter)
+			continue
+		}
+
+		if filter != nil && !filter(field) {
+			continue
+		}
+
+		fieldSchema := b.schemaOf(field.Type)
+		required := applyMatch(fieldSchema, field)
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// bodySchema 请求body的schema：去掉只用于路径、查询和请求头的属性
+func (b *schemaBuilder) bodySchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return b.schemaOf(t)
+	}
+
+	schema := b.structSchema(t, func(field reflect.StructField) bool {
+		return !isParameterField(field) || field.Tag.Get("json") != ""
+	})
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+	return schema
+}
+
+// parameters 结构体中带有 uri、form、header 的属性转换为参数
+func (b *schemaBuilder) parameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*Parameter
+	for i, num := 0, t.NumField(); i < num; i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			params = append(params, b.parameters(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		for _, pt := range parameterTags {
+			in := pt[1]
+			name := strings.Split(field.Tag.Get(pt[0]), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			schema := b.schemaOf(field.Type)
+			required := applyMatch(schema, field)
+			params = append(params, &Parameter{Name: name, In: in, Required: required || in == "path", Schema: schema})
+		}
+	}
+	return params
+}
+
+func isParameterField(field reflect.StructField) bool {
+	for _, pt := range parameterTags {
+		if _, ok := field.Tag.Lookup(pt[0]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
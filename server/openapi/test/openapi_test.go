@@ -0,0 +1,88 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/isyscore/isc-gobase/server/openapi"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/magiconair/properties/assert"
+)
+
+type UserReq struct {
+	Id     int64  `uri:"id"`
+	Token  string `header:"token" match:"isBlank=false"`
+	Name   string `json:"name" match:"range=[2,10]"`
+	Age    int    `json:"age" match:"range=(0,200]"`
+	Gender string `json:"gender" match:"value={male, female}"`
+	Mail   string `json:"mail" match:"regex=^.+@.+$"`
+	Remark string `json:"remark" match:"isBlank=true value=zhou"`
+	Alias  string `json:"alias" match:"isBlank"`
+}
+
+type UserRsp struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func TestToOpenApiPath(t *testing.T) {
+	assert.Equal(t, openapi.ToOpenApiPath("/api/user/:id/*path"), "/api/user/{id}/{path}")
+	assert.Equal(t, openapi.PathParams("/api/user/:id/*path"), []string{"id", "path"})
+}
+
+func TestGenerator(t *testing.T) {
+	g := openapi.NewGenerator("sample", "1.0.0")
+	g.AddRoute(openapi.Route{
+		Method:  "POST",
+		Path:    "/api/user/:id",
+		ReqType: reflect.TypeOf(UserReq{}),
+		RspType: reflect.TypeOf(rsp.DataResponse[UserRsp]{}),
+	})
+	g.AddRoute(openapi.Route{
+		Method:        "GET",
+		Path:          "/api/user/:id",
+		VersionParams: []openapi.VersionParam{{In: "header", Name: "isc-api-version", Values: []string{"1.0", "2.0"}}},
+	})
+	g.AddRoute(openapi.Route{
+		Method:  "GET",
+		Path:    "/api/users",
+		RspType: reflect.TypeOf(rsp.PagedResponse[UserRsp]{}),
+	})
+	doc := g.Document()
+
+	post := (*doc.Paths["/api/user/{id}"])["post"]
+	assert.Equal(t, len(post.Parameters), 2)
+	assert.Equal(t, post.Parameters[0].In, "path")
+	assert.Equal(t, post.Parameters[0].Schema.Format, "int64")
+	assert.Equal(t, post.Parameters[1].In, "header")
+	assert.Equal(t, post.Parameters[1].Required, true)
+
+	body := post.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, len(body.Properties), 6)
+	assert.Equal(t, *body.Properties["name"].MinLength, int64(2))
+	assert.Equal(t, *body.Properties["name"].MaxLength, int64(10))
+	assert.Equal(t, *body.Properties["age"].Minimum, float64(0))
+	assert.Equal(t, body.Properties["age"].ExclusiveMinimum, true)
+	assert.Equal(t, *body.Properties["age"].Maximum, float64(200))
+	assert.Equal(t, body.Properties["age"].ExclusiveMaximum, false)
+	assert.Equal(t, body.Properties["gender"].Enum, []any{"male", "female"})
+	assert.Equal(t, body.Properties["mail"].Pattern, "^.+@.+$")
+	assert.Equal(t, len(body.Properties["remark"].AnyOf), 2)
+	// 不带值的isBlank相当于isBlank=true
+	assert.Equal(t, *body.Properties["alias"].MaxLength, int64(0))
+
+	ref := post.Responses["200"].Content["application/json"].Schema.Ref
+	assert.Equal(t, ref, "#/components/schemas/DataResponse_UserRsp")
+	dataRsp := doc.Components.Schemas["DataResponse_UserRsp"]
+	assert.Equal(t, dataRsp.Properties["code"].Type, "integer")
+	assert.Equal(t, dataRsp.Properties["data"].Ref, "#/components/schemas/UserRsp")
+	assert.Equal(t, doc.Components.Schemas["UserRsp"].Properties["roles"].Items.Type, "string")
+
+	get := (*doc.Paths["/api/user/{id}"])["get"]
+	assert.Equal(t, len(get.Parameters), 2)
+	assert.Equal(t, get.Parameters[0].Schema.Enum, []any{"1.0", "2.0"})
+
+	paged := doc.Components.Schemas["PagedResponse_UserRsp"]
+	assert.Equal(t, paged.Properties["data"].Ref, "#/components/schemas/PagedData_UserRsp")
+	assert.Equal(t, doc.Components.Schemas["PagedData_UserRsp"].Properties["records"].Items.Ref, "#/components/schemas/UserRsp")
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/isyscore/isc-gobase/cache"
+)
+
+const lockShards = 64
+
+// store 进程内的存储，使用cache包
+type store interface {
+	Get(key string) (any, bool)
+	Set(key string, value any) error
+}
+
+type memoryLimiter struct {
+	rule  Rule
+	store store
+	locks [lockShards]sync.Mutex
+	now   func() time.Time
+}
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+type windowState struct {
+	start    time.Time
+	previous int64
+	current  int64
+}
+
+// NewMemoryLimiter 创建进程内的限流器，状态保存在cache中，空闲的key会过期清理
+func NewMemoryLimiter(rule Rule) Limiter {
+	rule = rule.normalize()
+	return &memoryLimiter{
+		rule:  rule,
+		store: cache.NewWithExpiration(2 * idleTtl(rule)),
+		now:   time.Now,
+	}
+}
+
+// idleTtl key空闲多久后状态可以丢弃：令牌桶填满的时间或者两个窗口
+func idleTtl(rule Rule) time.Duration {
+	if rule.Algorithm == SlidingWindow {
+		return 2 * rule.Period
+	}
+	return time.Duration(float64(rule.Period) * float64(rule.Burst) / float64(rule.Rate))
+}
+
+func (m *memoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	lock := &m.locks[shardOf(key)]
+	lock.Lock()
+	defer lock.Unlock()
+
+	if m.rule.Algorithm == SlidingWindow {
+		return m.allowWindow(key), nil
+	}
+	return m.allowBucket(key), nil
+}
+
+func (m *memoryLimiter) allowBucket(key string) Result {
+	now := m.now()
+	rule := m.rule
+	state, _ := m.load(key).(*bucketState)
+	if state == nil {
+		state = &bucketState{tokens: float64(rule.Burst), last: now}
+	}
+
+	elapsed := now.Sub(state.last)
+	if elapsed > 0 {
+		state.tokens = math.Min(float64(rule.Burst), state.tokens+float64(elapsed)*float64(rule.Rate)/float64(rule.Period))
+		state.last = now
+	}
+
+	result := Result{Limit: rule.Burst}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - state.tokens) * float64(rule.Period) / float64(rule.Rate))
+	}
+	result.Remaining = int64(state.tokens)
+	_ = m.store.Set(key, state)
+	return result
+}
+
+// allowWindow 滑动窗口：按照上个窗口在当前滑动窗口中的占比估算请求数
+func (m *memoryLimiter) allowWindow(key string) Result {
+	now := m.now()
+	rule := m.rule
+	windowStart := now.Truncate(rule.Period)
+	state, _ := m.load(key).(*windowState)
+	if state == nil {
+		state = &windowState{start: windowStart}
+	}
+	switch {
+	case windowStart.Sub(state.start) >= 2*rule.Period:
+		state.start, state.previous, state.current = windowStart, 0, 0
+	case windowStart.After(state.start):
+		state.start, state.previous, state.current = windowStart, state.current, 0
+	}
+
+	result := windowResult(rule, now.Sub(windowStart), state.previous, state.current)
+	if result.Allowed {
+		state.current++
+	}
+	_ = m.store.Set(key, state)
+	return result
+}
+
+// windowResult 计算滑动窗口是否允许请求，elapsed 为当前窗口已经过去的时间
+func windowResult(rule Rule, elapsed time.Duration, previous int64, current int64) Result {
+	weight := float64(rule.Period-elapsed) / float64(rule.Period)
+	count := float64(previous)*weight + float64(current)
+
+	result := Result{Limit: rule.Rate}
+	if count+1 <= float64(rule.Rate) {
+		result.Allowed = true
+		result.Remaining = int64(float64(rule.Rate) - count - 1)
+		return result
+	}
+
+	if current+1 > rule.Rate || previous == 0 {
+		// 当前窗口已满，需要等到下个窗口
+		result.RetryAfter = rule.Period - elapsed
+	} else {
+		// 上个窗口的占比下降后才能请求
+		wait := float64(rule.Period-elapsed) - float64(rule.Rate-current-1)*float64(rule.Period)/float64(previous)
+		result.RetryAfter = time.Duration(math.Max(wait, float64(time.Millisecond)))
+	}
+	return result
+}
+
+func (m *memoryLimiter) load(key string) any {
+	value, found := m.store.Get(key)
+	if !found {
+		return nil
+	}
+	return value
+}
+
+func shardOf(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % lockShards)
+}
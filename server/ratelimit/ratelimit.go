@@ -0,0 +1,267 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/redis"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+const (
+	TokenBucket   = "token-bucket"
+	SlidingWindow = "sliding-window"
+
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+
+	KeyIp     = "ip"
+	KeyRoute  = "route"
+	KeyHeader = "header:"
+)
+
+// Config base.server.ratelimit 配置
+type Config struct {
+	Enable bool
+	// 存储：memory（进程内）、redis（分布式），默认：memory
+	Store string
+	// redis中key的前缀，默认：isc:ratelimit:
+	KeyPrefix string
+
+	// 默认的限流规则，Rate为0则不限流
+	Algorithm string
+	Key       string
+	Rate      int64
+	Period    int64
+	Burst     int64
+
+	// 指定路由的限流规则，优先于默认规则
+	Routes []RouteConfig
+}
+
+// RouteConfig 指定路由的限流规则，没有配置的项使用默认规则的配置
+// Rate和Burst都没有配置时使用默认的Rate和Burst；只配置了Rate时Burst等于Rate；路由的计数与默认规则相互独立
+type RouteConfig struct {
+	// gin的路由路径，比如：/api/data/:id；以*结尾时按照前缀匹配
+	Path string
+	// http方法，为空则匹配全部
+	Method    string
+	Algorithm string
+	Key       string
+	Rate      int64
+	Period    int64
+	Burst     int64
+}
+
+// Rule 限流规则
+type Rule struct {
+	// 算法：token-bucket（令牌桶）、sliding-window（滑动窗口），默认：token-bucket
+	Algorithm string
+	// 每个周期允许的请求数
+	Rate int64
+	// 周期，默认：1秒
+	Period time.Duration
+	// 令牌桶的容量，默认等于Rate
+	Burst int64
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	RetryAfter time.Duration
+}
+
+// Limiter 限流器
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// KeyFunc 限流的key
+type KeyFunc func(c *gin.Context) string
+
+var keyFuncLock sync.RWMutex
+var keyFuncMap = map[string]KeyFunc{}
+
+// RegisterKeyFunc 注册自定义的key函数，配置中的key使用对应的名字即可
+func RegisterKeyFunc(name string, keyFunc KeyFunc) {
+	keyFuncLock.Lock()
+	defer keyFuncLock.Unlock()
+	keyFuncMap[name] = keyFunc
+}
+
+func getKeyFunc(name string) (KeyFunc, bool) {
+	keyFuncLock.RLock()
+	defer keyFuncLock.RUnlock()
+	keyFunc, exist := keyFuncMap[name]
+	return keyFunc, exist
+}
+
+func (r Rule) normalize() Rule {
+	if r.Algorithm == "" {
+		r.Algorithm = TokenBucket
+	}
+	if r.Period <= 0 {
+		r.Period = time.Second
+	}
+	if r.Burst <= 0 {
+		r.Burst = r.Rate
+	}
+	return r
+}
+
+type routeLimiter struct {
+	path    string
+	method  string
+	keyFunc KeyFunc
+	limiter Limiter
+}
+
+func (rl *routeLimiter) match(c *gin.Context) bool {
+	if rl.method != "" && !strings.EqualFold(rl.method, c.Request.Method) {
+		return false
+	}
+	if strings.HasSuffix(rl.path, "*") {
+		return strings.HasPrefix(c.Request.URL.Path, strings.TrimSuffix(rl.path, "*"))
+	}
+	return rl.path == c.FullPath()
+}
+
+// Middleware 按照配置 base.server.ratelimit 创建限流中间件
+func Middleware() gin.HandlerFunc {
+	cfg := Config{}
+	if err := config.GetValueObject("base.server.ratelimit", &cfg); err != nil {
+		logger.Warn("读取限流配置异常: %v", err)
+	}
+	return NewMiddleware(cfg)
+}
+
+// NewMiddleware 创建限流中间件：超过限制的请求返回429，并携带 Retry-After 头
+func NewMiddleware(cfg Config) gin.HandlerFunc {
+	newLimiter := func(name string, rule Rule) Limiter {
+		if cfg.Store == StoreRedis {
+			client, err := redis.GetClient()
+			if err != nil {
+				logger.Error("限流获取redis客户端失败，使用进程内限流: %v", err)
+				return NewMemoryLimiter(rule)
+			}
+			prefix := cfg.KeyPrefix
+			if prefix == "" {
+				prefix = "isc:ratelimit:"
+			}
+			return NewRedisLimiter(client, prefix+name+":", rule)
+		}
+		return NewMemoryLimiter(rule)
+	}
+
+	var limiters []*routeLimiter
+	for i, route := range cfg.Routes {
+		rule := Rule{
+			Algorithm: firstNotEmpty(route.Algorithm, cfg.Algorithm),
+			Rate:      route.Rate,
+			Period:    time.Duration(firstPositive(route.Period, cfg.Period)) * time.Millisecond,
+			Burst:     route.Burst,
+		}
+		if rule.Rate <= 0 {
+			rule.Rate = cfg.Rate
+			rule.Burst = firstPositive(route.Burst, cfg.Burst)
+		}
+		if rule.Rate <= 0 {
+			continue
+		}
+		limiters = append(limiters, &routeLimiter{
+			path:    route.Path,
+			method:  route.Method,
+			keyFunc: keyFuncOf(firstNotEmpty(route.Key, cfg.Key)),
+			limiter: newLimiter("route"+strconv.Itoa(i), rule),
+		})
+	}
+	if cfg.Rate > 0 {
+		rule := Rule{Algorithm: cfg.Algorithm, Rate: cfg.Rate, Period: time.Duration(cfg.Period) * time.Millisecond, Burst: cfg.Burst}
+		limiters = append(limiters, &routeLimiter{path: "*", keyFunc: keyFuncOf(cfg.Key), limiter: newLimiter("default", rule)})
+	}
+
+	return func(c *gin.Context) {
+		for _, rl := range limiters {
+			if !rl.match(c) {
+				continue
+			}
+			result, err := rl.limiter.Allow(c.Request.Context(), rl.keyFunc(c))
+			if err != nil {
+				// 限流异常时不拦截请求
+				logger.Warn("限流处理异常: %v", err)
+				break
+			}
+			c.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+			c.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+			if !result.Allowed {
+				c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(result.RetryAfter.Seconds())), 10))
+				rsp.FailedOfStatus(c, http.StatusTooManyRequests, "请求过于频繁，请稍后再试")
+				c.Abort()
+				return
+			}
+			break
+		}
+		c.Next()
+	}
+}
+
+// keyFuncOf 根据配置获取key函数：ip、route、header:xxx 或者注册的自定义函数名，默认：ip
+func keyFuncOf(key string) KeyFunc {
+	switch {
+	case key == "" || key == KeyIp:
+		return func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	case key == KeyRoute:
+		return func(c *gin.Context) string {
+			if path := c.FullPath(); path != "" {
+				return c.Request.Method + ":" + path
+			}
+			return c.Request.Method + ":" + c.Request.URL.Path
+		}
+	case strings.HasPrefix(key, KeyHeader):
+		header := strings.TrimPrefix(key, KeyHeader)
+		return func(c *gin.Context) string {
+			if value := c.GetHeader(header); value != "" {
+				return value
+			}
+			return c.ClientIP()
+		}
+	}
+	// 自定义函数可能在中间件创建之后才注册，因此在请求时再查找
+	return func(c *gin.Context) string {
+		if keyFunc, exist := getKeyFunc(key); exist {
+			return keyFunc(c)
+		}
+		logger.Warn("限流的key函数 %s 不存在，请先通过 RegisterKeyFunc 注册", key)
+		return c.ClientIP()
+	}
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstPositive(values ...int64) int64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
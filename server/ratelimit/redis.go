@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// redisNow 在脚本中使用redis服务端的时间(毫秒)，避免多个实例之间的时钟偏差破坏共享的限流状态；
+// 低版本redis需要开启命令复制后才允许在TIME之后写入
+const redisNow = `
+if redis.replicate_commands then
+  redis.replicate_commands()
+end
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+// 令牌桶：KEYS[1] 桶；ARGV：rate、period(毫秒)、burst、过期时间(毫秒)
+// 返回：是否允许、剩余令牌、需要等待的毫秒数
+var tokenBucketScript = goredis.NewScript(redisNow + `
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate / period)
+  ts = now
+end
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * period / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, math.floor(tokens), retry}
+`)
+
+// 滑动窗口：KEYS[1] 当前窗口计数，KEYS[2] 上个窗口计数；ARGV：rate、period(毫秒)、当前窗口已过去的毫秒数
+// 返回：是否允许、上个窗口计数、当前窗口计数
+var slidingWindowScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local count = previous * (period - elapsed) / period + current
+if count + 1 > rate then
+  return {0, previous, current}
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], period * 2)
+return {1, previous, current}
+`)
+
+type redisLimiter struct {
+	client goredis.UniversalClient
+	prefix string
+	rule   Rule
+}
+
+// NewRedisLimiter 创建基于redis的分布式限流器，通过lua脚本保证原子性，时间以redis服务端为准；
+// 脚本访问的key都通过KEYS声明，兼容集群以及ACL的key权限
+func NewRedisLimiter(client goredis.UniversalClient, prefix string, rule Rule) Limiter {
+	return &redisLimiter{client: client, prefix: prefix, rule: rule.normalize()}
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	if r.rule.Algorithm == SlidingWindow {
+		return r.allowWindow(ctx, key)
+	}
+	return r.allowBucket(ctx, key)
+}
+
+func (r *redisLimiter) allowBucket(ctx context.Context, key string) (Result, error) {
+	rule := r.rule
+	ttl := idleTtl(rule) + time.Second
+	values, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + key},
+		rule.Rate, rule.Period.Milliseconds(), rule.Burst, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      rule.Burst,
+		Remaining:  values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+func (r *redisLimiter) allowWindow(ctx context.Context, key string) (Result, error) {
+	rule := r.rule
+	// 窗口的key需要在KEYS中声明，先读取redis服务端的时间计算当前窗口
+	now, err := r.client.Time(ctx).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	period := rule.Period.Milliseconds()
+	window := now.UnixMilli() / period
+	elapsed := time.Duration(now.UnixMilli()-window*period) * time.Millisecond
+	// 使用hash tag，保证集群模式下两个key位于同一个slot
+	base := "{" + r.prefix + key + "}:"
+	keys := []string{base + strconv.FormatInt(window, 10), base + strconv.FormatInt(window-1, 10)}
+
+	values, err := slidingWindowScript.Run(ctx, r.client, keys, rule.Rate, period, elapsed.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return windowResult(rule, elapsed, values[1], values[2]), nil
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server/ratelimit"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Rule{Rate: 2, Period: time.Second})
+	ctx := context.Background()
+
+	r1, _ := limiter.Allow(ctx, "k")
+	r2, _ := limiter.Allow(ctx, "k")
+	r3, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r1.Allowed, true)
+	assert.Equal(t, r2.Allowed, true)
+	assert.Equal(t, r3.Allowed, false)
+	assert.Equal(t, r3.RetryAfter > 0 && r3.RetryAfter <= 500*time.Millisecond, true)
+
+	// 不同的key互不影响
+	r4, _ := limiter.Allow(ctx, "other")
+	assert.Equal(t, r4.Allowed, true)
+
+	time.Sleep(550 * time.Millisecond)
+	r5, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r5.Allowed, true)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Rule{Algorithm: ratelimit.SlidingWindow, Rate: 3, Period: 200 * time.Millisecond})
+	ctx := context.Background()
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if r, _ := limiter.Allow(ctx, "k"); r.Allowed {
+			allowed++
+		} else {
+			assert.Equal(t, r.RetryAfter > 0, true)
+		}
+	}
+	assert.Equal(t, allowed, 3)
+
+	// 两个窗口之后，计数清空
+	time.Sleep(400 * time.Millisecond)
+	r, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r.Allowed, true)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	ratelimit.RegisterKeyFunc("user", func(c *gin.Context) string {
+		return c.Query("user")
+	})
+	engine.Use(ratelimit.NewMiddleware(ratelimit.Config{
+		Rate:   100,
+		Period: 1000,
+		Routes: []ratelimit.RouteConfig{
+			{Path: "/limited", Method: "GET", Rate: 1, Period: 60000, Key: "user"},
+		},
+	}))
+	engine.GET("/limited", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	do := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	assert.Equal(t, do("/limited?user=a").Code, http.StatusOK)
+	w := do("/limited?user=a")
+	assert.Equal(t, w.Code, http.StatusTooManyRequests)
+	assert.Equal(t, w.Header().Get("Retry-After"), "60")
+	assert.Equal(t, w.Header().Get("X-RateLimit-Remaining"), "0")
+	assert.Equal(t, do("/limited?user=b").Code, http.StatusOK)
+}
+
+func TestMiddlewareInheritRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ratelimit.NewMiddleware(ratelimit.Config{
+		Rate:   2,
+		Period: 60000,
+		Key:    ratelimit.KeyRoute,
+		Routes: []ratelimit.RouteConfig{
+			// 没有配置rate、burst，使用默认的配置
+			{Path: "/inherit"},
+			{Path: "/burst", Rate: 1, Burst: 3},
+		},
+	}))
+	for _, path := range []string{"/inherit", "/burst", "/other"} {
+		engine.GET(path, func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+	}
+
+	do := func(url string) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, do("/inherit"), http.StatusOK)
+	assert.Equal(t, do("/inherit"), http.StatusOK)
+	assert.Equal(t, do("/inherit"), http.StatusTooManyRequests)
+	// 路由的规则单独计数
+	assert.Equal(t, do("/other"), http.StatusOK)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, do("/burst"), http.StatusOK)
+	}
+	assert.Equal(t, do("/burst"), http.StatusTooManyRequests)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/isyscore/isc-gobase/server/ratelimit"
+	"github.com/magiconair/properties/assert"
+)
+
+// newRedisLimiter 创建基于miniredis的限流器，返回的miniredis用于调整服务端的时间
+func newRedisLimiter(t *testing.T, rule ratelimit.Rule) (ratelimit.Limiter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.UnixMilli(1_700_000_000_000))
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return ratelimit.NewRedisLimiter(client, "limit:", rule), server
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	limiter, server := newRedisLimiter(t, ratelimit.Rule{Rate: 2, Period: time.Second})
+	ctx := context.Background()
+
+	r1, err := limiter.Allow(ctx, "k")
+	assert.Equal(t, err, nil)
+	r2, _ := limiter.Allow(ctx, "k")
+	r3, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r1.Allowed, true)
+	assert.Equal(t, r1.Remaining, int64(1))
+	assert.Equal(t, r2.Allowed, true)
+	assert.Equal(t, r3.Allowed, false)
+	assert.Equal(t, r3.RetryAfter, 500*time.Millisecond)
+
+	// 不同的key互不影响
+	r4, _ := limiter.Allow(ctx, "other")
+	assert.Equal(t, r4.Allowed, true)
+
+	// 按照redis服务端的时间补充令牌
+	server.SetTime(time.UnixMilli(1_700_000_000_550))
+	r5, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r5.Allowed, true)
+	r6, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r6.Allowed, false)
+}
+
+func TestRedisSlidingWindow(t *testing.T) {
+	limiter, server := newRedisLimiter(t, ratelimit.Rule{Algorithm: ratelimit.SlidingWindow, Rate: 3, Period: 200 * time.Millisecond})
+	ctx := context.Background()
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		r, err := limiter.Allow(ctx, "k")
+		assert.Equal(t, err, nil)
+		if r.Allowed {
+			allowed++
+		} else {
+			assert.Equal(t, r.RetryAfter > 0, true)
+		}
+	}
+	assert.Equal(t, allowed, 3)
+	// 窗口的key由redis服务端的时间计算
+	assert.Equal(t, server.Keys(), []string{"{limit:k}:8500000000"})
+
+	// 下个窗口开始时上个窗口的计数仍然占满
+	server.SetTime(time.UnixMilli(1_700_000_000_200))
+	r, _ := limiter.Allow(ctx, "k")
+	assert.Equal(t, r.Allowed, false)
+
+	// 两个窗口之后，计数清空
+	server.SetTime(time.UnixMilli(1_700_000_000_400))
+	r, _ = limiter.Allow(ctx, "k")
+	assert.Equal(t, r.Allowed, true)
+	assert.Equal(t, r.Remaining, int64(2))
+}
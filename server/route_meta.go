@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// routeMeta 路由的元数据，用于生成openapi文档
+type routeMeta struct {
+	ReqType reflect.Type
+	RspType reflect.Type
+}
+
+// routeKey 路由元数据的key：method + " " + path
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
+
+// httpMethodNames 返回HttpMethod对应的http方法
+func httpMethodNames(method HttpMethod) []string {
+	switch method {
+	case HmAll:
+		return []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodHead}
+	case HmGet:
+		return []string{http.MethodGet}
+	case HmPost:
+		return []string{http.MethodPost}
+	case HmPut:
+		return []string{http.MethodPut}
+	case HmDelete:
+		return []string{http.MethodDelete}
+	case HmOptions:
+		return []string{http.MethodOptions}
+	case HmHead:
+		return []string{http.MethodHead}
+	case HmGetPost:
+		return []string{http.MethodGet, http.MethodPost}
+	}
+	return nil
+}
+
+// setRouteTypes 记录路由的请求和响应类型，已经存在则不覆盖
+func setRouteTypes(path string, method HttpMethod, reqType reflect.Type, rspType reflect.Type) {
+	metas := currentRegistry().routeMeta
+	for _, m := range httpMethodNames(method) {
+		key := routeKey(m, path)
+		if _, exist := metas[key]; !exist {
+			metas[key] = &routeMeta{ReqType: reqType, RspType: rspType}
+		}
+	}
+}
+
+func typedRouteTypes[Req any, Rsp any]() (reflect.Type, reflect.Type) {
+	return reflect.TypeOf((*Req)(nil)).Elem(), reflect.TypeOf((*rsp.DataResponse[Rsp])(nil)).Elem()
+}
+
+func registerTypedRoute[Req any, Rsp any](path string, method HttpMethod, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	reqType, rspType := typedRouteTypes[Req, Rsp]()
+	setRouteTypes(path, method, reqType, rspType)
+	return RegisterRoute(path, method, Handle(handler))
+}
+
+func registerTypedRouteWithHeaders[Req any, Rsp any](path string, method HttpMethod, header []string, versionName []string, handler TypedHandler[Req, Rsp]) gin.IRoutes {
+	reqType, rspType := typedRouteTypes[Req, Rsp]()
+	setRouteTypes(path, method, reqType, rspType)
+	return RegisterRouteWithHeaders(path, method, header, versionName, Handle(handler))
+}
@@ -0,0 +1,54 @@
+package rsp
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/listener"
+)
+
+// configValue 根据配置生成的值，所有的handler共享，配置变更时重新生成，配置变更的监听只注册一次
+type configValue[T any] struct {
+	load  func() T
+	match func(key string) bool
+	once  sync.Once
+	value atomic.Value
+}
+
+// listen 第一次使用时生成值并注册配置变更的监听
+func (v *configValue[T]) listen() {
+	v.once.Do(func() {
+		v.value.Store(v.load())
+		listener.AddListener(listener.EventOfConfigChange, func(event listener.BaseEvent) {
+			if ev, ok := event.(listener.ConfigChangeEvent); ok && v.match(ev.Key) {
+				v.value.Store(v.load())
+			}
+		})
+	})
+}
+
+// getter 返回读取值的函数，用于创建handler，每次调用时按照当前的配置重新生成
+// 在 config.WithProperties 中调用时，使用覆盖的配置生成固定的值，不受全局配置变更的影响
+func (v *configValue[T]) getter() func() T {
+	if config.IsOverridden() {
+		value := v.load()
+		return func() T {
+			return value
+		}
+	}
+	v.listen()
+	v.value.Store(v.load())
+	return func() T {
+		return v.value.Load().(T)
+	}
+}
+
+// get 读取当前的值，用于请求中；在 config.WithProperties 中时按照覆盖的配置生成
+func (v *configValue[T]) get() T {
+	if config.IsOverridden() {
+		return v.load()
+	}
+	v.listen()
+	return v.value.Load().(T)
+}
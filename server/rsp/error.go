@@ -0,0 +1,26 @@
+package rsp
+
+import "fmt"
+
+const (
+	CodeSuccess       = 0
+	CodeBadRequest    = 400
+	CodeInternalError = 500
+)
+
+// MessageInternalError 非 CodeError 的错误返回给调用方的提示
+const MessageInternalError = "服务内部错误"
+
+// CodeError 携带业务码的错误，用于在处理函数中直接返回标准的失败结构
+type CodeError struct {
+	Code    int
+	Message string
+}
+
+func (e *CodeError) Error() string {
+	return fmt.Sprintf("code=%d, message=%s", e.Code, e.Message)
+}
+
+func NewCodeError(code int, message string) *CodeError {
+	return &CodeError{Code: code, Message: message}
+}
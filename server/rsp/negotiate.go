@@ -0,0 +1,399 @@
+package rsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/golang/protobuf/proto"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/isc"
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+// Encoder 响应的编码器
+type Encoder interface {
+	// ContentType 响应的Content-Type
+	ContentType() string
+	// Encode 编码响应；payload为响应中的业务数据，body为包含业务码的完整响应
+	// 编码器不支持该响应时返回 ErrEncoderUnsupported，使用默认格式返回；返回 ErrNotAcceptable 时响应406
+	Encode(w io.Writer, body any, payload any) error
+}
+
+// ErrEncoderUnsupported 编码器不支持该响应
+var ErrEncoderUnsupported = errors.New("编码器不支持该响应")
+
+// ErrNotAcceptable 请求的格式无法表示该响应，返回406，不使用默认格式
+var ErrNotAcceptable = errors.New("响应无法编码为请求的格式")
+
+// 内置的格式
+const (
+	FormatJSON     = "json"
+	FormatXML      = "xml"
+	FormatYAML     = "yaml"
+	FormatMsgPack  = "msgpack"
+	FormatProtobuf = "protobuf"
+)
+
+var encoderLock sync.RWMutex
+var encoders = map[string]string{}
+var encoderOfFormat = map[string]Encoder{}
+
+func init() {
+	RegisterEncoder(FormatJSON, jsonEncoder{}, "application/json", "text/json")
+	RegisterEncoder(FormatXML, xmlEncoder{}, "application/xml", "text/xml")
+	RegisterEncoder(FormatYAML, yamlEncoder{}, "application/yaml", "application/x-yaml", "text/yaml")
+	RegisterEncoder(FormatMsgPack, msgpackEncoder{}, "application/msgpack", "application/x-msgpack")
+	RegisterEncoder(FormatProtobuf, protobufEncoder{}, "application/x-protobuf", "application/protobuf")
+}
+
+// RegisterEncoder 注册编码器，mediaTypes为Accept中对应该编码器的类型，注册同名的格式会覆盖
+func RegisterEncoder(format string, encoder Encoder, mediaTypes ...string) {
+	encoderLock.Lock()
+	defer encoderLock.Unlock()
+	encoderOfFormat[format] = encoder
+	for _, mediaType := range mediaTypes {
+		encoders[strings.ToLower(mediaType)] = format
+	}
+}
+
+// negotiationConfig base.server.response.negotiation 配置
+type negotiationConfig struct {
+	enable        bool
+	defaultFormat string
+}
+
+var negotiationValue = &configValue[*negotiationConfig]{
+	load: func() *negotiationConfig {
+		return &negotiationConfig{
+			enable:        config.GetValueBoolDefault("base.server.response.negotiation.enable", false),
+			defaultFormat: config.GetValueStringDefault("base.server.response.negotiation.default", FormatJSON),
+		}
+	},
+	match: func(key string) bool {
+		return strings.HasPrefix(key, "base.server.response.negotiation")
+	},
+}
+
+// Negotiate 按照请求头Accept选择响应的格式
+// 没有开启 base.server.response.negotiation.enable 时总是返回json；Accept中没有支持的格式时返回默认格式
+func Negotiate(ctx *gin.Context) string {
+	cfg := negotiationValue.get()
+	if !cfg.enable {
+		return FormatJSON
+	}
+	return negotiateAccept(ctx.GetHeader("Accept"), cfg.defaultFormat)
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func negotiateAccept(accept string, defaultFormat string) string {
+	if accept == "" {
+		return defaultFormat
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	encoderLock.RLock()
+	defer encoderLock.RUnlock()
+	for _, r := range ranges {
+		if format, ok := encoders[r.mediaType]; ok {
+			return format
+		}
+		if r.mediaType == "*/*" {
+			return defaultFormat
+		}
+		if strings.HasSuffix(r.mediaType, "/*") {
+			// 默认格式优先
+			prefix := strings.TrimSuffix(r.mediaType, "*")
+			var matched []string
+			for mediaType, format := range encoders {
+				if strings.HasPrefix(mediaType, prefix) {
+					if format == defaultFormat {
+						return format
+					}
+					matched = append(matched, format)
+				}
+			}
+			if len(matched) != 0 {
+				sort.Strings(matched)
+				return matched[0]
+			}
+		}
+	}
+	return defaultFormat
+}
+
+// Render 按照协商的格式返回响应；payload为响应中的业务数据，用于protobuf等只编码业务数据的格式
+// 开启协商时所有的响应都携带 Vary: Accept，包括json以及使用默认格式的响应
+func Render(ctx *gin.Context, status int, body any, payload any) {
+	if negotiationValue.get().enable {
+		ctx.Writer.Header().Add("Vary", "Accept")
+	}
+	format := Negotiate(ctx)
+	if format == FormatJSON {
+		ctx.JSON(status, body)
+		return
+	}
+
+	encoderLock.RLock()
+	encoder, ok := encoderOfFormat[format]
+	encoderLock.RUnlock()
+	if !ok {
+		ctx.JSON(status, body)
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err := encoder.Encode(&buffer, body, payload); err != nil {
+		if errors.Is(err, ErrNotAcceptable) {
+			message := fmt.Sprintf("响应无法编码为%s格式", format)
+			renderDefault(ctx, http.StatusNotAcceptable, map[string]any{"code": http.StatusNotAcceptable, "message": message, "data": nil}, nil, format)
+			return
+		}
+		if !errors.Is(err, ErrEncoderUnsupported) {
+			logger.Warn("响应编码为%s失败：%v", format, err)
+		}
+		renderDefault(ctx, status, body, payload, format)
+		return
+	}
+	ctx.Data(status, encoder.ContentType(), buffer.Bytes())
+}
+
+// renderDefault 编码失败时使用默认格式，默认格式也失败则使用json
+func renderDefault(ctx *gin.Context, status int, body any, payload any, failedFormat string) {
+	defaultFormat := negotiationValue.get().defaultFormat
+	encoderLock.RLock()
+	encoder, ok := encoderOfFormat[defaultFormat]
+	encoderLock.RUnlock()
+	if ok && defaultFormat != failedFormat && defaultFormat != FormatJSON {
+		var buffer bytes.Buffer
+		if err := encoder.Encode(&buffer, body, payload); err == nil {
+			ctx.Data(status, encoder.ContentType(), buffer.Bytes())
+			return
+		}
+	}
+	ctx.JSON(status, body)
+}
+
+// normalize 通过json转换为map、slice以及基本类型，使各个格式的字段名与json保持一致
+func normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertNumber(value), nil
+}
+
+func convertNumber(v any) any {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]any:
+		for key, item := range value {
+			value[key] = convertNumber(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = convertNumber(item)
+		}
+	}
+	return v
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jsonEncoder) Encode(w io.Writer, body any, _ any) error {
+	return json.NewEncoder(w).Encode(body)
+}
+
+type yamlEncoder struct{}
+
+func (yamlEncoder) ContentType() string {
+	return "application/yaml; charset=utf-8"
+}
+
+func (yamlEncoder) Encode(w io.Writer, body any, _ any) error {
+	value, err := normalize(body)
+	if err != nil {
+		return err
+	}
+	text, err := isc.ObjectToYaml(value)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, text)
+	return err
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string {
+	return "application/msgpack"
+}
+
+func (msgpackEncoder) Encode(w io.Writer, body any, _ any) error {
+	value, err := normalize(body)
+	if err != nil {
+		return err
+	}
+	return render.WriteMsgPack(&headerlessWriter{Writer: w}, value)
+}
+
+// headerlessWriter 适配 render.WriteMsgPack 需要的 http.ResponseWriter
+type headerlessWriter struct {
+	io.Writer
+	header http.Header
+}
+
+func (w *headerlessWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *headerlessWriter) WriteHeader(int) {}
+
+type protobufEncoder struct{}
+
+func (protobufEncoder) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// Encode 只编码业务数据：没有业务数据（例如失败的响应）时使用默认格式；
+// 业务数据不是protobuf消息（例如分页数据 PagedData）时无法表示，返回406
+func (protobufEncoder) Encode(w io.Writer, _ any, payload any) error {
+	if payload == nil {
+		return ErrEncoderUnsupported
+	}
+	message, ok := payload.(proto.Message)
+	if !ok {
+		return ErrNotAcceptable
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+// Encode 根节点为response，对象的字段为子节点，数组的元素为item节点
+func (xmlEncoder) Encode(w io.Writer, body any, _ any) error {
+	value, err := normalize(body)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err = encodeXmlElement(encoder, "response", value); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func encodeXmlElement(encoder *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := encodeXmlElement(encoder, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := encodeXmlElement(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(isc.ToString(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlName 将不能作为节点名的字符替换为下划线
+func xmlName(name string) string {
+	var sb strings.Builder
+	for i, ch := range name {
+		valid := ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch > 0x7f
+		if i > 0 {
+			valid = valid || ch == '-' || ch == '.' || (ch >= '0' && ch <= '9')
+		}
+		if valid {
+			sb.WriteRune(ch)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_"
+	}
+	return sb.String()
+}
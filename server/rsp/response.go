@@ -1,9 +1,11 @@
 package rsp
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/logger"
 )
 
 type ResponseBase struct {
@@ -35,30 +37,75 @@ type PagedResponse[T any] struct {
 	Data PagedData[T] `json:"data"`
 }
 
+// CursorData 游标（keyset）分页的数据，通过 NextCursor 获取下一页
+type CursorData[T any] struct {
+	Size       int64  `json:"size"`
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore"`
+	Records    []T    `json:"records"`
+}
+
 func Success(ctx *gin.Context, object any) {
-	ctx.JSON(http.StatusOK, object)
+	Render(ctx, http.StatusOK, object, object)
 }
 
 func SuccessOfStandard(ctx *gin.Context, v any) {
-	ctx.JSON(http.StatusOK, map[string]any{
+	Render(ctx, http.StatusOK, map[string]any{
 		"code":    0,
 		"message": "success",
 		"data":    v,
-	})
+	}, v)
 }
 
 func FailedOfStandard(ctx *gin.Context, code int, message string) {
-	ctx.JSON(http.StatusOK, map[string]any{
+	Render(ctx, http.StatusOK, map[string]any{
 		"code":    code,
 		"message": message,
 		"data":    nil,
-	})
+	}, nil)
+}
+
+// FailedOfStatus 以http状态码返回标准的失败结构，业务码与http状态码相同
+func FailedOfStatus(ctx *gin.Context, status int, message string) {
+	Render(ctx, status, map[string]any{
+		"code":    status,
+		"message": message,
+		"data":    nil,
+	}, nil)
 }
 
 func FailedWithDataOfStandard(ctx *gin.Context, code string, message string, v any) {
-	ctx.JSON(http.StatusOK, map[string]any{
+	Render(ctx, http.StatusOK, map[string]any{
 		"code":    code,
 		"message": message,
 		"data":    v,
-	})
+	}, v)
+}
+
+// SuccessOfData 以 DataResponse[T] 的结构返回成功的数据
+func SuccessOfData[T any](ctx *gin.Context, v T) {
+	Render(ctx, http.StatusOK, DataResponse[T]{
+		ResponseBase: ResponseBase{Code: CodeSuccess, Message: "success"},
+		Data:         v,
+	}, v)
+}
+
+// SuccessOfPaged 返回分页数据，结构为 PagedResponse
+func SuccessOfPaged[T any](ctx *gin.Context, data PagedData[T]) {
+	Render(ctx, http.StatusOK, PagedResponse[T]{
+		ResponseBase: ResponseBase{Code: CodeSuccess, Message: "success"},
+		Data:         data,
+	}, data)
+}
+
+// FailedOfError 将错误转换为标准的失败结构；CodeError 使用其业务码，
+// 其他错误（比如数据库错误）只打印日志，响应使用 500 以及通用的提示，不把错误内容返回给调用方
+func FailedOfError(ctx *gin.Context, err error) {
+	var codeErr *CodeError
+	if errors.As(err, &codeErr) {
+		FailedOfStandard(ctx, codeErr.Code, codeErr.Message)
+		return
+	}
+	logger.Error("请求 %s %s 处理异常：%v", ctx.Request.Method, ctx.Request.URL.Path, err)
+	FailedOfStandard(ctx, CodeInternalError, MessageInternalError)
 }
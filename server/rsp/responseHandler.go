@@ -3,178 +3,441 @@ package rsp
 import (
 	"bytes"
 	"encoding/json"
-	"github.com/isyscore/isc-gobase/config"
-	"io/ioutil"
+	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/isyscore/isc-gobase/isc"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/goid"
 	"github.com/isyscore/isc-gobase/logger"
 )
 
-type bodyLogWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
+const (
+	AccessLogFormatJson   = "json"
+	AccessLogFormatApache = "apache"
+)
+
+// TraceIdKey traceId在gin上下文中的key，由server.Trace()设置
+const TraceIdKey = "isc-trace-id"
+
+// AccessLogConfig base.server.access-log 配置
+type AccessLogConfig struct {
+	Enable bool
+	// 格式：json、apache，默认：json
+	Format string
+	// 采样率：0~1，默认：1；异常的请求不采样，全部打印
+	SampleRate *float64
+	// 打印的路由，为空则全部打印；以*结尾时按照前缀匹配
+	Include []string
+	// 不打印的路由，优先于include
+	Exclude []string
+	// 不打印的状态码
+	ExcludeStatus []int
+	// 是否只打印异常：状态码大于等于400，或者json响应的业务码不是0、200
+	OnlyError bool
+	// 需要打印的请求头
+	Headers []string
+	Body    AccessLogBodyConfig
 }
 
-func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
-	return w.ResponseWriter.Write(b)
+// AccessLogBodyConfig base.server.access-log.body 配置
+type AccessLogBodyConfig struct {
+	// 是否打印请求体和响应体
+	Request  bool
+	Response bool
+	// 打印body的路由，为空则全部打印；以*结尾时按照前缀匹配
+	Include []string
+	// body打印的最大字节数，超过的部分截断，默认：4096
+	MaxSize int
 }
 
+// AccessLog 一条访问日志
+type AccessLog struct {
+	Time         string            `json:"time"`
+	Method       string            `json:"method"`
+	Uri          string            `json:"uri"`
+	Route        string            `json:"route,omitempty"`
+	Proto        string            `json:"proto"`
+	Status       int               `json:"status"`
+	Size         int               `json:"size"`
+	Cost         float64           `json:"cost"`
+	Ip           string            `json:"ip"`
+	User         string            `json:"user,omitempty"`
+	UserAgent    string            `json:"userAgent,omitempty"`
+	Referer      string            `json:"referer,omitempty"`
+	TraceId      string            `json:"traceId,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	RequestBody  any               `json:"requestBody,omitempty"`
+	ResponseBody any               `json:"responseBody,omitempty"`
+
+	start time.Time
+}
+
+// AccessLogSink 访问日志的输出，line为按照格式化后的内容
+type AccessLogSink func(entry *AccessLog, line string)
+
+type accessLogPolicy struct {
+	enable        bool
+	format        string
+	sampleRate    float64
+	include       []string
+	exclude       []string
+	excludeStatus map[int]bool
+	onlyError     bool
+	headers       []string
+	requestBody   bool
+	responseBody  bool
+	bodyInclude   []string
+	bodyMaxSize   int
+}
+
+// ResponseHandler 按照配置 base.server.access-log 打印访问日志，配置变更后自动生效
+// 兼容旧的配置：base.server.request.print、base.server.response.print、base.server.exception.print
 func ResponseHandler() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		reqPrint := config.GetValueBoolDefault("base.server.request.print.enable", false)
-		rspPrint := config.GetValueBoolDefault("base.server.response.print.enable", false)
-		expPrint := config.GetValueBoolDefault("base.server.exception.print.enable", false)
+	return accessLogHandler(accessLogPolicyValue.getter(), logAccess)
+}
 
-		if !reqPrint && !rspPrint && !expPrint {
-			return
-		}
+var accessLogPolicyValue = &configValue[*accessLogPolicy]{
+	load: func() *accessLogPolicy {
+		return newAccessLogPolicy(loadAccessLogConfig())
+	},
+	match: isAccessLogKey,
+}
 
-		// 开始时间
-		startTime := time.Now()
+// NewAccessLog 使用指定的配置创建访问日志，sink为空时使用logger打印
+func NewAccessLog(cfg AccessLogConfig, sink AccessLogSink) gin.HandlerFunc {
+	if sink == nil {
+		sink = logAccess
+	}
+	policy := newAccessLogPolicy(cfg)
+	return accessLogHandler(func() *accessLogPolicy {
+		return policy
+	}, sink)
+}
 
-		data, err := ioutil.ReadAll(c.Request.Body)
-		if err != nil {
-			logger.Error("read request body failed,err = %s.", err)
-			return
+func logAccess(_ *AccessLog, line string) {
+	logger.Info("%s", line)
+}
+
+func isAccessLogKey(key string) bool {
+	for _, prefix := range []string{"base.server.access-log", "base.server.request.print", "base.server.response.print", "base.server.exception.print"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
 		}
-		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+	}
+	return false
+}
 
-		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		c.Writer = blw
+func loadAccessLogConfig() AccessLogConfig {
+	cfg := AccessLogConfig{}
+	if err := config.GetValueObject("base.server.access-log", &cfg); err != nil {
+		logger.Warn("读取访问日志配置异常: %v", err)
+	}
 
-		// 处理请求
-		c.Next()
+	// 旧的配置：请求打印只打印请求体，响应打印同时打印请求体和响应体，异常打印只打印异常
+	reqPrint := config.GetValueBoolDefault("base.server.request.print.enable", false)
+	rspPrint := config.GetValueBoolDefault("base.server.response.print.enable", false)
+	expPrint := config.GetValueBoolDefault("base.server.exception.print.enable", false)
+	if !cfg.Enable && (reqPrint || rspPrint || expPrint) {
+		cfg.Enable = true
+		cfg.OnlyError = expPrint && !reqPrint && !rspPrint
+		cfg.Body.Request = true
+		cfg.Body.Response = rspPrint || expPrint
+		prefix := "base.server.request.print"
+		if rspPrint {
+			prefix = "base.server.response.print"
+		}
+		for _, uri := range config.GetValueArray(prefix + ".include-uri") {
+			cfg.Include = append(cfg.Include, fmt.Sprintf("%v*", uri))
+		}
+		for _, uri := range config.GetValueArray(prefix + ".exclude-uri") {
+			cfg.Exclude = append(cfg.Exclude, fmt.Sprintf("%v*", uri))
+		}
+		cfg.ExcludeStatus = append(cfg.ExcludeStatus, config.GetValueArrayInt("base.server.exception.print.exclude")...)
+	}
+	return cfg
+}
 
-		// 状态码
-		statusCode := c.Writer.Status()
-
-		var body any
-		bodyStr := string(data)
-		if "" != bodyStr && len(bodyStr) < 1000 {
-			if strings.HasPrefix(bodyStr, "{") && strings.HasSuffix(bodyStr, "}") {
-				bodys := map[string]any{}
-				_ = isc.StrToObject(bodyStr, &bodys)
-				body = bodys
-			} else if strings.HasPrefix(bodyStr, "[") && strings.HasSuffix(bodyStr, "]") {
-				var bodys []any
-				_ = isc.StrToObject(bodyStr, &bodys)
-				body = bodys
-			}
+func newAccessLogPolicy(cfg AccessLogConfig) *accessLogPolicy {
+	p := &accessLogPolicy{
+		enable:        cfg.Enable,
+		format:        strings.ToLower(cfg.Format),
+		sampleRate:    1,
+		include:       cfg.Include,
+		exclude:       cfg.Exclude,
+		excludeStatus: map[int]bool{},
+		onlyError:     cfg.OnlyError,
+		headers:       cfg.Headers,
+		requestBody:   cfg.Body.Request,
+		responseBody:  cfg.Body.Response,
+		bodyInclude:   cfg.Body.Include,
+		bodyMaxSize:   cfg.Body.MaxSize,
+	}
+	if p.format != AccessLogFormatApache {
+		p.format = AccessLogFormatJson
+	}
+	if cfg.SampleRate != nil {
+		p.sampleRate = *cfg.SampleRate
+	}
+	if p.bodyMaxSize <= 0 {
+		p.bodyMaxSize = 4096
+	}
+	for _, status := range cfg.ExcludeStatus {
+		p.excludeStatus[status] = true
+	}
+	return p
+}
+
+func (p *accessLogPolicy) match(c *gin.Context) bool {
+	for _, pattern := range p.exclude {
+		if matchAccessLogPath(pattern, c) {
+			return false
 		}
+	}
+	if len(p.include) == 0 {
+		return true
+	}
+	for _, pattern := range p.include {
+		if matchAccessLogPath(pattern, c) {
+			return true
+		}
+	}
+	return false
+}
 
-		request := Request{
-			Method:     c.Request.Method,
-			Uri:        c.Request.RequestURI,
-			Ip:         c.ClientIP(),
-			Parameters: c.Params,
-			Headers:    c.Request.Header,
-			Body:       body,
+func (p *accessLogPolicy) matchBody(c *gin.Context) bool {
+	if len(p.bodyInclude) == 0 {
+		return true
+	}
+	for _, pattern := range p.bodyInclude {
+		if matchAccessLogPath(pattern, c) {
+			return true
 		}
+	}
+	return false
+}
+
+// matchAccessLogPath 以*结尾时按照前缀匹配请求路径，否则匹配gin的路由路径或者请求路径
+func matchAccessLogPath(pattern string, c *gin.Context) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(c.Request.URL.Path, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == c.FullPath() || pattern == c.Request.URL.Path
+}
 
-		errMessage := ErrorMessage{
-			Request:    request,
-			StatusCode: statusCode,
-			Cost:       time.Now().Sub(startTime).String(),
+func accessLogHandler(load func() *accessLogPolicy, sink AccessLogSink) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := load()
+		if !p.enable || !p.match(c) {
+			c.Next()
+			return
 		}
 
-		responseMessage := Response{
-			Request:    request,
-			StatusCode: statusCode,
-			Cost:       time.Now().Sub(startTime).String(),
+		start := time.Now()
+		captureBody := p.matchBody(c)
+		var reqBody *captureBuffer
+		if captureBody && p.requestBody && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			// 处理函数读取请求体时同时保存，不会提前读取整个请求体
+			reqBody = &captureBuffer{max: p.bodyMaxSize}
+			c.Request.Body = &captureReader{ReadCloser: c.Request.Body, capture: reqBody}
 		}
+		var rspBody *captureBuffer
+		// 判断业务码需要响应体
+		if (captureBody && p.responseBody) || p.onlyError || p.sampleRate < 1 {
+			rspBody = &captureBuffer{max: p.bodyMaxSize}
+			c.Writer = &captureWriter{ResponseWriter: c.Writer, capture: rspBody}
+		}
+
+		c.Next()
 
-		if reqPrint && !rspPrint && !expPrint {
-			printReq(request.Uri, request)
+		status := c.Writer.Status()
+		if p.excludeStatus[status] {
+			return
+		}
+		isError := status >= http.StatusBadRequest || (rspBody != nil && hasErrorCode(c, rspBody))
+		if p.onlyError && !isError {
+			return
+		}
+		if !isError && p.sampleRate < 1 && rand.Float64() >= p.sampleRate {
+			return
 		}
 
-		if statusCode != 200 && statusCode != 0 {
-			config.GetValueArrayInt("base.server.exception.exclude")
-			datas := config.BaseCfg.Server.Exception.Print.Exclude
-			for _, code := range datas {
-				if code == statusCode {
-					return
+		entry := &AccessLog{
+			Time:      start.Format("2006-01-02 15:04:05.000"),
+			Method:    c.Request.Method,
+			Uri:       c.Request.RequestURI,
+			Route:     c.FullPath(),
+			Proto:     c.Request.Proto,
+			Status:    status,
+			Size:      c.Writer.Size(),
+			Cost:      float64(time.Since(start).Microseconds()) / 1000,
+			Ip:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Referer:   c.Request.Referer(),
+			TraceId:   traceId(c),
+			start:     start,
+		}
+		if entry.Size < 0 {
+			entry.Size = 0
+		}
+		if user, _, ok := c.Request.BasicAuth(); ok {
+			entry.User = user
+		}
+		if len(p.headers) != 0 {
+			entry.Headers = map[string]string{}
+			for _, header := range p.headers {
+				if value := c.GetHeader(header); value != "" {
+					entry.Headers[header] = value
 				}
 			}
-			if expPrint {
-				logger.Error("返回异常, result：%v", isc.ObjectToJson(errMessage))
-			}
+		}
+		if reqBody != nil {
+			entry.RequestBody = reqBody.value(c.ContentType())
+		}
+		if rspBody != nil && captureBody && p.responseBody {
+			entry.ResponseBody = rspBody.value(c.Writer.Header().Get("Content-Type"))
+		}
+
+		if p.format == AccessLogFormatApache {
+			sink(entry, entry.apacheLine())
 		} else {
-			var response DataResponse[any]
-			if err := json.Unmarshal([]byte(blw.body.String()), &response); err != nil {
-				return
-			} else {
-				if response.Code != 0 && response.Code != 200 {
-					errMessage.Response = response
-					if expPrint {
-						logger.Error("返回异常, result：%v", isc.ObjectToJson(errMessage))
-					}
-				} else {
-					responseMessage.Response = response
-					if rspPrint {
-						printRsq(request.Uri, responseMessage)
-					}
-				}
-			}
+			data, _ := json.Marshal(entry)
+			sink(entry, string(data))
 		}
 	}
 }
 
-func printReq(requestUri string, requestData Request) {
-	includeUri := config.GetValueArray("base.server.request.print.include-uri")
-	printFlag := true
-	if len(includeUri) != 0 {
-		for _, uri := range includeUri {
-			if !strings.HasPrefix(requestUri, isc.ToString(uri)) {
-				printFlag = false
-			}
-		}
+// traceId 优先使用gin上下文中的traceId，没有时使用协程存储中的
+func traceId(c *gin.Context) string {
+	if id := c.GetString(TraceIdKey); id != "" {
+		return id
 	}
+	return goid.GetTraceID()
+}
 
-	excludeUri := config.GetValueArray("base.server.request.print.exclude-uri")
-	if len(excludeUri) != 0 {
-		for _, uri := range excludeUri {
-			if strings.HasPrefix(requestUri, isc.ToString(uri)) {
-				printFlag = false
-				break
-			}
-		}
+// hasErrorCode 响应为json时，判断业务码是否异常
+func hasErrorCode(c *gin.Context, body *captureBuffer) bool {
+	if !strings.Contains(c.Writer.Header().Get("Content-Type"), "json") || body.truncated {
+		return false
 	}
+	var response struct {
+		Code *json.Number `json:"code"`
+	}
+	if err := json.Unmarshal(body.buf.Bytes(), &response); err != nil || response.Code == nil {
+		return false
+	}
+	code := response.Code.String()
+	return code != "0" && code != "200"
+}
 
-	if printFlag {
-		logger.Info("请求：%v", isc.ObjectToJson(requestData))
+// apacheLine Apache combined格式，时间使用CLF格式，末尾追加耗时(毫秒)和traceId
+func (l *AccessLog) apacheLine() string {
+	user := l.User
+	if user == "" {
+		user = "-"
 	}
-	return
+	line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %d "%s" "%s" %.3f %s`,
+		l.Ip, user, l.start.Format("02/Jan/2006:15:04:05 -0700"), l.Method, l.Uri, l.Proto, l.Status, l.Size, dashIfEmpty(l.Referer), dashIfEmpty(l.UserAgent), l.Cost, dashIfEmpty(l.TraceId))
+	if l.RequestBody != nil {
+		line += " request=" + bodyString(l.RequestBody)
+	}
+	if l.ResponseBody != nil {
+		line += " response=" + bodyString(l.ResponseBody)
+	}
+	return line
 }
 
-func printRsq(requestUri string, responseMessage Response) {
-	includeUri := config.GetValueArray("base.server.response.print.include-uri")
-	printFlag := true
-	if len(includeUri) != 0 {
-		for _, uri := range includeUri {
-			if !strings.HasPrefix(requestUri, isc.ToString(uri)) {
-				printFlag = false
-			}
-		}
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
 	}
+	return s
+}
 
-	excludeUri := config.GetValueArray("base.server.response.print.exclude-uri")
-	if len(excludeUri) != 0 {
-		for _, uri := range excludeUri {
-			if strings.HasPrefix(requestUri, isc.ToString(uri)) {
-				printFlag = false
-				break
-			}
+func bodyString(body any) string {
+	switch v := body.(type) {
+	case json.RawMessage:
+		return string(v)
+	case string:
+		return strconv.Quote(v)
+	}
+	return fmt.Sprintf("%v", body)
+}
+
+// captureBuffer 只保存前max个字节
+type captureBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	total     int
+	truncated bool
+}
+
+func (b *captureBuffer) write(p []byte) {
+	b.total += len(p)
+	if remain := b.max - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			p = p[:remain]
+			b.truncated = true
 		}
+		b.buf.Write(p)
+	} else if len(p) > 0 {
+		b.truncated = true
 	}
+}
 
-	if printFlag {
-		logger.Info("响应：%v", isc.ObjectToJson(responseMessage))
+// value json时原样输出，文本输出字符串，其他类型只输出大小
+func (b *captureBuffer) value(contentType string) any {
+	if b.total == 0 {
+		return nil
 	}
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.Contains(contentType, "text/event-stream"):
+		return fmt.Sprintf("<stream %d bytes>", b.total)
+	case strings.Contains(contentType, "json") && !b.truncated && json.Valid(b.buf.Bytes()):
+		return json.RawMessage(b.buf.Bytes())
+	case contentType == "" || strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") || strings.Contains(contentType, "x-www-form-urlencoded") || strings.Contains(contentType, "yaml"):
+		if b.truncated {
+			return fmt.Sprintf("%s...(%d bytes)", b.buf.String(), b.total)
+		}
+		return b.buf.String()
+	}
+	return fmt.Sprintf("<%s %d bytes>", contentType, b.total)
+}
+
+type captureReader struct {
+	io.ReadCloser
+	capture *captureBuffer
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture.write(p[:n])
+	return n, err
+}
+
+// captureWriter 写入时同时保存响应体，不影响流式响应的Flush
+type captureWriter struct {
+	gin.ResponseWriter
+	capture *captureBuffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.capture.write(b[:n])
+	return n, err
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture.write([]byte(s[:n]))
+	return n, err
 }
 
 type Request struct {
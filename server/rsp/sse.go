@@ -0,0 +1,211 @@
+package rsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrSSEClosed 连接已经关闭
+var ErrSSEClosed = errors.New("sse连接已经关闭")
+
+// SSEEvent 服务端推送的事件
+type SSEEvent struct {
+	// 事件id，客户端断线重连时通过 Last-Event-ID 携带
+	Id string
+	// 事件名，为空则为 message
+	Event string
+	// 数据：string和[]byte原样输出，其他类型序列化为json
+	Data any
+	// 客户端断线后的重连间隔
+	Retry time.Duration
+}
+
+// SSEOption sse的选项
+type SSEOption struct {
+	// 连接建立时下发的重连间隔，为0则不下发
+	Retry time.Duration
+	// 心跳间隔，默认：15秒，小于0则不发送心跳
+	Heartbeat time.Duration
+	// 事件缓存：发送的事件会保存到缓存中，客户端携带 Last-Event-ID 重连时补发之后的事件
+	Buffer SSEBuffer
+}
+
+// SSEStream 一个sse连接，可以在多个协程中并发发送
+type SSEStream struct {
+	ctx         context.Context
+	writer      gin.ResponseWriter
+	lastEventId string
+	buffer      SSEBuffer
+	lock        sync.Mutex
+	closed      bool
+}
+
+// ServeSSE 将请求作为sse连接处理，handler返回或者客户端断开后连接结束
+func ServeSSE(c *gin.Context, option SSEOption, handler func(stream *SSEStream)) {
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭nginx的缓冲
+	header.Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	stream := &SSEStream{
+		ctx:         c.Request.Context(),
+		writer:      c.Writer,
+		lastEventId: c.GetHeader("Last-Event-ID"),
+		buffer:      option.Buffer,
+	}
+	if stream.lastEventId == "" {
+		// EventSource 不支持自定义请求头时，可以通过查询参数传递
+		stream.lastEventId = c.Query("lastEventId")
+	}
+	if option.Retry > 0 {
+		_ = stream.write(fmt.Sprintf("retry: %d\n\n", option.Retry.Milliseconds()))
+	} else {
+		stream.writer.Flush()
+	}
+
+	// 补发断线期间的事件
+	if stream.buffer != nil && stream.lastEventId != "" {
+		events, _ := stream.buffer.Since(stream.lastEventId)
+		for _, event := range events {
+			if stream.writeEvent(event) != nil {
+				break
+			}
+		}
+	}
+
+	heartbeat := option.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = 15 * time.Second
+	}
+	done := make(chan struct{})
+	if heartbeat > 0 {
+		go stream.heartbeat(heartbeat, done)
+	}
+	handler(stream)
+	close(done)
+
+	stream.lock.Lock()
+	stream.closed = true
+	stream.lock.Unlock()
+}
+
+func (s *SSEStream) heartbeat(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.Comment("ping") != nil {
+				return
+			}
+		}
+	}
+}
+
+// Context 请求的context，客户端断开后取消
+func (s *SSEStream) Context() context.Context {
+	return s.ctx
+}
+
+// Done 客户端断开后关闭
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// LastEventId 客户端重连时携带的最后一个事件id
+func (s *SSEStream) LastEventId() string {
+	return s.lastEventId
+}
+
+// Send 发送事件；配置了缓存时先保存到缓存中，没有id的事件由缓存生成id
+func (s *SSEStream) Send(event SSEEvent) error {
+	if s.buffer != nil {
+		event = s.buffer.Append(event)
+	}
+	return s.writeEvent(event)
+}
+
+// SendData 发送只有数据的事件
+func (s *SSEStream) SendData(data any) error {
+	return s.Send(SSEEvent{Data: data})
+}
+
+// Comment 发送注释，客户端会忽略，用于心跳
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": " + strings.ReplaceAll(text, "\n", " ") + "\n\n")
+}
+
+func (s *SSEStream) writeEvent(event SSEEvent) error {
+	text, err := FormatSSEEvent(event)
+	if err != nil {
+		return err
+	}
+	return s.write(text)
+}
+
+func (s *SSEStream) write(text string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed || s.ctx.Err() != nil {
+		return ErrSSEClosed
+	}
+	if _, err := s.writer.WriteString(text); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return nil
+}
+
+// FormatSSEEvent 按照 text/event-stream 的格式输出事件
+func FormatSSEEvent(event SSEEvent) (string, error) {
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		data = string(bytes)
+	}
+
+	var sb strings.Builder
+	if event.Id != "" {
+		sb.WriteString("id: " + singleLine(event.Id) + "\n")
+	}
+	if event.Event != "" {
+		sb.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
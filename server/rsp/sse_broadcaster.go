@@ -0,0 +1,238 @@
+package rsp
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+// SSEBuffer 事件缓存，用于客户端携带 Last-Event-ID 重连时补发事件，可以自行实现为redis等存储
+type SSEBuffer interface {
+	// Append 保存事件，没有id的事件需要生成id并返回保存后的事件
+	Append(event SSEEvent) SSEEvent
+	// Since 返回lastId之后的事件；lastId已经不在缓存中时返回缓存中全部的事件以及false
+	Since(lastId string) ([]SSEEvent, bool)
+}
+
+// SSEMemoryBuffer 基于环形数组的内存缓存，生成的id为递增的数字
+type SSEMemoryBuffer struct {
+	lock     sync.Mutex
+	events   []SSEEvent
+	start    int
+	size     int
+	sequence uint64
+}
+
+// NewSSEMemoryBuffer 创建内存缓存，capacity为最多保存的事件数，默认：256
+func NewSSEMemoryBuffer(capacity int) *SSEMemoryBuffer {
+	if capacity <= 0 {
+		capacity = 256
+	}
+	return &SSEMemoryBuffer{events: make([]SSEEvent, capacity)}
+}
+
+func (b *SSEMemoryBuffer) Append(event SSEEvent) SSEEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sequence++
+	if event.Id == "" {
+		event.Id = strconv.FormatUint(b.sequence, 10)
+	}
+	capacity := len(b.events)
+	if b.size < capacity {
+		b.events[(b.start+b.size)%capacity] = event
+		b.size++
+	} else {
+		b.events[b.start] = event
+		b.start = (b.start + 1) % capacity
+	}
+	return event
+}
+
+func (b *SSEMemoryBuffer) Since(lastId string) ([]SSEEvent, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	capacity := len(b.events)
+	for i := b.size - 1; i >= 0; i-- {
+		if b.events[(b.start+i)%capacity].Id == lastId {
+			return b.copyFrom(i + 1), true
+		}
+	}
+	return b.copyFrom(0), false
+}
+
+func (b *SSEMemoryBuffer) copyFrom(offset int) []SSEEvent {
+	capacity := len(b.events)
+	result := make([]SSEEvent, 0, b.size-offset)
+	for i := offset; i < b.size; i++ {
+		result = append(result, b.events[(b.start+i)%capacity])
+	}
+	return result
+}
+
+// SSE订阅者的队列满时的处理策略
+const (
+	// SSEDropOldest 丢弃队列中最早的事件
+	SSEDropOldest = "drop-oldest"
+	// SSEDropNewest 丢弃新的事件
+	SSEDropNewest = "drop-newest"
+	// SSEDisconnect 断开消费过慢的订阅者，客户端重连后通过 Last-Event-ID 补发
+	SSEDisconnect = "disconnect"
+)
+
+// SSEBroadcasterOption 广播的选项
+type SSEBroadcasterOption struct {
+	// 事件缓存，默认：容量为256的内存缓存
+	Buffer SSEBuffer
+	// 每个订阅者的队列长度，默认：64
+	QueueSize int
+	// 队列满时的策略：drop-oldest、drop-newest、disconnect，默认：drop-oldest
+	Policy string
+}
+
+// SSEBroadcaster 将事件广播给所有的订阅者，每个订阅者有独立的队列，慢的订阅者不会阻塞发布
+type SSEBroadcaster struct {
+	option SSEBroadcasterOption
+	// publishLock 保证缓存中的顺序和推送给订阅者的顺序一致
+	publishLock sync.Mutex
+	lock        sync.RWMutex
+	subscribers map[*sseSubscriber]struct{}
+	closed      bool
+}
+
+type sseSubscriber struct {
+	queue chan SSEEvent
+	done  chan struct{}
+	once  sync.Once
+	lock  sync.Mutex
+}
+
+func (s *sseSubscriber) close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// NewSSEBroadcaster 创建广播
+func NewSSEBroadcaster(option SSEBroadcasterOption) *SSEBroadcaster {
+	if option.Buffer == nil {
+		option.Buffer = NewSSEMemoryBuffer(0)
+	}
+	if option.QueueSize <= 0 {
+		option.QueueSize = 64
+	}
+	if option.Policy == "" {
+		option.Policy = SSEDropOldest
+	}
+	return &SSEBroadcaster{option: option, subscribers: map[*sseSubscriber]struct{}{}}
+}
+
+// Buffer 广播使用的事件缓存
+func (b *SSEBroadcaster) Buffer() SSEBuffer {
+	return b.option.Buffer
+}
+
+// Publish 发布事件，返回生成id后的事件；并发发布时，订阅者收到事件的顺序与缓存中的顺序一致
+func (b *SSEBroadcaster) Publish(event SSEEvent) SSEEvent {
+	b.publishLock.Lock()
+	defer b.publishLock.Unlock()
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	event = b.option.Buffer.Append(event)
+	for subscriber := range b.subscribers {
+		b.offer(subscriber, event)
+	}
+	return event
+}
+
+func (b *SSEBroadcaster) offer(subscriber *sseSubscriber, event SSEEvent) {
+	subscriber.lock.Lock()
+	defer subscriber.lock.Unlock()
+	select {
+	case subscriber.queue <- event:
+		return
+	default:
+	}
+
+	switch b.option.Policy {
+	case SSEDropNewest:
+	case SSEDisconnect:
+		logger.Warn("sse订阅者消费过慢，断开连接")
+		subscriber.close()
+	default:
+		select {
+		case <-subscriber.queue:
+		default:
+		}
+		select {
+		case subscriber.queue <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers 当前的订阅者数量
+func (b *SSEBroadcaster) Subscribers() int {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return len(b.subscribers)
+}
+
+// Serve 将连接注册为订阅者，补发 Last-Event-ID 之后的事件，然后持续推送直到连接断开、被判定为慢订阅者或者广播关闭
+func (b *SSEBroadcaster) Serve(stream *SSEStream) {
+	subscriber := &sseSubscriber{queue: make(chan SSEEvent, b.option.QueueSize), done: make(chan struct{})}
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return
+	}
+	b.subscribers[subscriber] = struct{}{}
+	b.lock.Unlock()
+	defer b.unsubscribe(subscriber)
+
+	// 先订阅后补发，补发过的事件在队列中跳过
+	replayed := map[string]struct{}{}
+	if lastId := stream.LastEventId(); lastId != "" {
+		events, _ := b.option.Buffer.Since(lastId)
+		for _, event := range events {
+			if stream.writeEvent(event) != nil {
+				return
+			}
+			replayed[event.Id] = struct{}{}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case <-subscriber.done:
+			return
+		case event := <-subscriber.queue:
+			if _, ok := replayed[event.Id]; ok {
+				delete(replayed, event.Id)
+				continue
+			}
+			if stream.writeEvent(event) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *SSEBroadcaster) unsubscribe(subscriber *sseSubscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subscribers, subscriber)
+	subscriber.close()
+}
+
+// Close 关闭广播，断开所有的订阅者
+func (b *SSEBroadcaster) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for subscriber := range b.subscribers {
+		subscriber.close()
+	}
+	b.subscribers = map[*sseSubscriber]struct{}{}
+}
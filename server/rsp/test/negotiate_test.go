@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/listener"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/isyscore/isc-gobase/server/servertest"
+	"github.com/magiconair/properties/assert"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type negotiateUser struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Tags []string `json:"tags"`
+}
+
+func TestNegotiate(t *testing.T) {
+	s := servertest.New(t, `
+base:
+  server:
+    response:
+      negotiation:
+        enable: true
+`)
+	e := s.Engine()
+	e.GET("/user", func(c *gin.Context) {
+		rsp.SuccessOfData(c, negotiateUser{Name: "isc", Age: 3, Tags: []string{"a", "b"}})
+	})
+	e.GET("/paged", func(c *gin.Context) {
+		rsp.SuccessOfPaged(c, rsp.PagedData[string]{Total: 1, Size: 10, Current: 1, Pages: 1, Records: []string{"x"}})
+	})
+	e.GET("/proto", func(c *gin.Context) {
+		rsp.SuccessOfData(c, wrapperspb.String("isc"))
+	})
+	e.GET("/error", func(c *gin.Context) {
+		rsp.FailedOfStatus(c, http.StatusNotFound, "not found")
+	})
+
+	// 没有Accept以及不支持的格式时使用默认的json
+	s.Get("/user").ExpectJSON(`{"code":0,"message":"success","data":{"name":"isc","age":3,"tags":["a","b"]}}`).ExpectHeader("Vary", "Accept")
+	s.Get("/user").WithHeader("Accept", "text/html").Do().ExpectHeader("Content-Type", "application/json; charset=utf-8")
+
+	s.Get("/user").WithHeader("Accept", "text/html, application/xml;q=0.9").Do().
+		ExpectHeader("Content-Type", "application/xml; charset=utf-8").
+		ExpectBodyContains("<response><code>0</code><data><age>3</age><name>isc</name><tags><item>a</item><item>b</item></tags></data><message>success</message></response>")
+
+	s.Get("/paged").WithHeader("Accept", "application/yaml").Do().
+		ExpectHeader("Content-Type", "application/yaml; charset=utf-8").
+		ExpectBody("code: 0\ndata:\n  current: 1\n  isSearchCount: false\n  pages: 1\n  records:\n  - x\n  size: 10\n  total: 1\nmessage: success\n")
+
+	r := s.Get("/user").WithHeader("Accept", "application/msgpack").Do().ExpectHeader("Content-Type", "application/msgpack")
+	var decoded map[string]any
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	_ = codec.NewDecoder(bytes.NewReader(r.Recorder.Body.Bytes()), handle).Decode(&decoded)
+	assert.Equal(t, decoded["message"], "success")
+
+	// protobuf只编码业务数据
+	r = s.Get("/proto").WithHeader("Accept", "application/x-protobuf").Do().ExpectHeader("Content-Type", "application/x-protobuf")
+	message := &wrapperspb.StringValue{}
+	assert.Equal(t, proto.Unmarshal(r.Recorder.Body.Bytes(), message), nil)
+	assert.Equal(t, message.GetValue(), "isc")
+
+	// 失败的响应使用协商的格式，protobuf不支持时使用默认格式
+	s.Get("/error").WithHeader("Accept", "application/xml").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectBodyContains("<response><code>404</code><data></data><message>not found</message></response>")
+	s.Get("/error").WithHeader("Accept", "application/x-protobuf").Do().
+		ExpectStatus(http.StatusNotFound).
+		ExpectHeader("Vary", "Accept").
+		ExpectJSON(`{"code":404,"message":"not found","data":null}`)
+
+	// 分页数据不是protobuf消息，无法编码
+	s.Get("/paged").WithHeader("Accept", "application/x-protobuf").Do().
+		ExpectStatus(http.StatusNotAcceptable).
+		ExpectCode(http.StatusNotAcceptable)
+}
+
+func TestNegotiateWithCors(t *testing.T) {
+	s := servertest.New(t, `
+base:
+  server:
+    response:
+      negotiation:
+        enable: true
+`)
+	e := s.Engine()
+	e.Use(server.NewCors(server.CorsConfig{AllowOrigins: []string{"https://*.isyscore.com"}}))
+	e.GET("/user", func(c *gin.Context) {
+		rsp.SuccessOfData(c, negotiateUser{Name: "isc"})
+	})
+
+	// 协商不能覆盖跨域中间件添加的 Vary: Origin
+	r := s.Get("/user").WithHeader("Origin", "https://app.isyscore.com").WithHeader("Accept", "application/xml").Do().
+		ExpectHeader("Access-Control-Allow-Origin", "https://app.isyscore.com")
+	assert.Equal(t, r.Recorder.Header().Values("Vary"), []string{"Origin", "Accept"})
+}
+
+func TestNegotiateConfigChange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/user", func(c *gin.Context) {
+		c.String(http.StatusOK, rsp.Negotiate(c))
+	})
+	do := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.Header.Set("Accept", "application/xml")
+		e.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	change := func(enable bool) {
+		_ = config.LoadYamlContent(fmt.Sprintf("base:\n  server:\n    response:\n      negotiation:\n        enable: %v\n", enable))
+		listener.PublishEvent(listener.ConfigChangeEvent{Key: "base.server.response.negotiation.enable", Value: fmt.Sprint(enable)})
+	}
+	defer change(false)
+
+	assert.Equal(t, do(), rsp.FormatJSON)
+	// 配置变更事件之后生效
+	change(true)
+	assert.Equal(t, do(), rsp.FormatXML)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/isyscore/isc-gobase/bean"
 	"github.com/isyscore/isc-gobase/listener"
@@ -10,18 +11,25 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/isyscore/isc-gobase/server/auth"
+	"github.com/isyscore/isc-gobase/server/ratelimit"
 	"github.com/isyscore/isc-gobase/server/rsp"
 
 	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/goid"
 	"github.com/isyscore/isc-gobase/isc"
 
 	"github.com/isyscore/isc-gobase/logger"
+	"github.com/isyscore/isc-gobase/redis"
 
 	"github.com/gin-gonic/gin"
 	"github.com/isyscore/isc-gobase/websocket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type HttpMethod int
@@ -42,6 +50,10 @@ var ApiPrefix = "/api"
 
 var engine *gin.Engine = nil
 
+// WithEngine 设置的当前协程的engine，engineOverrideCount 为0时不读取协程存储
+var engineStorage = goid.NewLocalStorage()
+var engineOverrideCount int32
+
 func init() {
 	isc.PrintBanner()
 	config.LoadConfig()
@@ -70,15 +82,16 @@ func InitServer() {
 		gin.DefaultWriter = ioutil.Discard
 	}
 
-	engine = gin.New()
-	engine.Use(Cors(), gin.Recovery())
-	engine.Use(rsp.ResponseHandler())
+	engine = NewStandardEngine()
 
 	ap := config.GetValueStringDefault("base.api.prefix", "")
 	if ap != "" {
 		ApiPrefix = ap
 	}
 
+	// 管理端口
+	initManagementEngine()
+
 	// 注册 健康检查endpoint
 	if config.GetValueBoolDefault("base.endpoint.health.enable", false) {
 		RegisterHealthCheckEndpoint(ApiPrefix + "/" + config.ApiModule)
@@ -94,16 +107,94 @@ func InitServer() {
 		RegisterBeanWatchEndpoint(ApiPrefix + "/" + config.ApiModule)
 	}
 
+	// 注册 指标endpoint
+	if config.GetValueBoolDefault("base.endpoint.metrics.enable", false) {
+		RegisterMetricsEndpoint(ApiPrefix + "/" + config.ApiModule)
+		if config.GetValueBoolDefault("base.redis.enable", false) {
+			RegisterMetrics("redis", func() any {
+				return redis.Metrics()
+			})
+		}
+	}
+
+	// 注册 openapi文档
+	if config.GetValueBoolDefault("base.server.openapi.enable", false) {
+		RegisterOpenApiEndpoint(config.GetValueStringDefault("base.server.openapi.path", "/openapi.json"), config.GetValueString("base.server.openapi.ui-path"))
+	}
+
 	appName := config.GetValueStringDefault("base.application.name", "isc-gobase")
 
 	var loggerCfg logger.LoggerConfig
 	if err := config.GetValueObject("base.logger", &loggerCfg); err != nil {
-		logger.Warn("获取配置失败", err)
+		logger.Warn("获取配置失败: %v", err)
 	} else {
 		logger.InitLog(appName, &loggerCfg)
 	}
 }
 
+// NewStandardEngine 按照当前的配置创建带有标准中间件（trace、跨域、请求体限制、访问日志、限流、认证、超时等）的engine
+func NewStandardEngine() *gin.Engine {
+	return newEngine(true)
+}
+
+// newEngine 创建带有标准中间件的engine，accessControl为false时不使用限流和认证，用于管理端口
+func newEngine(accessControl bool) *gin.Engine {
+	e := gin.New()
+	e.Use(Trace())
+	// 跨域
+	if config.GetValueBoolDefault("base.server.cors.enable", true) {
+		e.Use(Cors())
+	}
+	e.Use(gin.Recovery())
+	// 请求体大小限制，需要在读取请求体之前
+	e.Use(BodyLimit())
+	// 慢请求
+	if config.GetValueBoolDefault("base.server.slow-request.enable", false) {
+		e.Use(SlowRequest())
+	}
+	e.Use(rsp.ResponseHandler())
+
+	// 限流
+	if accessControl && config.GetValueBoolDefault("base.server.ratelimit.enable", false) {
+		e.Use(ratelimit.Middleware())
+	}
+
+	// 认证
+	if accessControl && config.GetValueBoolDefault("base.server.auth.enable", false) {
+		e.Use(auth.Middleware())
+	}
+
+	// 处理超时
+	e.Use(HandlerTimeout())
+	return e
+}
+
+// WithEngine 在register执行期间将当前协程中 Get、Post、RegisterRoute 等函数注册的路由注册到指定的engine，用于测试等场景；
+// 和 config.WithProperties 一样只对当前协程生效，不修改全局的engine，多个协程可以同时使用不同的engine
+func WithEngine(e *gin.Engine, register func()) {
+	atomic.AddInt32(&engineOverrideCount, 1)
+	previous := engineStorage.Set(e)
+	defer func() {
+		if previous == nil {
+			engineStorage.Del()
+		} else {
+			engineStorage.Set(previous)
+		}
+		atomic.AddInt32(&engineOverrideCount, -1)
+	}()
+	register()
+}
+
+// currentEngine 当前协程注册路由使用的engine，WithEngine 执行期间为指定的engine
+func currentEngine() *gin.Engine {
+	if atomic.LoadInt32(&engineOverrideCount) != 0 {
+		if e, ok := engineStorage.Get().(*gin.Engine); ok {
+			return e
+		}
+	}
+	return engine
+}
+
 func printVersionAndProfile() {
 	fmt.Printf("----------------------------- isc-gobase: %s --------------------------\n", GoBaseVersion)
 	fmt.Printf("profile：%s\n", config.CurrentProfile)
@@ -131,15 +222,13 @@ func StartServer() {
 }
 
 func graceRun(port int) {
-	engineServer := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: engine}
-	go func() {
-		if err := engineServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("启动服务异常 (%v)", err)
-		} else {
-			// 发送服务关闭事件
-			listener.PublishEvent(listener.ServerStopEvent{})
-		}
-	}()
+	servers := newServers(port)
+	if len(servers) == 0 {
+		return
+	}
+	for _, s := range servers {
+		go s.run()
+	}
 
 	// 发送服务启动事件
 	listener.PublishEvent(listener.ServerFinishEvent{})
@@ -149,90 +238,171 @@ func graceRun(port int) {
 	logger.Warn("服务端准备关闭...")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := engineServer.Shutdown(ctx); err != nil {
-		logger.Warn("服务关闭异常: ", err)
+	for _, s := range servers {
+		if err := s.server.Shutdown(ctx); err != nil {
+			logger.Warn("服务关闭异常: %v", err)
+		}
 	}
+	// 发送服务关闭事件
+	listener.PublishEvent(listener.ServerStopEvent{})
 	logger.Info("服务端退出")
 }
 
+type engineServer struct {
+	server *http.Server
+	tls    bool
+}
+
+func (s *engineServer) run() {
+	var err error
+	if s.tls {
+		// 证书由 TLSConfig.GetCertificate 提供
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		logger.Error("启动服务异常 (%v)", err)
+	}
+}
+
+// newServers 按照配置创建http以及https服务，以及管理端口和命名engine的服务
+func newServers(port int) []*engineServer {
+	var handler http.Handler = engine
+	if config.GetValueBoolDefault("base.server.h2c.enable", false) {
+		// 明文的http2，用于内部服务之间的调用
+		handler = h2c.NewHandler(engine, &http2.Server{})
+	}
+
+	servers := newEngineServers(port, handler)
+	if len(servers) == 0 {
+		return nil
+	}
+	servers = append(servers, newNamedEngineServers()...)
+	timeoutCfg := getTimeoutConfig()
+	for _, s := range servers {
+		applyServerLimits(s.server, timeoutCfg)
+	}
+	return servers
+}
+
+func newEngineServers(port int, handler http.Handler) []*engineServer {
+	tlsCfg := getTlsConfig()
+	if !tlsCfg.Enable {
+		return []*engineServer{{server: &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler}}}
+	}
+
+	tlsConfig, err := NewTlsConfig(tlsCfg)
+	if err != nil {
+		logger.Error("启动服务异常 (%v)", err)
+		return nil
+	}
+	tlsPort := tlsCfg.Port
+	if tlsPort == 0 {
+		tlsPort = port
+	}
+	logger.Info("https服务端口号: %d", tlsPort)
+	tlsServer := &http.Server{Addr: fmt.Sprintf(":%d", tlsPort), Handler: engine, TLSConfig: tlsConfig}
+	if tlsCfg.DisableHttp2 {
+		tlsServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	servers := []*engineServer{{server: tlsServer, tls: true}}
+
+	if tlsCfg.KeepHttp {
+		if tlsPort == port {
+			logger.Error("同时开启http和https时，base.server.tls.port 不能与 base.server.port 相同")
+			return nil
+		}
+		servers = append(servers, &engineServer{server: &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler}})
+	}
+	return servers
+}
+
 func RegisterStatic(relativePath string, rootPath string) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
-	engine.Static(relativePath, rootPath)
-	return engine
+	e := currentEngine()
+	e.Static(relativePath, rootPath)
+	return e
 }
 
 func RegisterStaticFile(relativePath string, filePath string) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
-	engine.StaticFile(relativePath, filePath)
-	return engine
+	e := currentEngine()
+	e.StaticFile(relativePath, filePath)
+	return e
 }
 
 func RegisterPlugin(plugin gin.HandlerFunc) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
-	engine.Use(plugin)
-	return engine
+	e := currentEngine()
+	e.Use(plugin)
+	return e
 }
 
 func Engine() gin.IRoutes {
-	return engine
+	return currentEngine()
 }
 
 func RegisterHealthCheckEndpoint(apiBase string) gin.IRoutes {
-	if "" == apiBase {
+	if "" == apiBase || !checkEngine() {
 		return nil
 	}
-	RegisterRoute(apiBase+"/system/status", HmAll, healthSystemStatus)
-	RegisterRoute(apiBase+"/system/init", HmAll, healthSystemInit)
-	RegisterRoute(apiBase+"/system/destroy", HmAll, healthSystemDestroy)
-	return engine
+	e := ManagementEngine()
+	registerRouteOn(e, apiBase+"/system/status", HmAll, healthSystemStatus)
+	registerRouteOn(e, apiBase+"/system/init", HmAll, healthSystemInit)
+	registerRouteOn(e, apiBase+"/system/destroy", HmAll, healthSystemDestroy)
+	return e
 }
 
 func RegisterConfigWatchEndpoint(apiBase string) gin.IRoutes {
-	if "" == apiBase {
+	if "" == apiBase || !checkEngine() {
 		return nil
 	}
-	RegisterRoute(apiBase+"/config/values", HmGet, config.GetConfigValues)
-	RegisterRoute(apiBase+"/config/value/:key", HmGet, config.GetConfigValue)
-	RegisterRoute(apiBase+"/config/update", HmPut, config.UpdateConfig)
-	return engine
+	e := ManagementEngine()
+	registerRouteOn(e, apiBase+"/config/values", HmGet, config.GetConfigValues)
+	registerRouteOn(e, apiBase+"/config/value/:key", HmGet, config.GetConfigValue)
+	registerRouteOn(e, apiBase+"/config/update", HmPut, config.UpdateConfig)
+	return e
 }
 
 func RegisterBeanWatchEndpoint(apiBase string) gin.IRoutes {
-	if "" == apiBase {
+	if "" == apiBase || !checkEngine() {
 		return nil
 	}
-	RegisterRoute(apiBase+"/bean/name/all", HmGet, bean.DebugBeanAll)
-	RegisterRoute(apiBase+"/bean/name/list/:name", HmGet, bean.DebugBeanList)
-	RegisterRoute(apiBase+"/bean/field/get", HmPost, bean.DebugBeanGetField)
-	RegisterRoute(apiBase+"/bean/field/set", HmPut, bean.DebugBeanSetField)
-	RegisterRoute(apiBase+"/bean/fun/call", HmPost, bean.DebugBeanFunCall)
-	return engine
+	e := ManagementEngine()
+	registerRouteOn(e, apiBase+"/bean/name/all", HmGet, bean.DebugBeanAll)
+	registerRouteOn(e, apiBase+"/bean/name/list/:name", HmGet, bean.DebugBeanList)
+	registerRouteOn(e, apiBase+"/bean/field/get", HmPost, bean.DebugBeanGetField)
+	registerRouteOn(e, apiBase+"/bean/field/set", HmPut, bean.DebugBeanSetField)
+	registerRouteOn(e, apiBase+"/bean/fun/call", HmPost, bean.DebugBeanFunCall)
+	return e
 }
 
 func RegisterCustomHealthCheck(apiBase string, status func() string, init func() string, destroy func() string) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
-	RegisterRoute(apiBase+"/system/status", HmAll, func(c *gin.Context) {
+	e := ManagementEngine()
+	registerRouteOn(e, apiBase+"/system/status", HmAll, func(c *gin.Context) {
 		c.Data(200, "application/json; charset=utf-8", []byte(status()))
 	})
-	RegisterRoute(apiBase+"/system/init", HmAll, func(c *gin.Context) {
+	registerRouteOn(e, apiBase+"/system/init", HmAll, func(c *gin.Context) {
 		c.Data(200, "application/json; charset=utf-8", []byte(init()))
 	})
-	RegisterRoute(apiBase+"/system/destroy", HmAll, func(c *gin.Context) {
+	registerRouteOn(e, apiBase+"/system/destroy", HmAll, func(c *gin.Context) {
 		c.Data(200, "application/json; charset=utf-8", []byte(destroy()))
 	})
-	return engine
+	return e
 }
 
 func checkEngine() bool {
-	if engine == nil {
+	if currentEngine() == nil {
 		logger.Error("服务没有初始化，请先调用 InitServer")
 		return false
 	}
@@ -243,75 +413,70 @@ func RegisterRoute(path string, method HttpMethod, handler gin.HandlerFunc) gin.
 	if !checkEngine() {
 		return nil
 	}
+	return registerRouteOn(currentEngine(), path, method, handler)
+}
+
+func registerRouteOn(e *gin.Engine, path string, method HttpMethod, handler gin.HandlerFunc) gin.IRoutes {
 	switch method {
 	case HmAll:
-		engine.GET(path, handler)
-		engine.POST(path, handler)
-		engine.PUT(path, handler)
-		engine.DELETE(path, handler)
-		engine.OPTIONS(path, handler)
-		engine.HEAD(path, handler)
+		e.GET(path, handler)
+		e.POST(path, handler)
+		e.PUT(path, handler)
+		e.DELETE(path, handler)
+		e.OPTIONS(path, handler)
+		e.HEAD(path, handler)
 	case HmGet:
-		engine.GET(path, handler)
+		e.GET(path, handler)
 	case HmPost:
-		engine.POST(path, handler)
+		e.POST(path, handler)
 	case HmPut:
-		engine.PUT(path, handler)
+		e.PUT(path, handler)
 	case HmDelete:
-		engine.DELETE(path, handler)
+		e.DELETE(path, handler)
 	case HmOptions:
-		engine.OPTIONS(path, handler)
+		e.OPTIONS(path, handler)
 	case HmHead:
-		engine.HEAD(path, handler)
+		e.HEAD(path, handler)
 	case HmGetPost:
-		engine.GET(path, handler)
-		engine.POST(path, handler)
+		e.GET(path, handler)
+		e.POST(path, handler)
 	}
-	return engine
+	return e
 }
 
 func RegisterRouteWithHeaders(path string, method HttpMethod, header []string, versionName []string, handler gin.HandlerFunc) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
+	getOrRegisterApiPath(path, method).AddVersion(header, versionName, handler)
+	return currentEngine()
+}
+
+// RegisterVersionRoute 注册带版本的路由，版本可以来自请求头、查询参数或者路径参数，并支持版本范围和默认版本
+func RegisterVersionRoute(path string, method HttpMethod, option VersionOption, handler gin.HandlerFunc) gin.IRoutes {
+	if !checkEngine() {
+		return nil
+	}
+	getOrRegisterApiPath(path, method).AddVersionOption(option, handler)
+	return currentEngine()
+}
+
+func getOrRegisterApiPath(path string, method HttpMethod) *ApiPath {
 	p := GetApiPath(path, method)
 	if p == nil {
 		p = NewApiPath(path, method)
-		switch method {
-		case HmAll:
-			engine.GET(path, p.Handler)
-			engine.POST(path, p.Handler)
-			engine.PUT(path, p.Handler)
-			engine.DELETE(path, p.Handler)
-			engine.OPTIONS(path, p.Handler)
-			engine.HEAD(path, p.Handler)
-		case HmGet:
-			engine.GET(path, p.Handler)
-		case HmPost:
-			engine.POST(path, p.Handler)
-		case HmPut:
-			engine.PUT(path, p.Handler)
-		case HmDelete:
-			engine.DELETE(path, p.Handler)
-		case HmOptions:
-			engine.OPTIONS(path, p.Handler)
-		case HmHead:
-			engine.HEAD(path, p.Handler)
-		case HmGetPost:
-			engine.GET(path, p.Handler)
-			engine.POST(path, p.Handler)
-		}
+		RegisterRoute(path, method, p.Handler)
 	}
-	p.AddVersion(header, versionName, handler)
-	return engine
+	return p
 }
 
 func RegisterWebSocketRoute(path string, svr *websocket.Server) gin.IRoutes {
 	if !checkEngine() {
 		return nil
 	}
-	engine.GET(path, svr.Handler())
-	return engine
+	e := currentEngine()
+	e.GET(path, svr.Handler())
+	return e
 }
 
 func Post(path string, handler gin.HandlerFunc) gin.IRoutes {
@@ -378,9 +543,25 @@ func AllWith(path string, header []string, versionName []string, handler gin.Han
 	return RegisterRouteWithHeaders(getPathAppendApiModel(path), HmAll, header, versionName, handler)
 }
 
+func GetVersion(path string, option VersionOption, handler gin.HandlerFunc) gin.IRoutes {
+	return RegisterVersionRoute(getPathAppendApiModel(path), HmGet, option, handler)
+}
+
+func PostVersion(path string, option VersionOption, handler gin.HandlerFunc) gin.IRoutes {
+	return RegisterVersionRoute(getPathAppendApiModel(path), HmPost, option, handler)
+}
+
+func PutVersion(path string, option VersionOption, handler gin.HandlerFunc) gin.IRoutes {
+	return RegisterVersionRoute(getPathAppendApiModel(path), HmPut, option, handler)
+}
+
+func DeleteVersion(path string, option VersionOption, handler gin.HandlerFunc) gin.IRoutes {
+	return RegisterVersionRoute(getPathAppendApiModel(path), HmDelete, option, handler)
+}
+
 func Use(middleware ...gin.HandlerFunc) {
-	if engine != nil {
-		engine.Use(middleware...)
+	if e := currentEngine(); e != nil {
+		e.Use(middleware...)
 	}
 }
 
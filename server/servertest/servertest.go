@@ -0,0 +1,295 @@
+package servertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// gin的模式是全局的，只设置一次，避免并行的测试同时修改
+var testModeOnce sync.Once
+
+// Server 测试用的服务，使用独立的engine，不监听端口
+type Server struct {
+	t        testing.TB
+	engine   *gin.Engine
+	property *config.ApplicationProperty
+}
+
+// New 使用yaml内容作为配置创建独立的engine，带有与正式服务相同的标准中间件；yamlConfig为空则使用当前的配置
+// yaml中的配置只在创建engine、Register 以及处理请求期间生效，不修改全局的配置
+func New(t testing.TB, yamlConfig string) *Server {
+	t.Helper()
+	s := &Server{t: t}
+	if yamlConfig != "" {
+		property, err := config.ParseYamlContent(yamlConfig)
+		if err != nil {
+			t.Fatalf("加载配置失败: %v", err)
+		}
+		s.property = property
+	}
+	testModeOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+	})
+	s.withConfig(func() {
+		s.engine = server.NewStandardEngine()
+	})
+	return s
+}
+
+// withConfig 使用测试的配置执行fn
+func (s *Server) withConfig(fn func()) {
+	if s.property == nil {
+		fn()
+		return
+	}
+	config.WithProperties(s.property, fn)
+}
+
+// Engine 独立的engine，可以直接注册路由
+func (s *Server) Engine() *gin.Engine {
+	return s.engine
+}
+
+// Register 执行期间通过 server.Get、server.PostJSON 等函数注册的路由会注册到该engine
+func (s *Server) Register(register func()) *Server {
+	s.withConfig(func() {
+		server.WithEngine(s.engine, register)
+	})
+	return s
+}
+
+func (s *Server) Get(path string) *Request {
+	return s.Request(http.MethodGet, path)
+}
+
+func (s *Server) Post(path string) *Request {
+	return s.Request(http.MethodPost, path)
+}
+
+func (s *Server) Put(path string) *Request {
+	return s.Request(http.MethodPut, path)
+}
+
+func (s *Server) Patch(path string) *Request {
+	return s.Request(http.MethodPatch, path)
+}
+
+func (s *Server) Delete(path string) *Request {
+	return s.Request(http.MethodDelete, path)
+}
+
+// Request 创建请求，path中可以携带查询参数
+func (s *Server) Request(method string, path string) *Request {
+	return &Request{server: s, method: method, path: path, header: http.Header{}, query: url.Values{}}
+}
+
+// Request 测试的请求，调用 Do 或者任意的 ExpectXxx 时发送
+type Request struct {
+	server *Server
+	method string
+	path   string
+	header http.Header
+	query  url.Values
+	body   io.Reader
+}
+
+func (r *Request) WithHeader(key string, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) WithQuery(key string, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithJSON 将对象序列化为json作为请求体
+func (r *Request) WithJSON(v any) *Request {
+	r.server.t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.server.t.Fatalf("序列化请求体失败: %v", err)
+	}
+	return r.WithBody("application/json", data)
+}
+
+func (r *Request) WithBody(contentType string, body []byte) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = bytes.NewReader(body)
+	return r
+}
+
+// Do 发送请求
+func (r *Request) Do() *Response {
+	target := r.path
+	if len(r.query) != 0 {
+		if strings.Contains(target, "?") {
+			target += "&" + r.query.Encode()
+		} else {
+			target += "?" + r.query.Encode()
+		}
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.server.withConfig(func() {
+		r.server.engine.ServeHTTP(w, req)
+	})
+	return &Response{t: r.server.t, Recorder: w}
+}
+
+func (r *Request) ExpectStatus(status int) *Response {
+	r.server.t.Helper()
+	return r.Do().ExpectStatus(status)
+}
+
+func (r *Request) ExpectJSON(expected any) *Response {
+	r.server.t.Helper()
+	return r.Do().ExpectJSON(expected)
+}
+
+func (r *Request) ExpectCode(code int) *Response {
+	r.server.t.Helper()
+	return r.Do().ExpectCode(code)
+}
+
+// Response 测试的响应
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+}
+
+func (r *Response) Status() int {
+	return r.Recorder.Code
+}
+
+func (r *Response) Body() string {
+	return r.Recorder.Body.String()
+}
+
+func (r *Response) Header(key string) string {
+	return r.Recorder.Header().Get(key)
+}
+
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != status {
+		r.t.Errorf("期望状态码：%d，实际：%d，响应：%s", status, r.Recorder.Code, r.Body())
+	}
+	return r
+}
+
+func (r *Response) ExpectHeader(key string, value string) *Response {
+	r.t.Helper()
+	if actual := r.Header(key); actual != value {
+		r.t.Errorf("期望响应头 %s：%s，实际：%s", key, value, actual)
+	}
+	return r
+}
+
+func (r *Response) ExpectBody(body string) *Response {
+	r.t.Helper()
+	if r.Body() != body {
+		r.t.Errorf("期望响应：%s，实际：%s", body, r.Body())
+	}
+	return r
+}
+
+func (r *Response) ExpectBodyContains(sub string) *Response {
+	r.t.Helper()
+	if !strings.Contains(r.Body(), sub) {
+		r.t.Errorf("期望响应包含：%s，实际：%s", sub, r.Body())
+	}
+	return r
+}
+
+// ExpectJSON 按照json的语义比较响应，expected可以是json字符串、结构体或者map
+func (r *Response) ExpectJSON(expected any) *Response {
+	r.t.Helper()
+	var expectedData []byte
+	switch v := expected.(type) {
+	case string:
+		expectedData = []byte(v)
+	case []byte:
+		expectedData = v
+	default:
+		data, err := json.Marshal(expected)
+		if err != nil {
+			r.t.Fatalf("序列化期望值失败: %v", err)
+		}
+		expectedData = data
+	}
+
+	var want, got any
+	if err := json.Unmarshal(expectedData, &want); err != nil {
+		r.t.Fatalf("期望值不是合法的json: %v", err)
+	}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("响应不是合法的json：%s", r.Body())
+		return r
+	}
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("期望响应：%s，实际：%s", expectedData, r.Body())
+	}
+	return r
+}
+
+// ExpectCode 期望 rsp.DataResponse 中的业务码
+func (r *Response) ExpectCode(code int) *Response {
+	r.t.Helper()
+	var base rsp.ResponseBase
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &base); err != nil {
+		r.t.Errorf("响应不是标准的结构：%s", r.Body())
+		return r
+	}
+	if base.Code != code {
+		r.t.Errorf("期望业务码：%d，实际：%d，响应：%s", code, base.Code, r.Body())
+	}
+	return r
+}
+
+// DecodeJSON 将响应反序列化到v
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("反序列化响应失败：%v，响应：%s", err, r.Body())
+	}
+	return r
+}
+
+// DecodeResponse 将响应反序列化为 rsp.DataResponse[T]
+func DecodeResponse[T any](r *Response) rsp.DataResponse[T] {
+	r.t.Helper()
+	var response rsp.DataResponse[T]
+	r.DecodeJSON(&response)
+	return response
+}
+
+// DecodeData 将响应反序列化为 rsp.DataResponse[T]，并返回其中的data
+func DecodeData[T any](r *Response) T {
+	r.t.Helper()
+	return DecodeResponse[T](r).Data
+}
+
+// DecodePaged 将响应反序列化为 rsp.PagedResponse[T]，并返回其中的分页数据
+func DecodePaged[T any](r *Response) rsp.PagedData[T] {
+	r.t.Helper()
+	var response rsp.PagedResponse[T]
+	r.DecodeJSON(&response)
+	return response.Data
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/isyscore/isc-gobase/server/servertest"
+	"github.com/magiconair/properties/assert"
+)
+
+const testConfig = `
+api-module: sample
+base:
+  server:
+    body:
+      max-size: 64
+    auth:
+      enable: true
+      include:
+        - /api/secure/*
+      api-key:
+        enable: true
+        keys:
+          - key: k-1
+            name: job
+`
+
+type userReq struct {
+	Id   string `uri:"id"`
+	Name string `json:"name" match:"isBlank=false" errMsg:"name不可为空"`
+}
+
+type userRsp struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newServer(t *testing.T) *servertest.Server {
+	s := servertest.New(t, testConfig)
+	s.Register(func() {
+		server.PutJSON("/api/user/:id", func(c *gin.Context, req userReq) (userRsp, error) {
+			if req.Id == "0" {
+				return userRsp{}, rsp.NewCodeError(404, "用户不存在")
+			}
+			return userRsp{Id: req.Id, Name: req.Name}, nil
+		})
+		server.Get("/api/secure/data", func(c *gin.Context) {
+			rsp.SuccessOfStandard(c, "secret")
+		})
+	})
+	s.Engine().GET("/api/echo", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Query("name")+":"+c.GetHeader("X-Name"))
+	})
+	return s
+}
+
+func TestTypedRoute(t *testing.T) {
+	s := newServer(t)
+
+	r := s.Put("/api/user/12").WithJSON(map[string]string{"name": "isc"}).ExpectStatus(http.StatusOK).ExpectCode(0)
+	assert.Equal(t, servertest.DecodeData[userRsp](r), userRsp{Id: "12", Name: "isc"})
+
+	s.Put("/api/user/12").WithJSON(map[string]string{}).ExpectCode(rsp.CodeBadRequest)
+	s.Put("/api/user/0").WithJSON(map[string]string{"name": "isc"}).
+		ExpectJSON(`{"code":404,"message":"用户不存在","data":null}`)
+
+	// 配置中的请求体限制
+	s.Put("/api/user/12").WithBody("application/json", []byte(`{"name":"`+string(make([]byte, 100))+`"}`)).
+		ExpectStatus(http.StatusRequestEntityTooLarge)
+}
+
+func TestConfiguredMiddleware(t *testing.T) {
+	s := newServer(t)
+
+	s.Get("/api/secure/data").ExpectStatus(http.StatusUnauthorized)
+	r := s.Get("/api/secure/data").WithHeader("X-API-Key", "k-1").ExpectStatus(http.StatusOK)
+	assert.Equal(t, servertest.DecodeResponse[string](r).Data, "secret")
+
+	s.Get("/api/echo").WithQuery("name", "a").WithHeader("X-Name", "b").ExpectStatus(http.StatusOK).ExpectBody("a:b")
+}
+
+func TestOptionsNeedAuth(t *testing.T) {
+	s := servertest.New(t, testConfig)
+	s.Register(func() {
+		server.All("/api/secure/all", func(c *gin.Context) {
+			rsp.SuccessOfStandard(c, "secret")
+		})
+	})
+
+	// 没有携带跨域头的OPTIONS请求不是预检请求，需要认证
+	s.Request(http.MethodOptions, "/api/secure/all").Do().ExpectStatus(http.StatusUnauthorized)
+	s.Request(http.MethodOptions, "/api/secure/all").WithHeader("X-API-Key", "k-1").Do().ExpectStatus(http.StatusOK)
+	// 预检请求由跨域处理直接返回
+	s.Request(http.MethodOptions, "/api/secure/all").
+		WithHeader("Origin", "https://app.isyscore.com").
+		WithHeader("Access-Control-Request-Method", http.MethodGet).
+		Do().ExpectStatus(http.StatusNoContent)
+}
+
+func TestVersionRouteOnEngines(t *testing.T) {
+	// 每个engine独立维护版本路由，同一个版本可以注册到多个engine
+	for _, name := range []string{"first", "second"} {
+		s := servertest.New(t, testConfig)
+		s.Register(func() {
+			server.GetWith("/api/version", []string{server.DefaultVersionName}, []string{"1.0"}, func(c *gin.Context) {
+				c.String(http.StatusOK, name)
+			})
+		})
+		s.Get("/api/version").WithHeader(server.DefaultVersionName, "1.0").ExpectStatus(http.StatusOK).ExpectBody(name)
+		assert.Equal(t, len(s.Engine().Routes()), 1)
+	}
+}
+
+func TestParallelRegister(t *testing.T) {
+	// 多个协程同时注册路由，不会注册到其他协程的engine上
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			s := servertest.New(t, testConfig)
+			s.Register(func() {
+				for j := 0; j < 20; j++ {
+					server.Get(fmt.Sprintf("/api/route/%d", j), func(c *gin.Context) {
+						c.String(http.StatusOK, name)
+					})
+					runtime.Gosched()
+				}
+			})
+			assert.Equal(t, len(s.Engine().Routes()), 20)
+			s.Get("/api/route/3").ExpectStatus(http.StatusOK).ExpectBody(name)
+		}(fmt.Sprintf("server-%d", i))
+	}
+	wg.Wait()
+}
+
+func TestConfigIsolation(t *testing.T) {
+	s := servertest.New(t, testConfig)
+	var module string
+	s.Register(func() {
+		module = config.GetValueString("api-module")
+	})
+	assert.Equal(t, module, "sample")
+	// 测试的配置不修改全局的配置
+	assert.Equal(t, config.GetValueString("api-module"), "")
+	assert.Equal(t, config.GetValueInt("base.server.body.max-size"), 0)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// SSE 注册Server-Sent Events的路由，handler返回或者客户端断开后连接结束
+func SSE(path string, handler func(stream *rsp.SSEStream)) gin.IRoutes {
+	return SSEWith(path, rsp.SSEOption{}, handler)
+}
+
+// SSEWith 注册Server-Sent Events的路由，可以配置重连间隔、心跳以及用于 Last-Event-ID 补发的缓存
+func SSEWith(path string, option rsp.SSEOption, handler func(stream *rsp.SSEStream)) gin.IRoutes {
+	return Get(path, func(c *gin.Context) {
+		rsp.ServeSSE(c, option, handler)
+	})
+}
+
+// SSEBroadcast 注册Server-Sent Events的路由，连接作为订阅者接收broadcaster发布的事件
+func SSEBroadcast(path string, option rsp.SSEOption, broadcaster *rsp.SSEBroadcaster) gin.IRoutes {
+	// 补发由broadcaster处理
+	option.Buffer = nil
+	return SSEWith(path, option, broadcaster.Serve)
+}
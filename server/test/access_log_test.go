@@ -0,0 +1,144 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/magiconair/properties/assert"
+)
+
+func newAccessLogEngine(cfg rsp.AccessLogConfig) (*gin.Engine, *[]*rsp.AccessLog, *[]string) {
+	var entries []*rsp.AccessLog
+	var lines []string
+	engine := gin.New()
+	engine.Use(rsp.NewAccessLog(cfg, func(entry *rsp.AccessLog, line string) {
+		entries = append(entries, entry)
+		lines = append(lines, line)
+	}))
+	engine.POST("/api/data/:id", func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	})
+	engine.GET("/api/text", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("a", 100))
+	})
+	engine.GET("/api/failed", func(c *gin.Context) {
+		rsp.FailedOfStandard(c, 500, "failed")
+	})
+	engine.GET("/api/missing", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	engine.GET("/api/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = c.Writer.WriteString("data: hello\n\n")
+			c.Writer.Flush()
+		}
+	})
+	engine.GET("/health", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return engine, &entries, &lines
+}
+
+func serve(engine *gin.Engine, method string, url string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestAccessLogJson(t *testing.T) {
+	engine, entries, lines := newAccessLogEngine(rsp.AccessLogConfig{
+		Enable:  true,
+		Exclude: []string{"/health"},
+		Body:    rsp.AccessLogBodyConfig{Request: true, Response: true, Include: []string{"/api/data/:id", "/api/stream"}, MaxSize: 16},
+	})
+
+	w := serve(engine, http.MethodPost, "/api/data/12", `{"name":"isc"}`)
+	assert.Equal(t, w.Body.String(), `{"name":"isc"}`)
+	assert.Equal(t, len(*entries), 1)
+	entry := (*entries)[0]
+	assert.Equal(t, entry.Route, "/api/data/:id")
+	assert.Equal(t, entry.Status, 200)
+
+	var logged map[string]any
+	_ = json.Unmarshal([]byte((*lines)[0]), &logged)
+	assert.Equal(t, logged["requestBody"], map[string]any{"name": "isc"})
+	assert.Equal(t, logged["responseBody"], map[string]any{"name": "isc"})
+
+	// 超过最大值时截断
+	serve(engine, http.MethodPost, "/api/data/12", `{"name":"isyscore-gobase"}`)
+	assert.Equal(t, (*entries)[1].RequestBody, `{"name":"isyscor...(26 bytes)`)
+
+	// 不在body的路由中
+	serve(engine, http.MethodGet, "/api/text", "")
+	assert.Equal(t, (*entries)[2].ResponseBody, nil)
+	assert.Equal(t, (*entries)[2].Size, 100)
+
+	// 流式响应
+	w = serve(engine, http.MethodGet, "/api/stream", "")
+	assert.Equal(t, w.Flushed, true)
+	assert.Equal(t, (*entries)[3].ResponseBody, "<stream 39 bytes>")
+
+	serve(engine, http.MethodGet, "/health", "")
+	assert.Equal(t, len(*entries), 4)
+}
+
+func TestAccessLogApacheAndError(t *testing.T) {
+	rate := 0.0
+	engine, entries, lines := newAccessLogEngine(rsp.AccessLogConfig{
+		Enable:     true,
+		Format:     rsp.AccessLogFormatApache,
+		SampleRate: &rate,
+		OnlyError:  true,
+	})
+
+	serve(engine, http.MethodGet, "/api/text", "")
+	assert.Equal(t, len(*entries), 0)
+
+	// 业务码异常以及状态码异常不受采样的影响
+	serve(engine, http.MethodGet, "/api/failed", "")
+	serve(engine, http.MethodGet, "/api/missing", "")
+	assert.Equal(t, len(*entries), 2)
+	assert.Equal(t, strings.HasPrefix((*lines)[1], "192.0.2.1 - - ["), true)
+	assert.Equal(t, strings.Contains((*lines)[1], `"GET /api/missing HTTP/1.1" 404 0 "-" "-"`), true)
+
+	// apache格式的时间为CLF格式，json中仍然使用原来的时间格式
+	line := (*lines)[1]
+	_, err := time.Parse("02/Jan/2006:15:04:05 -0700", line[strings.Index(line, "[")+1:strings.Index(line, "]")])
+	assert.Equal(t, err, nil)
+	_, err = time.ParseInLocation("2006-01-02 15:04:05.000", (*entries)[1].Time, time.Local)
+	assert.Equal(t, err, nil)
+}
+
+func TestAccessLogTraceId(t *testing.T) {
+	var entries []*rsp.AccessLog
+	engine := gin.New()
+	engine.Use(server.Trace(), rsp.NewAccessLog(rsp.AccessLogConfig{Enable: true}, func(entry *rsp.AccessLog, _ string) {
+		entries = append(entries, entry)
+	}))
+	engine.GET("/api/data", func(c *gin.Context) {
+		// 其他的协程存储不影响traceId
+		goid.NewLocalStorage().Set(42)
+		c.String(http.StatusOK, "ok")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	r.Header.Set("X-Trace-Id", "trace-1")
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].TraceId, "trace-1")
+}
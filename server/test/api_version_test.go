@@ -0,0 +1,108 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+func init() {
+	versionHandler := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/plain", []byte(name))
+		}
+	}
+	server.RegisterVersionRoute("/api/version/header", server.HmGet, server.VersionOption{Version: "1.0", Default: true, Deprecated: true, Sunset: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}, versionHandler("v1"))
+	server.RegisterVersionRoute("/api/version/header", server.HmGet, server.VersionOption{Version: ">=1.2 <2"}, versionHandler("v1.2"))
+	server.RegisterVersionRoute("/api/version/header", server.HmGet, server.VersionOption{Version: "1.5"}, versionHandler("v1.5"))
+	server.RegisterVersionRoute("/api/version/query", server.HmGet, server.VersionOption{Source: server.VsQuery, Name: "v", Version: "^2.0"}, versionHandler("v2"))
+	server.RegisterVersionRoute("/api/version/path/:v", server.HmGet, server.VersionOption{Source: server.VsPath, Name: "v", Version: "v3"}, versionHandler("v3"))
+	server.RegisterRouteWithHeaders("/api/version/legacy", server.HmGet, []string{"isc-api-version"}, []string{"1.0"}, versionHandler("legacy"))
+	server.GetWith("/api/version/legacy-empty", []string{"a", "b"}, []string{"1", ""}, versionHandler("legacy-empty"))
+}
+
+func doVersionRequest(url string, version string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	if version != "" {
+		r.Header.Set(server.DefaultVersionName, version)
+	}
+	w := httptest.NewRecorder()
+	server.Engine().(http.Handler).ServeHTTP(w, r)
+	return w
+}
+
+func TestVersionHeader(t *testing.T) {
+	w := doVersionRequest("/api/version/header", "")
+	assert.Equal(t, w.Body.String(), "v1")
+	assert.Equal(t, w.Header().Get("Deprecation"), "true")
+	assert.Equal(t, w.Header().Get("Sunset"), "Tue, 01 Jan 2030 00:00:00 GMT")
+
+	assert.Equal(t, doVersionRequest("/api/version/header", "1.3").Body.String(), "v1.2")
+	assert.Equal(t, doVersionRequest("/api/version/header", "1.5").Body.String(), "v1.5")
+
+	w = doVersionRequest("/api/version/header", "2.0")
+	assert.Equal(t, w.Code, http.StatusNotAcceptable)
+	assert.Equal(t, w.Header().Get("Deprecation"), "")
+}
+
+func TestVersionQueryAndPath(t *testing.T) {
+	assert.Equal(t, doVersionRequest("/api/version/query?v=2.3", "").Body.String(), "v2")
+	assert.Equal(t, doVersionRequest("/api/version/query", "").Code, http.StatusNotFound)
+	assert.Equal(t, doVersionRequest("/api/version/query?v=3.0", "").Code, http.StatusNotAcceptable)
+
+	assert.Equal(t, doVersionRequest("/api/version/path/v3", "").Body.String(), "v3")
+	assert.Equal(t, doVersionRequest("/api/version/path/v4", "").Code, http.StatusNotAcceptable)
+}
+
+func TestVersionLegacy(t *testing.T) {
+	assert.Equal(t, doVersionRequest("/api/version/legacy", "1.0").Body.String(), "legacy")
+	assert.Equal(t, doVersionRequest("/api/version/legacy", "").Code, http.StatusNotFound)
+}
+
+func TestVersionLegacyEmptyHeader(t *testing.T) {
+	do := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/version/legacy-empty", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		server.Engine().(http.Handler).ServeHTTP(w, r)
+		return w
+	}
+	// 期望的版本为空时，匹配没有携带该请求头的请求
+	assert.Equal(t, do(map[string]string{"a": "1"}).Body.String(), "legacy-empty")
+	assert.Equal(t, do(map[string]string{"a": "1", "b": "2"}).Code, http.StatusNotAcceptable)
+	assert.Equal(t, do(map[string]string{"a": "2"}).Code, http.StatusNotAcceptable)
+	assert.Equal(t, do(nil).Code, http.StatusNotFound)
+}
+
+func TestVersionRange(t *testing.T) {
+	cases := []struct {
+		express string
+		version string
+		match   bool
+	}{
+		{">=1.2 <2", "1.10", true},
+		{">=1.2 <2", "2.0", false},
+		{"^1.2", "1.9.9", true},
+		{"^1.2", "2", false},
+		{"^0.2.3", "0.3.0", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"1.0 || >=3", "3.1", true},
+		{"1.0 || >=3", "2.1", false},
+		{"*", "beta", true},
+		{"beta", "beta", true},
+		{"v1", "1.0", true},
+	}
+	for _, c := range cases {
+		vr, err := server.ParseVersionRange(c.express)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, vr.Match(c.version), c.match, c.express+" "+c.version)
+	}
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+var noCredentials = false
+
+func newCorsEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(server.NewCors(server.CorsConfig{
+		AllowOrigins:     []string{"https://*.isyscore.com", "regex:^http://localhost:\\d+$"},
+		AllowHeaders:     []string{"Content-Type", "Token"},
+		ExposeHeaders:    []string{"X-Total"},
+		MaxAge:           600,
+		AllowCredentials: true,
+		Routes: []server.CorsRouteConfig{
+			{Path: "/open/:id", AllowOrigins: []string{"*"}, AllowHeaders: []string{"*"}},
+			{Path: "/public/*", AllowCredentials: &noCredentials},
+		},
+	}))
+	engine.GET("/data", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	engine.GET("/open/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	engine.GET("/public/file", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return engine
+}
+
+func doCorsRequest(engine *gin.Engine, method string, url string, origin string, preflight bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	r.Header.Set("Origin", origin)
+	if preflight {
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		r.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestCorsAllowedOrigin(t *testing.T) {
+	engine := newCorsEngine()
+
+	w := doCorsRequest(engine, http.MethodGet, "/data", "https://app.isyscore.com", false)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "https://app.isyscore.com")
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Credentials"), "true")
+	assert.Equal(t, w.Header().Get("Access-Control-Expose-Headers"), "X-Total")
+	assert.Equal(t, w.Header().Get("Vary"), "Origin")
+
+	w = doCorsRequest(engine, http.MethodGet, "/data", "http://localhost:8080", false)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "http://localhost:8080")
+}
+
+func TestCorsDeniedOrigin(t *testing.T) {
+	engine := newCorsEngine()
+
+	w := doCorsRequest(engine, http.MethodGet, "/data", "https://evil.com", false)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "")
+	assert.Equal(t, w.Header().Get("Vary"), "Origin")
+
+	w = doCorsRequest(engine, http.MethodOptions, "/data", "https://evil.com", true)
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.Equal(t, w.Header().Get("Vary"), "Origin")
+}
+
+func TestCorsVary(t *testing.T) {
+	engine := newCorsEngine()
+
+	// 没有来源的请求也要按来源区分缓存
+	w := doCorsRequest(engine, http.MethodGet, "/data", "", false)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "")
+	assert.Equal(t, w.Header().Get("Vary"), "Origin")
+
+	// 允许全部来源时响应不随来源变化
+	w = doCorsRequest(engine, http.MethodGet, "/open/1", "https://evil.com", false)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+	assert.Equal(t, w.Header().Get("Vary"), "")
+}
+
+func TestCorsPreflight(t *testing.T) {
+	engine := newCorsEngine()
+
+	w := doCorsRequest(engine, http.MethodOptions, "/data", "https://app.isyscore.com", true)
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Headers"), "Content-Type, Token")
+	assert.Equal(t, w.Header().Get("Access-Control-Max-Age"), "600")
+	assert.Equal(t, w.Body.Len(), 0)
+
+	// 路由的配置：允许全部来源时不携带凭证
+	w = doCorsRequest(engine, http.MethodOptions, "/open/12", "https://evil.com", true)
+	assert.Equal(t, w.Code, http.StatusNoContent)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Credentials"), "")
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Custom")
+}
+
+func TestCorsRouteCredentials(t *testing.T) {
+	engine := newCorsEngine()
+
+	// 路由配置为false时关闭凭证，其他的配置使用全局的配置
+	w := doCorsRequest(engine, http.MethodGet, "/public/file", "https://app.isyscore.com", false)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "https://app.isyscore.com")
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Credentials"), "")
+	assert.Equal(t, w.Header().Get("Access-Control-Expose-Headers"), "X-Total")
+
+	w = doCorsRequest(engine, http.MethodGet, "/data", "https://app.isyscore.com", false)
+	assert.Equal(t, w.Header().Get("Access-Control-Allow-Credentials"), "true")
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestNamedEngine(t *testing.T) {
+	// 测试之后删除，避免后面启动服务的测试监听该端口
+	t.Cleanup(func() {
+		server.RemoveEngine("internal")
+	})
+	internal := server.NewEngine("internal", freePort(t))
+	assert.Equal(t, internal != nil, true)
+	internal.GET("/internal/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	assert.Equal(t, server.GetEngine("internal") == internal, true)
+	// 重复创建时返回已经存在的
+	assert.Equal(t, server.NewEngine("internal", freePort(t)) == internal, true)
+	// 没有配置端口
+	assert.Equal(t, server.NewEngine("unknown", 0) == nil, true)
+
+	w := httptest.NewRecorder()
+	internal.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/ping", nil))
+	assert.Equal(t, w.Body.String(), "pong")
+
+	// 主服务的engine中没有该路由
+	w = httptest.NewRecorder()
+	server.Engine().(http.Handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/ping", nil))
+	assert.Equal(t, w.Code, http.StatusNotFound)
+
+	server.RemoveEngine("internal")
+	assert.Equal(t, server.GetEngine("internal") == nil, true)
+}
+
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// waitListen 等待端口开始监听
+func waitListen(t *testing.T, port int) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("端口 %d 没有启动", port)
+}
+
+func TestNamedEngineListener(t *testing.T) {
+	port, listenerPort := freePort(t), freePort(t)
+	property, err := config.ParseYamlContent(fmt.Sprintf("base:\n  server:\n    port: %d\n    body:\n      max-size: 16B\n", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e *gin.Engine
+	config.WithProperties(property, func() {
+		e = server.NewEngine("listener", listenerPort)
+	})
+	t.Cleanup(func() {
+		server.RemoveEngine("listener")
+	})
+	e.POST("/listener/echo", func(c *gin.Context) {
+		data, err := c.GetRawData()
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, string(data))
+	})
+
+	// 关闭服务时向自己发送 SIGTERM，测试期间不使用默认的退出处理
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	stopped := make(chan struct{})
+	go func() {
+		config.WithProperties(property, server.StartServer)
+		close(stopped)
+	}()
+	defer func() {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			t.Error("服务没有关闭")
+		}
+	}()
+	waitListen(t, port)
+	waitListen(t, listenerPort)
+
+	request := func(port int, body string) *http.Response {
+		r, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/listener/echo", port), strings.NewReader(body))
+		r.Header.Set("Origin", "https://app.isyscore.com")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	// 命名engine在单独的端口上监听，带有跨域等标准中间件
+	resp := request(listenerPort, "ping")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "*")
+
+	// 请求体大小限制
+	resp = request(listenerPort, strings.Repeat("a", 32))
+	assert.Equal(t, resp.StatusCode, http.StatusRequestEntityTooLarge)
+
+	// 主服务的端口没有该路由
+	resp = request(port, "ping")
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	server.RegisterMetrics("custom", func() any {
+		return map[string]int{"count": 1}
+	})
+	// 没有配置管理端口时注册到主服务的engine
+	e := server.RegisterMetricsEndpoint("/api/metrics-test")
+	assert.Equal(t, e == server.Engine(), true)
+
+	w := httptest.NewRecorder()
+	server.ManagementEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics-test/system/metrics", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	metrics := map[string]any{}
+	_ = json.Unmarshal(w.Body.Bytes(), &metrics)
+	assert.Equal(t, metrics["goroutines"].(float64) > 0, true)
+	assert.Equal(t, metrics["custom"], map[string]any{"count": float64(1)})
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/coder"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+type uploadPart struct {
+	field    string
+	fileName string
+	content  string
+}
+
+func multipartBody(parts []uploadPart) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, part := range parts {
+		if part.fileName == "" {
+			_ = writer.WriteField(part.field, part.content)
+			continue
+		}
+		w, _ := writer.CreateFormFile(part.field, part.fileName)
+		_, _ = w.Write([]byte(part.content))
+	}
+	_ = writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	var saved *server.UploadResult
+	engine := gin.New()
+	server.WithEngine(engine, func() {
+		server.Upload("/api/upload", server.UploadConfig{
+			Dir:        dir,
+			MaxSize:    "16",
+			AllowTypes: []string{"text/*", ".csv"},
+			Checksum:   "sha256",
+		}, func(c *gin.Context, result *server.UploadResult) (int64, error) {
+			saved = result
+			return result.File("file").Size, nil
+		})
+	})
+
+	do := func(parts []uploadPart, header map[string]string) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(parts)
+		r := httptest.NewRequest(http.MethodPost, "/api/upload", body)
+		r.Header.Set("Content-Type", contentType)
+		for key, value := range header {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do([]uploadPart{{field: "name", content: "isc"}, {field: "file", fileName: "a.txt", content: "hello"}}, nil)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), `{"code":0,"message":"success","data":5}`)
+	assert.Equal(t, saved.Fields["name"], "isc")
+	file := saved.File("file")
+	assert.Equal(t, file.FileName, "a.txt")
+	assert.Equal(t, file.Checksum, coder.Sha256String("hello"))
+	data, _ := os.ReadFile(file.Path)
+	assert.Equal(t, string(data), "hello")
+
+	// 校验和
+	w = do([]uploadPart{{field: "file", fileName: "a.txt", content: "hello"}}, map[string]string{"X-Checksum-Md5": coder.MD5String("hello")})
+	assert.Equal(t, w.Code, http.StatusOK)
+	w = do([]uploadPart{{field: "file", fileName: "a.txt", content: "hello"}, {field: "file_md5", content: "0000"}}, nil)
+	assert.Equal(t, strings.Contains(w.Body.String(), "文件校验和不一致"), true)
+
+	// 大小以及类型的限制
+	w = do([]uploadPart{{field: "file", fileName: "a.txt", content: strings.Repeat("a", 17)}}, nil)
+	assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+	w = do([]uploadPart{{field: "file", fileName: "a.png", content: "\x89PNG\r\n\x1a\n0000"}}, nil)
+	assert.Equal(t, w.Code, http.StatusUnsupportedMediaType)
+
+	// 出错时删除已经保存的文件
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, len(entries), 2)
+}
+
+func TestDownload(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("0123456789", 200)
+	_ = os.WriteFile(filepath.Join(dir, "data.txt"), []byte(content), 0644)
+
+	engine := gin.New()
+	server.WithEngine(engine, func() {
+		server.Download("/api/files", dir, server.DownloadOption{Attachment: true, Gzip: true, Checksum: "md5"})
+	})
+	do := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range header {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("/api/files/data.txt", nil)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), content)
+	assert.Equal(t, w.Header().Get("ETag"), "\""+coder.MD5String(content)+"\"")
+	assert.Equal(t, w.Header().Get("X-Checksum-Md5"), coder.MD5String(content))
+	assert.Equal(t, w.Header().Get("Content-Disposition"), "attachment; filename*=UTF-8''data.txt")
+
+	// 断点续传
+	w = do("/api/files/data.txt", map[string]string{"Range": "bytes=10-19"})
+	assert.Equal(t, w.Code, http.StatusPartialContent)
+	assert.Equal(t, w.Body.String(), "0123456789")
+	assert.Equal(t, w.Header().Get("Content-Range"), "bytes 10-19/2000")
+
+	w = do("/api/files/data.txt", map[string]string{"If-None-Match": w.Header().Get("ETag")})
+	assert.Equal(t, w.Code, http.StatusNotModified)
+
+	// gzip
+	w = do("/api/files/data.txt", map[string]string{"Accept-Encoding": "gzip"})
+	assert.Equal(t, w.Header().Get("Content-Encoding"), "gzip")
+	reader, _ := gzip.NewReader(w.Body)
+	data, _ := io.ReadAll(reader)
+	assert.Equal(t, string(data), content)
+	w = do("/api/files/data.txt", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": w.Header().Get("ETag")})
+	assert.Equal(t, w.Code, http.StatusNotModified)
+
+	// 不能访问目录之外的文件
+	w = do("/api/files/../../etc/passwd", nil)
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/magiconair/properties/assert"
+)
+
+type JsonReq struct {
+	Id    string `uri:"id"`
+	Page  int    `form:"page"`
+	Token string `header:"token"`
+	Name  string `json:"name" match:"isBlank=false" errMsg:"name不可为空"`
+	Age   int    `json:"age"`
+}
+
+type JsonRsp struct {
+	Id    string `json:"id"`
+	Page  int    `json:"page"`
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+func init() {
+	server.PostJSON("api/test/json/:id", func(c *gin.Context, req JsonReq) (JsonRsp, error) {
+		if req.Age < 0 {
+			return JsonRsp{}, rsp.NewCodeError(4001, "年龄不合法")
+		}
+		if req.Age > 200 {
+			return JsonRsp{}, errors.New("年龄太大")
+		}
+		return JsonRsp{Id: req.Id, Page: req.Page, Token: req.Token, Name: req.Name}, nil
+	})
+}
+
+func doJsonRequest(body string) rsp.DataResponse[JsonRsp] {
+	r := httptest.NewRequest(http.MethodPost, "/api/test/json/12?page=3", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("token", "abc")
+	w := httptest.NewRecorder()
+	server.Engine().(http.Handler).ServeHTTP(w, r)
+
+	result := rsp.DataResponse[JsonRsp]{}
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	return result
+}
+
+func TestPostJSON(t *testing.T) {
+	result := doJsonRequest(`{"name":"zhou","age":12}`)
+	assert.Equal(t, result.Code, 0)
+	assert.Equal(t, result.Data, JsonRsp{Id: "12", Page: 3, Token: "abc", Name: "zhou"})
+}
+
+func TestPostJSONValidateFailed(t *testing.T) {
+	result := doJsonRequest(`{"age":12}`)
+	assert.Equal(t, result.Code, rsp.CodeBadRequest)
+	assert.Equal(t, result.Message, "name不可为空")
+}
+
+func TestPostJSONError(t *testing.T) {
+	result := doJsonRequest(`{"name":"zhou","age":-1}`)
+	assert.Equal(t, result.Code, 4001)
+	assert.Equal(t, result.Message, "年龄不合法")
+
+	result = doJsonRequest(`{"name":"zhou","age":300}`)
+	assert.Equal(t, result.Code, rsp.CodeInternalError)
+	// 普通错误的内容只打印日志，不返回给调用方
+	assert.Equal(t, result.Message, rsp.MessageInternalError)
+}
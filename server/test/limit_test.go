@@ -0,0 +1,119 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	for size, expect := range map[string]int64{"1024": 1024, "512KB": 512 << 10, "10mb": 10 << 20, "1G": 1 << 30, "100B": 100} {
+		value, err := server.ParseSize(size)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, value, expect)
+	}
+	_, err := server.ParseSize("ten")
+	assert.Equal(t, err != nil, true)
+}
+
+type limitReq struct {
+	Name string `json:"name"`
+}
+
+func TestBodyLimit(t *testing.T) {
+	engine := gin.New()
+	engine.Use(server.NewBodyLimit(server.BodyLimitConfig{
+		MaxSize: "16",
+		Routes:  []server.BodyLimitRouteConfig{{Path: "/upload/*", MaxSize: "1KB"}},
+	}))
+	engine.POST("/data", server.Handle(func(c *gin.Context, req limitReq) (string, error) {
+		return req.Name, nil
+	}))
+	engine.POST("/raw", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+	})
+	engine.POST("/upload/file", func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, "%d", len(data))
+	})
+
+	do := func(url string, body string, chunked bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		if chunked {
+			// 没有Content-Length，读取时才能发现超过限制
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, do("/data", `{"name":"isc"}`, false).Code, http.StatusOK)
+	assert.Equal(t, do("/data", `{"name":"isyscore-gobase"}`, false).Code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, do("/data", `{"name":"isyscore-gobase"}`, true).Code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, do("/raw", `{"name":"isyscore-gobase"}`, true).Code, http.StatusRequestEntityTooLarge)
+
+	// 路由的配置
+	w := do("/upload/file", strings.Repeat("a", 1000), true)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "1000")
+	assert.Equal(t, do("/upload/file", strings.Repeat("a", 1025), true).Code, http.StatusRequestEntityTooLarge)
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	engine := gin.New()
+	engine.Use(server.NewHandlerTimeout(server.TimeoutConfig{
+		Handler: 50,
+		Routes:  []server.TimeoutRouteConfig{{Path: "/long", Handler: 500}},
+	}))
+	handler := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+			c.String(http.StatusOK, "ok")
+		}
+	}
+	engine.GET("/short", handler)
+	engine.GET("/long", handler)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/short", nil))
+	assert.Equal(t, w.Code, http.StatusServiceUnavailable)
+	assert.Equal(t, time.Since(start) < 200*time.Millisecond, true)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/long", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestSlowRequestAndTrace(t *testing.T) {
+	engine := gin.New()
+	engine.Use(server.Trace(), server.NewSlowRequest(server.SlowRequestConfig{Threshold: 10}))
+	engine.GET("/slow/:id", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.String(http.StatusOK, server.TraceId(c))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/slow/1", nil)
+	r.Header.Set("X-Trace-Id", "trace-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	assert.Equal(t, w.Body.String(), "trace-1")
+	assert.Equal(t, w.Header().Get("X-Trace-Id"), "trace-1")
+
+	// 没有traceId时生成
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/1", nil))
+	assert.Equal(t, len(w.Body.String()), 32)
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/openapi"
+	"github.com/magiconair/properties/assert"
+)
+
+func init() {
+	server.RegisterOpenApiEndpoint("/openapi.json", "/swagger-ui")
+}
+
+func TestOpenApiDocument(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	server.Engine().(http.Handler).ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	doc := openapi.Document{}
+	_ = json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.Equal(t, doc.OpenAPI, openapi.Version)
+
+	_, exist := doc.Paths["/openapi.json"]
+	assert.Equal(t, exist, false)
+
+	post := (*doc.Paths["/api/test/json/{id}"])["post"]
+	assert.Equal(t, post.Responses["200"].Content["application/json"].Schema.Ref, "#/components/schemas/DataResponse_JsonRsp")
+	assert.Equal(t, post.RequestBody.Content["application/json"].Schema.Required, []string{"name"})
+}
+
+func TestOpenApiSwaggerUi(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/swagger-ui", nil)
+	w := httptest.NewRecorder()
+	server.Engine().(http.Handler).ServeHTTP(w, r)
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, strings.Contains(w.Body.String(), `<script src="/swagger-ui/swagger-ui-bundle.js">`), true)
+
+	// 页面使用的静态文件打包在服务中
+	for path, contentType := range map[string]string{"/swagger-ui/swagger-ui-bundle.js": "javascript", "/swagger-ui/swagger-ui.css": "text/css"} {
+		r = httptest.NewRequest(http.MethodGet, path, nil)
+		w = httptest.NewRecorder()
+		server.Engine().(http.Handler).ServeHTTP(w, r)
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, strings.Contains(w.Header().Get("Content-Type"), contentType), true)
+		assert.Equal(t, w.Body.Len() > 0, true)
+	}
+
+	// swagger-ui的路径不出现在文档中
+	doc := server.OpenApiDocument()
+	_, exist := doc.Paths["/swagger-ui/swagger-ui.css"]
+	assert.Equal(t, exist, false)
+}
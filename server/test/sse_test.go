@@ -0,0 +1,204 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/isyscore/isc-gobase/server/rsp"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSSEFormat(t *testing.T) {
+	text, _ := rsp.FormatSSEEvent(rsp.SSEEvent{Id: "1", Event: "update", Data: "a\nb", Retry: 3 * time.Second})
+	assert.Equal(t, text, "id: 1\nevent: update\nretry: 3000\ndata: a\ndata: b\n\n")
+
+	text, _ = rsp.FormatSSEEvent(rsp.SSEEvent{Data: map[string]int{"count": 1}})
+	assert.Equal(t, text, "data: {\"count\":1}\n\n")
+}
+
+func TestSSEMemoryBuffer(t *testing.T) {
+	buffer := rsp.NewSSEMemoryBuffer(3)
+	for i := 0; i < 5; i++ {
+		buffer.Append(rsp.SSEEvent{Data: i})
+	}
+	events, ok := buffer.Since("4")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Id, "5")
+
+	// 1已经被淘汰，返回缓存中全部的事件
+	events, ok = buffer.Since("1")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(events), 3)
+	assert.Equal(t, events[0].Id, "3")
+}
+
+// readSSE 读取事件，直到读到count个事件或者连接关闭
+func readSSE(t *testing.T, url string, lastEventId string, count int) []string {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream; charset=utf-8")
+
+	var blocks []string
+	var block strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			block.WriteString(line + "\n")
+			continue
+		}
+		blocks = append(blocks, block.String())
+		block.Reset()
+		if len(blocks) == count {
+			break
+		}
+	}
+	return blocks
+}
+
+func TestSSEStream(t *testing.T) {
+	engine := gin.New()
+	server.WithEngine(engine, func() {
+		server.SSEWith("/api/sse/events", rsp.SSEOption{
+			Retry:     time.Second,
+			Heartbeat: 20 * time.Millisecond,
+			Buffer:    rsp.NewSSEMemoryBuffer(10),
+		}, func(stream *rsp.SSEStream) {
+			if stream.LastEventId() != "" {
+				return
+			}
+			_ = stream.Send(rsp.SSEEvent{Event: "greeting", Data: "hello"})
+			_ = stream.SendData(map[string]string{"name": "world"})
+			select {
+			case <-stream.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	})
+	svr := httptest.NewServer(engine)
+	defer svr.Close()
+
+	blocks := readSSE(t, svr.URL+"/api/sse/events", "", 0)
+	assert.Equal(t, blocks[0], "retry: 1000\n")
+	assert.Equal(t, blocks[1], "id: 1\nevent: greeting\ndata: hello\n")
+	assert.Equal(t, blocks[2], "id: 2\ndata: {\"name\":\"world\"}\n")
+	assert.Equal(t, blocks[3], ": ping\n")
+
+	// 断线重连时补发之后的事件
+	blocks = readSSE(t, svr.URL+"/api/sse/events", "1", 0)
+	assert.Equal(t, blocks, []string{"retry: 1000\n", "id: 2\ndata: {\"name\":\"world\"}\n"})
+}
+
+func TestSSEBroadcaster(t *testing.T) {
+	broadcaster := rsp.NewSSEBroadcaster(rsp.SSEBroadcasterOption{QueueSize: 8})
+	engine := gin.New()
+	server.WithEngine(engine, func() {
+		server.SSEBroadcast("/api/sse/broadcast", rsp.SSEOption{Heartbeat: -1}, broadcaster)
+	})
+	svr := httptest.NewServer(engine)
+	defer svr.Close()
+
+	results := make(chan []string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			results <- readSSE(t, svr.URL+"/api/sse/broadcast", "", 3)
+		}()
+	}
+	for broadcaster.Subscribers() < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, data := range []string{"a", "b", "c"} {
+		broadcaster.Publish(rsp.SSEEvent{Data: data})
+	}
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, <-results, []string{"id: 1\ndata: a\n", "id: 2\ndata: b\n", "id: 3\ndata: c\n"})
+	}
+
+	// 重连时从缓存中补发
+	for broadcaster.Subscribers() != 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	go func() {
+		results <- readSSE(t, svr.URL+"/api/sse/broadcast", "1", 3)
+	}()
+	for broadcaster.Subscribers() < 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	broadcaster.Publish(rsp.SSEEvent{Data: "d"})
+	assert.Equal(t, <-results, []string{"id: 2\ndata: b\n", "id: 3\ndata: c\n", "id: 4\ndata: d\n"})
+
+	broadcaster.Close()
+	assert.Equal(t, broadcaster.Subscribers(), 0)
+}
+
+// yieldBuffer 保存事件之后让出调度，使并发发布更容易交错
+type yieldBuffer struct {
+	*rsp.SSEMemoryBuffer
+}
+
+func (b yieldBuffer) Append(event rsp.SSEEvent) rsp.SSEEvent {
+	event = b.SSEMemoryBuffer.Append(event)
+	runtime.Gosched()
+	return event
+}
+
+func TestSSEBroadcasterConcurrentPublish(t *testing.T) {
+	const publishers, count = 8, 50
+	broadcaster := rsp.NewSSEBroadcaster(rsp.SSEBroadcasterOption{
+		Buffer:    yieldBuffer{rsp.NewSSEMemoryBuffer(publishers * count)},
+		QueueSize: publishers * count,
+	})
+	engine := gin.New()
+	server.WithEngine(engine, func() {
+		server.SSEBroadcast("/api/sse/broadcast", rsp.SSEOption{Heartbeat: -1}, broadcaster)
+	})
+	svr := httptest.NewServer(engine)
+	defer svr.Close()
+
+	results := make(chan []string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			results <- readSSE(t, svr.URL+"/api/sse/broadcast", "", publishers*count)
+		}()
+	}
+	for broadcaster.Subscribers() < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				broadcaster.Publish(rsp.SSEEvent{Data: "x"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每个订阅者收到的id都是递增的，断线后通过 Last-Event-ID 补发不会丢失事件
+	for i := 0; i < 2; i++ {
+		blocks := <-results
+		assert.Equal(t, len(blocks), publishers*count)
+		for n, block := range blocks {
+			assert.Equal(t, block, fmt.Sprintf("id: %d\ndata: x\n", n+1))
+		}
+	}
+}
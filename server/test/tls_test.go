@@ -0,0 +1,196 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/server"
+	"github.com/magiconair/properties/assert"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPem []byte
+	keyPem  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCa bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         isCa,
+
+		BasicConstraintsValid: true,
+	}
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTlsTestServer 与服务端一样通过 GetCertificate 提供证书，httptest.Server 会覆盖证书所以不使用
+func newTlsTestServer(t *testing.T, cfg server.TlsConfig) (string, func()) {
+	tlsConfig, err := server.NewTlsConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.GET("/tls", func(c *gin.Context) {
+		name := ""
+		if len(c.Request.TLS.PeerCertificates) != 0 {
+			name = c.Request.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		c.String(http.StatusOK, c.Request.Proto+" "+name)
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &http.Server{Handler: engine, TLSConfig: tlsConfig}
+	go func() {
+		_ = s.ServeTLS(ln, "", "")
+	}()
+	return "https://" + ln.Addr().String(), func() {
+		_ = s.Close()
+	}
+}
+
+func newTlsClient(ca *testCert, clientCert *testCert) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	tlsConfig := &tls.Config{RootCAs: pool}
+	if clientCert != nil {
+		pair, _ := tls.X509KeyPair(clientCert.certPem, clientCert.keyPem)
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}}
+}
+
+func TestTlsHttp2(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil, true)
+	serverCert := newTestCert(t, "server", ca, false)
+
+	url, closeFunc := newTlsTestServer(t, server.TlsConfig{
+		CertFile: writeFile(t, filepath.Join(dir, "server.crt"), serverCert.certPem),
+		KeyFile:  writeFile(t, filepath.Join(dir, "server.key"), serverCert.keyPem),
+	})
+	defer closeFunc()
+
+	r, err := newTlsClient(ca, nil).Get(url + "/tls")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, r.ProtoMajor, 2)
+}
+
+func TestTlsMutual(t *testing.T) {
+	// require 与 require-and-verify 一样使用CA校验客户端证书
+	for _, clientAuth := range []string{"require-and-verify", "require"} {
+		t.Run(clientAuth, func(t *testing.T) {
+			dir := t.TempDir()
+			ca := newTestCert(t, "ca", nil, true)
+			otherCa := newTestCert(t, "other-ca", nil, true)
+			serverCert := newTestCert(t, "server", ca, false)
+
+			url, closeFunc := newTlsTestServer(t, server.TlsConfig{
+				CertFile:      writeFile(t, filepath.Join(dir, "server.crt"), serverCert.certPem),
+				KeyFile:       writeFile(t, filepath.Join(dir, "server.key"), serverCert.keyPem),
+				ClientAuth:    clientAuth,
+				ClientCaFiles: []string{writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPem)},
+				DisableHttp2:  true,
+			})
+			defer closeFunc()
+
+			// 没有客户端证书
+			_, err := newTlsClient(ca, nil).Get(url + "/tls")
+			assert.Equal(t, err != nil, true)
+
+			// 不受信任的客户端证书
+			_, err = newTlsClient(ca, newTestCert(t, "evil", otherCa, false)).Get(url + "/tls")
+			assert.Equal(t, err != nil, true)
+
+			r, err := newTlsClient(ca, newTestCert(t, "client", ca, false)).Get(url + "/tls")
+			assert.Equal(t, err, nil)
+			assert.Equal(t, r.ProtoMajor, 1)
+			assert.Equal(t, r.TLS.PeerCertificates[0].Subject.CommonName, "server")
+		})
+	}
+}
+
+func TestTlsCertReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil, true)
+	first := newTestCert(t, "first", ca, false)
+	second := newTestCert(t, "second", ca, false)
+	certFile := writeFile(t, filepath.Join(dir, "server.crt"), first.certPem)
+	keyFile := writeFile(t, filepath.Join(dir, "server.key"), first.keyPem)
+
+	url, closeFunc := newTlsTestServer(t, server.TlsConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: 1})
+	defer closeFunc()
+
+	get := func() string {
+		// 每次新建连接，触发握手
+		r, err := newTlsClient(ca, nil).Get(url + "/tls")
+		assert.Equal(t, err, nil)
+		return r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, get(), "first")
+
+	writeFile(t, certFile, second.certPem)
+	writeFile(t, keyFile, second.keyPem)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	_ = os.Chtimes(keyFile, future, future)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, get(), "second")
+}
+
+func TestTlsConfigInvalid(t *testing.T) {
+	_, err := server.NewTlsConfig(server.TlsConfig{})
+	assert.Equal(t, err != nil, true)
+
+	_, err = server.NewTlsConfig(server.TlsConfig{CertFile: "a.crt", KeyFile: "a.key", ClientAuth: "unknown"})
+	assert.Equal(t, err != nil, true)
+}
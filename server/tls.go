@@ -0,0 +1,174 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/logger"
+)
+
+// TlsConfig base.server.tls 配置
+type TlsConfig struct {
+	Enable bool
+	// https端口，默认：base.server.port
+	Port int
+	// 证书和私钥文件，文件变更后会自动重新加载
+	CertFile string
+	KeyFile  string
+	// 客户端证书校验：none、request、require-any、verify-if-given、require（同require-and-verify），默认：none；
+	// request和require-any不使用CA校验客户端证书
+	ClientAuth string
+	// 校验客户端证书的CA文件
+	ClientCaFiles []string
+	// 最低的tls版本：1.0、1.1、1.2、1.3，默认：1.2
+	MinVersion string
+	// 是否关闭http2，默认开启
+	DisableHttp2 bool
+	// 是否同时保留http端口（base.server.port），此时 port 需要配置为其他端口
+	KeepHttp bool
+	// 证书文件变更的检查间隔，单位毫秒，默认：10000
+	ReloadInterval int
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require-any":        tls.RequireAnyClientCert,
+	"require":            tls.RequireAndVerifyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func getTlsConfig() TlsConfig {
+	cfg := TlsConfig{}
+	if err := config.GetValueObject("base.server.tls", &cfg); err != nil {
+		logger.Warn("读取tls配置异常: %v", err)
+	}
+	return cfg
+}
+
+// NewTlsConfig 根据配置创建 tls.Config，证书通过 GetCertificate 获取，文件变更后自动重新加载
+func NewTlsConfig(cfg TlsConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls的证书文件 cert-file 和私钥文件 key-file 不能为空")
+	}
+	clientAuth, ok := clientAuthTypes[strings.ToLower(cfg.ClientAuth)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的客户端证书校验方式: %s", cfg.ClientAuth)
+	}
+	minVersion, ok := tlsVersions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("不支持的tls版本: %s", cfg.MinVersion)
+	}
+
+	interval := time.Duration(cfg.ReloadInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.getCertificate,
+	}
+	if len(cfg.ClientCaFiles) != 0 {
+		pool := x509.NewCertPool()
+		for _, file := range cfg.ClientCaFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("读取客户端CA文件 %s 异常: %v", file, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("客户端CA文件 %s 中没有合法的证书", file)
+			}
+		}
+		tlsConfig.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, errors.New("校验客户端证书时需要配置 client-ca-files")
+	}
+
+	if cfg.DisableHttp2 {
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	} else {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return tlsConfig, nil
+}
+
+// certReloader 在握手时检查证书文件的修改时间，变更后重新加载；加载失败时继续使用旧的证书
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	lock      sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkTime time.Time
+}
+
+func newCertReloader(certFile string, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("加载tls证书异常: %v", err)
+	}
+	r.cert = &cert
+	r.modTime = r.lastModTime()
+	r.checkTime = time.Now()
+	return nil
+}
+
+func (r *certReloader) lastModTime() time.Time {
+	var last time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if time.Since(r.checkTime) >= r.interval {
+		r.checkTime = time.Now()
+		if modTime := r.lastModTime(); modTime.After(r.modTime) {
+			if err := r.load(); err != nil {
+				logger.Error("重新加载tls证书失败，继续使用旧的证书: %v", err)
+				r.modTime = modTime
+			} else {
+				logger.Info("tls证书已重新加载")
+			}
+		}
+	}
+	return r.cert, nil
+}
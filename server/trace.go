@@ -0,0 +1,35 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/isyscore/isc-gobase/config"
+	"github.com/isyscore/isc-gobase/goid"
+	"github.com/isyscore/isc-gobase/server/rsp"
+)
+
+// TraceIdKey traceId在gin上下文中的key
+const TraceIdKey = rsp.TraceIdKey
+
+// Trace 从请求头（base.server.trace.header，默认：X-Trace-Id）中获取traceId，没有则生成，并设置到响应头以及协程存储中
+func Trace() gin.HandlerFunc {
+	header := config.GetValueStringDefault("base.server.trace.header", "X-Trace-Id")
+	return func(c *gin.Context) {
+		traceId := c.GetHeader(header)
+		if traceId == "" {
+			traceId = goid.GenerateTraceID()
+		}
+		c.Set(TraceIdKey, traceId)
+		c.Header(header, traceId)
+		goid.SetTraceID(traceId)
+		defer goid.DelTraceID()
+		c.Next()
+	}
+}
+
+// TraceId 获取请求的traceId
+func TraceId(c *gin.Context) string {
+	if traceId := c.GetString(TraceIdKey); traceId != "" {
+		return traceId
+	}
+	return goid.GetTraceID()
+}
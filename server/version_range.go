@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionRange 版本范围，比如：1.0、>=1.2 <2、^1.2、~1.2.3、1.0 || >=2.1、*
+// 空格分隔的条件为"且"的关系，|| 分隔的为"或"的关系
+type VersionRange struct {
+	Express string
+	groups  [][]versionComparator
+}
+
+type versionComparator struct {
+	op      string
+	version string
+}
+
+func ParseVersionRange(express string) (*VersionRange, error) {
+	vr := &VersionRange{Express: express}
+	for _, group := range strings.Split(express, "||") {
+		var comparators []versionComparator
+		for _, item := range strings.Fields(group) {
+			comparator, err := parseComparator(item)
+			if err != nil {
+				return nil, err
+			}
+			comparators = append(comparators, comparator...)
+		}
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("版本范围 %s 不合法", express)
+		}
+		vr.groups = append(vr.groups, comparators)
+	}
+	return vr, nil
+}
+
+func parseComparator(item string) ([]versionComparator, error) {
+	if item == "*" || item == "x" {
+		return []versionComparator{{op: "*"}}, nil
+	}
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if !strings.HasPrefix(item, op) {
+			continue
+		}
+		version := strings.TrimPrefix(item, op)
+		if version == "" {
+			return nil, fmt.Errorf("版本 %s 不合法", item)
+		}
+		switch op {
+		case "^":
+			// ^1.2.3 := >=1.2.3 <2.0.0，^0.2.3 := >=0.2.3 <0.3.0
+			return []versionComparator{{op: ">=", version: version}, {op: "<", version: bumpVersion(version, true)}}, nil
+		case "~":
+			// ~1.2.3 := >=1.2.3 <1.3.0
+			return []versionComparator{{op: ">=", version: version}, {op: "<", version: bumpVersion(version, false)}}, nil
+		}
+		return []versionComparator{{op: op, version: version}}, nil
+	}
+	// 没有操作符为精确匹配
+	return []versionComparator{{op: "", version: item}}, nil
+}
+
+// bumpVersion 计算上界版本：caret为true时，提升第一个非零的位，否则提升次版本号
+func bumpVersion(version string, caret bool) string {
+	parts := versionParts(version)
+	if parts == nil {
+		return version
+	}
+	index := 0
+	if caret {
+		for index < len(parts)-1 && parts[index] == 0 {
+			index++
+		}
+	} else if len(parts) > 1 {
+		index = 1
+	}
+	bumped := make([]string, index+1)
+	for i := 0; i < index; i++ {
+		bumped[i] = strconv.Itoa(parts[i])
+	}
+	bumped[index] = strconv.Itoa(parts[index] + 1)
+	return strings.Join(bumped, ".")
+}
+
+// IsExact 是否是精确版本（没有操作符）
+func (vr *VersionRange) IsExact() bool {
+	return len(vr.groups) == 1 && len(vr.groups[0]) == 1 && vr.groups[0][0].op == ""
+}
+
+func (vr *VersionRange) Match(version string) bool {
+	version = strings.TrimSpace(version)
+	for _, group := range vr.groups {
+		matched := true
+		for _, comparator := range group {
+			if !comparator.match(version) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (vc versionComparator) match(version string) bool {
+	if vc.op == "*" {
+		return true
+	}
+	if vc.op == "" && vc.version == version {
+		return true
+	}
+	result, ok := CompareVersion(version, vc.version)
+	if !ok {
+		// 非数字版本只能精确匹配
+		return false
+	}
+	switch vc.op {
+	case "", "=":
+		return result == 0
+	case ">=":
+		return result >= 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	}
+	return false
+}
+
+// CompareVersion 比较两个数字版本，比如：1.2 和 1.10；缺少的位按照0处理，第二个返回值表示是否可以比较
+func CompareVersion(left, right string) (int, bool) {
+	leftParts, rightParts := versionParts(left), versionParts(right)
+	if leftParts == nil || rightParts == nil {
+		return 0, false
+	}
+	for i := 0; i < len(leftParts) || i < len(rightParts); i++ {
+		l, r := 0, 0
+		if i < len(leftParts) {
+			l = leftParts[i]
+		}
+		if i < len(rightParts) {
+			r = rightParts[i]
+		}
+		if l != r {
+			if l < r {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(version), "v"), "V")
+	if version == "" {
+		return nil
+	}
+	items := strings.Split(version, ".")
+	parts := make([]int, len(items))
+	for i, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 0 {
+			return nil
+		}
+		parts[i] = n
+	}
+	return parts
+}